@@ -1,11 +1,17 @@
 package main
 
 import (
+	"expvar"
+	"github.com/thxhix/shortener/internal/clicks"
 	"github.com/thxhix/shortener/internal/config"
 	"github.com/thxhix/shortener/internal/database"
+	"github.com/thxhix/shortener/internal/database/interfaces"
+	"github.com/thxhix/shortener/internal/handlers"
 	"github.com/thxhix/shortener/internal/meta"
 	r "github.com/thxhix/shortener/internal/router"
 	http "github.com/thxhix/shortener/internal/server"
+	"github.com/thxhix/shortener/internal/url"
+	"github.com/thxhix/shortener/internal/useragent"
 	"go.uber.org/zap"
 	"log"
 )
@@ -31,6 +37,12 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	// Закрывается последним, после остановки всех фоновых писателей
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Printf("ошибка закрытия БД: %v", err)
+		}
+	}()
 
 	// Кривое исполнение, но пока не представляю как работают миграции в Go
 	err = db.RunMigrations()
@@ -38,6 +50,12 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if cfg.LinkSweepInterval > 0 {
+		sweeper := database.NewSweeper(db, cfg.LinkSweepInterval)
+		sweeper.Start()
+		defer sweeper.Stop()
+	}
+
 	zapLogger := zap.NewExample()
 	defer func() {
 		err := zapLogger.Sync()
@@ -46,7 +64,41 @@ func main() {
 		}
 	}()
 
-	router := r.NewRouter(cfg, db, zapLogger.Sugar())
+	generator, err := url.NewCodeGenerator(*cfg, db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	agents, err := useragent.Load(cfg.UserAgentRules)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var options []handlers.Option
+	if store, ok := interfaces.As[interfaces.ClickStore](db); ok && cfg.ClickBufferSize > 0 {
+		pipeline := clicks.NewPipeline(store, clicks.Options{
+			BufferSize:    cfg.ClickBufferSize,
+			Workers:       cfg.ClickWorkers,
+			BatchSize:     cfg.ClickBatchSize,
+			FlushInterval: cfg.ClickFlushInterval,
+		})
+		pipeline.Start()
+		// Дописываем накопленные события при остановке сервера
+		defer pipeline.Stop()
+		expvar.Publish("click_events", expvar.Func(func() any { return pipeline.Stats() }))
+		options = append(options, handlers.WithClickTracker(pipeline))
+	}
+
+	if roller, ok := interfaces.As[interfaces.ClickRoller](db); ok && cfg.ClickRollupInterval > 0 {
+		rollup := clicks.NewRollup(roller, clicks.RollupOptions{
+			Interval:  cfg.ClickRollupInterval,
+			Lag:       cfg.ClickRollupLag,
+			Retention: cfg.ClickRetention,
+		})
+		rollup.Start()
+		defer rollup.Stop()
+	}
+
+	router := r.NewRouter(cfg, db, generator, agents, zapLogger.Sugar(), options...)
 
 	server := http.NewServer(*cfg, *router, db, zapLogger.Sugar())
 	err = server.StartPooling()
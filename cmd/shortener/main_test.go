@@ -3,9 +3,15 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"github.com/thxhix/shortener/internal/clicks"
 	"github.com/thxhix/shortener/internal/config"
 	"github.com/thxhix/shortener/internal/database"
+	"github.com/thxhix/shortener/internal/database/interfaces"
+	"github.com/thxhix/shortener/internal/handlers"
+	"github.com/thxhix/shortener/internal/models"
 	"github.com/thxhix/shortener/internal/router"
+	"github.com/thxhix/shortener/internal/url"
 	"go.uber.org/zap"
 	"log"
 	"net/http"
@@ -21,6 +27,9 @@ import (
 var cfg config.Config
 var route *chi.Mux
 
+// testSeed makes the short codes issued by the router predictable.
+const testSeed = 1
+
 func TestMain(m *testing.M) {
 	conf, err := config.NewConfig()
 	if err != nil {
@@ -28,6 +37,16 @@ func TestMain(m *testing.M) {
 	}
 	cfg = *conf
 
+	// Каждый прогон работает со своим файлом, чтобы не ловить дубликаты с прошлых запусков
+	storage, err := os.CreateTemp("", "shortener-test-*.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.DBFileName = storage.Name()
+	if err := storage.Close(); err != nil {
+		log.Fatal(err)
+	}
+
 	db, err := database.NewDatabase(&cfg)
 	if err != nil {
 		log.Fatal(err)
@@ -41,9 +60,18 @@ func TestMain(m *testing.M) {
 		}
 	}()
 
-	route = router.NewRouter(&cfg, db, zapLogger.Sugar())
+	route = router.NewRouter(&cfg, db, url.NewSeededGenerator(testSeed, cfg.CodeLength), nil, zapLogger.Sugar())
 
-	os.Exit(m.Run())
+	code := m.Run()
+	if err := db.Close(); err != nil {
+		log.Println(err)
+	}
+	for _, path := range []string{cfg.DBFileName, cfg.DBFileName + ".lock"} {
+		if err := os.Remove(path); err != nil {
+			log.Println(err)
+		}
+	}
+	os.Exit(code)
 }
 
 func Test_shortLink(t *testing.T) {
@@ -105,6 +133,11 @@ func Test_getFullLink(t *testing.T) {
 		statusCode  int
 		header      string
 	}
+
+	// Ссылку на https://ya.ru создаёт Test_shortLink, получая первый код генератора
+	shorten, err := url.NewSeededGenerator(testSeed, cfg.CodeLength).Generate(context.Background())
+	require.NoError(t, err)
+
 	tests := []struct {
 		name   string
 		want   want
@@ -127,7 +160,7 @@ func Test_getFullLink(t *testing.T) {
 				statusCode:  http.StatusTemporaryRedirect,
 				header:      "https://ya.ru",
 			},
-			action: "/testHash",
+			action: "/" + shorten,
 			method: http.MethodGet,
 		},
 	}
@@ -194,6 +227,329 @@ func Test_APIStoreLink(t *testing.T) {
 	}
 }
 
+func Test_APIStoreLinkAlias(t *testing.T) {
+	type want struct {
+		contentType string
+		statusCode  int
+		body        string
+	}
+
+	tests := []struct {
+		name   string
+		action string
+		method string
+		body   string
+		want   want
+	}{
+		{
+			name:   "API store link with alias request",
+			action: "/api/shorten",
+			method: http.MethodPost,
+			body:   "{\"url\": \"https://test.ru/alias\", \"alias\": \"my-alias\"}",
+			want: want{
+				contentType: "application/json",
+				statusCode:  http.StatusCreated,
+				body:        "{\"result\":\"" + cfg.BaseURL + "/my-alias\"}",
+			},
+		},
+		{
+			name:   "API store link with taken alias request",
+			action: "/api/shorten",
+			method: http.MethodPost,
+			body:   "{\"url\": \"https://test.ru/other\", \"alias\": \"my-alias\"}",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				statusCode:  http.StatusConflict,
+				body:        "такой alias уже занят\n",
+			},
+		},
+		{
+			name:   "API store link with reserved alias request",
+			action: "/api/shorten",
+			method: http.MethodPost,
+			body:   "{\"url\": \"https://test.ru/other\", \"alias\": \"ping\"}",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				statusCode:  http.StatusBadRequest,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.action, strings.NewReader(tt.body))
+			if err != nil {
+				panic(err)
+			}
+			w := httptest.NewRecorder()
+
+			route.ServeHTTP(w, req)
+
+			require.Equal(t, tt.want.statusCode, w.Code, "Код ответа не совпадает с ожидаемым")
+			require.Equal(t, tt.want.contentType, w.Header().Get("Content-Type"), "Content-Type ответа не совпадает с ожидаемым")
+			if tt.want.body != "" {
+				require.Equal(t, tt.want.body, w.Body.String(), "Тело ответа не совпадает с ожидаемым")
+			}
+		})
+	}
+}
+
+func Test_RedirectLinkLimits(t *testing.T) {
+	type want struct {
+		statusCode int
+		location   string
+		body       string
+	}
+
+	tests := []struct {
+		name      string
+		action    string
+		method    string
+		body      string
+		userAgent string
+		want      want
+	}{
+		{
+			name:   "API store link with click limit request",
+			action: "/api/shorten",
+			method: http.MethodPost,
+			body:   "{\"url\": \"https://test.ru/limited\", \"alias\": \"one-click\", \"max_clicks\": 1}",
+			want: want{
+				statusCode: http.StatusCreated,
+			},
+		},
+		{
+			name:   "API store link with past expiration request",
+			action: "/api/shorten",
+			method: http.MethodPost,
+			body:   "{\"url\": \"https://test.ru/expired\", \"expires_at\": \"2000-01-01T00:00:00Z\"}",
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name:   "API store one-time links batch request",
+			action: "/api/shorten/batch",
+			method: http.MethodPost,
+			body:   "[{\"correlation_id\": \"1\", \"original_url\": \"https://test.ru/once\", \"alias\": \"once-only\", \"one_time\": true}]",
+			want: want{
+				statusCode: http.StatusCreated,
+			},
+		},
+		{
+			name:      "Link preview of one-time link request",
+			action:    "/once-only",
+			method:    http.MethodGet,
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			want: want{
+				statusCode: http.StatusForbidden,
+				body:       "ссылка с ограничением переходов не открывается ботам\n",
+			},
+		},
+		{
+			name:   "Redirect one-time link after preview request",
+			action: "/once-only",
+			method: http.MethodGet,
+			want: want{
+				statusCode: http.StatusTemporaryRedirect,
+				location:   "https://test.ru/once",
+			},
+		},
+		{
+			name:   "Redirect used one-time link request",
+			action: "/once-only",
+			method: http.MethodGet,
+			want: want{
+				statusCode: http.StatusGone,
+				body:       "срок действия ссылки истёк\n",
+			},
+		},
+		{
+			name:   "API store scheduled link request",
+			action: "/api/shorten",
+			method: http.MethodPost,
+			body:   "{\"url\": \"https://test.ru/scheduled\", \"alias\": \"scheduled\", \"active_from\": \"2999-01-01T00:00:00Z\"}",
+			want: want{
+				statusCode: http.StatusCreated,
+			},
+		},
+		{
+			name:   "Redirect not yet active link request",
+			action: "/scheduled",
+			method: http.MethodGet,
+			want: want{
+				statusCode: http.StatusNotFound,
+				body:       "ссылка ещё не активна\n",
+			},
+		},
+		{
+			name:   "Redirect within click limit request",
+			action: "/one-click",
+			method: http.MethodGet,
+			want: want{
+				statusCode: http.StatusTemporaryRedirect,
+				location:   "https://test.ru/limited",
+			},
+		},
+		{
+			name:   "Redirect over click limit request",
+			action: "/one-click",
+			method: http.MethodGet,
+			want: want{
+				statusCode: http.StatusGone,
+				body:       "срок действия ссылки истёк\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.action, strings.NewReader(tt.body))
+			if err != nil {
+				panic(err)
+			}
+			if tt.userAgent != "" {
+				req.Header.Set("User-Agent", tt.userAgent)
+			}
+			w := httptest.NewRecorder()
+
+			route.ServeHTTP(w, req)
+
+			require.Equal(t, tt.want.statusCode, w.Code, "Код ответа не совпадает с ожидаемым")
+			require.Equal(t, tt.want.location, w.Header().Get("Location"), "Location ответа не совпадает с ожидаемым")
+			if tt.want.body != "" {
+				require.Equal(t, tt.want.body, w.Body.String(), "Тело ответа не совпадает с ожидаемым")
+			}
+		})
+	}
+}
+
+func Test_RedirectPassword(t *testing.T) {
+	type want struct {
+		statusCode  int
+		contentType string
+		location    string
+		body        string
+	}
+
+	tests := []struct {
+		name    string
+		action  string
+		method  string
+		body    string
+		headers map[string]string
+		want    want
+	}{
+		{
+			name:   "API store link with password request",
+			action: "/api/shorten",
+			method: http.MethodPost,
+			body:   "{\"url\": \"https://test.ru/private\", \"alias\": \"private-doc\", \"password\": \"pa$$\"}",
+			want: want{
+				statusCode:  http.StatusCreated,
+				contentType: "application/json",
+			},
+		},
+		{
+			name:   "Redirect without password request",
+			action: "/private-doc",
+			method: http.MethodGet,
+			want: want{
+				statusCode:  http.StatusUnauthorized,
+				contentType: "text/html; charset=utf-8",
+				body:        "<form method=\"post\">",
+			},
+		},
+		{
+			name:    "Redirect with wrong password header request",
+			action:  "/private-doc",
+			method:  http.MethodGet,
+			headers: map[string]string{handlers.PasswordHeader: "wrong"},
+			want: want{
+				statusCode:  http.StatusUnauthorized,
+				contentType: "text/plain; charset=utf-8",
+				body:        "неверный пароль",
+			},
+		},
+		{
+			name:    "Redirect with password header request",
+			action:  "/private-doc",
+			method:  http.MethodGet,
+			headers: map[string]string{handlers.PasswordHeader: "pa$$"},
+			want: want{
+				statusCode: http.StatusTemporaryRedirect,
+				location:   "https://test.ru/private",
+			},
+		},
+		{
+			name:   "Redirect with password query request",
+			action: "/private-doc?password=pa%24%24",
+			method: http.MethodGet,
+			want: want{
+				statusCode: http.StatusTemporaryRedirect,
+				location:   "https://test.ru/private",
+			},
+		},
+		{
+			name:    "Redirect with password form request",
+			action:  "/private-doc",
+			method:  http.MethodPost,
+			body:    "password=pa%24%24",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			want: want{
+				statusCode: http.StatusTemporaryRedirect,
+				location:   "https://test.ru/private",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.action, strings.NewReader(tt.body))
+			if err != nil {
+				panic(err)
+			}
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+
+			route.ServeHTTP(w, req)
+
+			require.Equal(t, tt.want.statusCode, w.Code, "Код ответа не совпадает с ожидаемым")
+			require.Equal(t, tt.want.location, w.Header().Get("Location"), "Location ответа не совпадает с ожидаемым")
+			if tt.want.contentType != "" {
+				require.Equal(t, tt.want.contentType, w.Header().Get("Content-Type"), "Content-Type ответа не совпадает с ожидаемым")
+			}
+			require.Contains(t, w.Body.String(), tt.want.body, "Тело ответа не совпадает с ожидаемым")
+		})
+	}
+}
+
+func Test_RedirectInactiveFallback(t *testing.T) {
+	fallbackCfg := cfg
+	fallbackCfg.DBFileName = ""
+	fallbackCfg.InactiveLinkFallbackURL = "https://test.ru/soon"
+
+	db, err := database.NewDatabase(&fallbackCfg)
+	require.NoError(t, err)
+	defer db.Close()
+	fallbackRoute := router.NewRouter(&fallbackCfg, db, url.NewSeededGenerator(testSeed, fallbackCfg.CodeLength), nil, zap.NewNop().Sugar())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader("{\"url\": \"https://test.ru/campaign\", \"alias\": \"campaign\", \"active_from\": \"2999-01-01T00:00:00Z\"}"))
+	w := httptest.NewRecorder()
+	fallbackRoute.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+
+	req = httptest.NewRequest(http.MethodGet, "/campaign", nil)
+	w = httptest.NewRecorder()
+	fallbackRoute.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code, "Код ответа не совпадает с ожидаемым")
+	require.Equal(t, "https://test.ru/soon", w.Header().Get("Location"), "неактивная ссылка должна вести на запасной URL")
+	require.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
 func Test_BatchStoreLink(t *testing.T) {
 	type want struct {
 		contentType  string
@@ -213,7 +569,7 @@ func Test_BatchStoreLink(t *testing.T) {
 			name:        "Batch store request",
 			action:      "/api/shorten/batch",
 			method:      http.MethodPost,
-			body:        "[\n    {\n        \"correlation_id\": \"333\",\n        \"original_url\": \"https://ya.ru\"\n    },\n    {\n        \"correlation_id\": \"111\",\n        \"original_url\": \"https://google.com\"\n    }\n]",
+			body:        "[\n    {\n        \"correlation_id\": \"333\",\n        \"original_url\": \"https://ya.ru/batch\"\n    },\n    {\n        \"correlation_id\": \"111\",\n        \"original_url\": \"https://google.com\"\n    }\n]",
 			contentType: "application/json",
 
 			want: want{
@@ -263,3 +619,537 @@ func Test_BatchStoreLink(t *testing.T) {
 		})
 	}
 }
+
+func Test_EditLink(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader("{\"url\": \"https://ya.ru/typo\", \"alias\": \"editable\"}"))
+	w := httptest.NewRecorder()
+	route.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+	cookies := w.Result().Cookies()
+	require.NotEmpty(t, cookies, "Ответ должен выдать cookie авторизации")
+
+	type want struct {
+		statusCode int
+		body       string
+		location   string
+	}
+
+	tests := []struct {
+		name   string
+		action string
+		method string
+		body   string
+		owner  bool
+		want   want
+	}{
+		{
+			name:   "Update link of another user",
+			action: "/api/user/urls/editable",
+			method: http.MethodPatch,
+			body:   "{\"url\": \"https://ya.ru/fixed\"}",
+			want: want{
+				statusCode: http.StatusForbidden,
+				body:       "ссылка принадлежит другому пользователю\n",
+			},
+		},
+		{
+			name:   "Update link to invalid URL",
+			action: "/api/user/urls/editable",
+			method: http.MethodPatch,
+			body:   "{\"url\": \"fixed\"}",
+			owner:  true,
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name:   "Update missing link",
+			action: "/api/user/urls/missing-link",
+			method: http.MethodPatch,
+			body:   "{\"url\": \"https://ya.ru/fixed\"}",
+			owner:  true,
+			want: want{
+				statusCode: http.StatusNotFound,
+			},
+		},
+		{
+			name:   "Update link",
+			action: "/api/user/urls/editable",
+			method: http.MethodPatch,
+			body:   "{\"url\": \"https://ya.ru/fixed\"}",
+			owner:  true,
+			want: want{
+				statusCode: http.StatusOK,
+				body:       "{\"version\":2,\"original_url\":\"https://ya.ru/fixed\",\"current\":true}",
+			},
+		},
+		{
+			name:   "Redirect to updated link",
+			action: "/editable",
+			method: http.MethodGet,
+			want: want{
+				statusCode: http.StatusTemporaryRedirect,
+				location:   "https://ya.ru/fixed",
+			},
+		},
+		{
+			name:   "Rollback to missing version",
+			action: "/api/user/urls/editable/rollback",
+			method: http.MethodPost,
+			body:   "{\"version\": 5}",
+			owner:  true,
+			want: want{
+				statusCode: http.StatusNotFound,
+				body:       "версия ссылки не найдена\n",
+			},
+		},
+		{
+			name:   "Rollback link",
+			action: "/api/user/urls/editable/rollback",
+			method: http.MethodPost,
+			body:   "{\"version\": 1}",
+			owner:  true,
+			want: want{
+				statusCode: http.StatusOK,
+				body:       "{\"version\":3,\"original_url\":\"https://ya.ru/typo\",\"current\":true}",
+			},
+		},
+		{
+			name:   "Redirect to rolled back link",
+			action: "/editable",
+			method: http.MethodGet,
+			want: want{
+				statusCode: http.StatusTemporaryRedirect,
+				location:   "https://ya.ru/typo",
+			},
+		},
+		{
+			name:   "Versions of another user",
+			action: "/api/user/urls/editable/versions",
+			method: http.MethodGet,
+			want: want{
+				statusCode: http.StatusForbidden,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.action, strings.NewReader(tt.body))
+			if tt.owner {
+				for _, cookie := range cookies {
+					req.AddCookie(cookie)
+				}
+			}
+			w := httptest.NewRecorder()
+
+			route.ServeHTTP(w, req)
+
+			require.Equal(t, tt.want.statusCode, w.Code, "Код ответа не совпадает с ожидаемым")
+			if tt.want.body != "" {
+				require.Equal(t, tt.want.body, w.Body.String(), "Тело ответа не совпадает с ожидаемым")
+			}
+			if tt.want.location != "" {
+				require.Equal(t, tt.want.location, w.Header().Get("Location"), "Location не совпадает с ожидаемым")
+			}
+		})
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user/urls/editable/versions", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	route.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, "Код ответа не совпадает с ожидаемым")
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var versions models.LinkVersionList
+	require.NoError(t, versions.UnmarshalJSON(w.Body.Bytes()))
+	require.Len(t, versions, 3, "Должны вернуться все версии ссылки")
+	require.Equal(t, "https://ya.ru/fixed", versions[1].URL)
+	require.NotNil(t, versions[1].ReplacedAt)
+	require.True(t, versions[2].Current)
+}
+
+func Test_RedirectCaching(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader("{\"url\": \"https://ya.ru/permanent\", \"alias\": \"permanent\", \"redirect_code\": 301, \"cache_max_age\": 600}"))
+	w := httptest.NewRecorder()
+	route.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+	cookies := w.Result().Cookies()
+
+	type want struct {
+		statusCode   int
+		cacheControl string
+	}
+
+	tests := []struct {
+		name   string
+		action string
+		method string
+		body   string
+		want   want
+	}{
+		{
+			name:   "Store link with unsupported redirect code",
+			action: "/api/shorten",
+			method: http.MethodPost,
+			body:   "{\"url\": \"https://ya.ru/see-other\", \"redirect_code\": 303}",
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name:   "Store tracked link with max-age",
+			action: "/api/shorten",
+			method: http.MethodPost,
+			body:   "{\"url\": \"https://ya.ru/tracked\", \"alias\": \"tracked\", \"redirect_code\": 308, \"cache_max_age\": 600, \"max_clicks\": 5}",
+			want: want{
+				statusCode: http.StatusCreated,
+			},
+		},
+		{
+			name:   "Redirect permanent link",
+			action: "/permanent",
+			method: http.MethodGet,
+			want: want{
+				statusCode:   http.StatusMovedPermanently,
+				cacheControl: "public, max-age=600",
+			},
+		},
+		{
+			name:   "Redirect tracked link",
+			action: "/tracked",
+			method: http.MethodGet,
+			want: want{
+				statusCode:   http.StatusPermanentRedirect,
+				cacheControl: "no-store",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.action, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			route.ServeHTTP(w, req)
+
+			require.Equal(t, tt.want.statusCode, w.Code, "Код ответа не совпадает с ожидаемым")
+			require.Equal(t, tt.want.cacheControl, w.Header().Get("Cache-Control"), "Cache-Control не совпадает с ожидаемым")
+		})
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	route.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, "Код ответа не совпадает с ожидаемым")
+	require.Contains(t, w.Body.String(), "\"redirect_code\":301,\"cache_max_age\":600", "Настройки редиректа должны возвращаться в списке ссылок")
+}
+
+// recordingTracker keeps tracked click events.
+type recordingTracker struct {
+	events []models.ClickEvent
+}
+
+func (t *recordingTracker) Track(event models.ClickEvent) bool {
+	t.events = append(t.events, event)
+	return true
+}
+
+func Test_RedirectTracksClicks(t *testing.T) {
+	trackedCfg := cfg
+	trackedCfg.DBFileName = ""
+
+	db, err := database.NewDatabase(&trackedCfg)
+	require.NoError(t, err)
+	defer db.Close()
+	tracker := &recordingTracker{}
+	trackedRoute := router.NewRouter(&trackedCfg, db, url.NewSeededGenerator(testSeed, trackedCfg.CodeLength), nil, zap.NewNop().Sugar(),
+		handlers.WithClickTracker(tracker))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader("{\"url\": \"https://test.ru/tracked\", \"alias\": \"tracked\"}"))
+	w := httptest.NewRecorder()
+	trackedRoute.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+
+	req = httptest.NewRequest(http.MethodGet, "/tracked", nil)
+	req.RemoteAddr = "10.0.0.1:52100"
+	req.Header.Set("Referer", "https://google.com/search")
+	req.Header.Set("User-Agent", "curl/8.0")
+	w = httptest.NewRecorder()
+	trackedRoute.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code, "Код ответа не совпадает с ожидаемым")
+
+	req = httptest.NewRequest(http.MethodGet, "/missing-link", nil)
+	w = httptest.NewRecorder()
+	trackedRoute.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code, "Код ответа не совпадает с ожидаемым")
+
+	// Боты превью получают редирект, но в переходы не попадают
+	req = httptest.NewRequest(http.MethodGet, "/tracked", nil)
+	req.Header.Set("User-Agent", "TelegramBot (like TwitterBot)")
+	w = httptest.NewRecorder()
+	trackedRoute.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code, "Код ответа не совпадает с ожидаемым")
+
+	req = httptest.NewRequest(http.MethodGet, "/tracked", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1")
+	w = httptest.NewRecorder()
+	trackedRoute.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code, "Код ответа не совпадает с ожидаемым")
+
+	require.Len(t, tracker.events, 2, "Учитываться должны только успешные редиректы не от ботов")
+	event := tracker.events[0]
+	require.Equal(t, "tracked", event.Hash)
+	require.Equal(t, "https://google.com/search", event.Referrer)
+	require.Equal(t, "curl/8.0", event.UserAgent)
+	require.Equal(t, "10.0.0.1", event.IP)
+	require.False(t, event.Time.IsZero())
+	require.Equal(t, "curl", event.Browser)
+
+	event = tracker.events[1]
+	require.Equal(t, "mobile", event.Device)
+	require.Equal(t, "iOS", event.OS)
+	require.Equal(t, "Safari", event.Browser)
+}
+
+func Test_LinkStats(t *testing.T) {
+	statsCfg := cfg
+	statsCfg.DBFileName = ""
+
+	db, err := database.NewDatabase(&statsCfg)
+	require.NoError(t, err)
+	defer db.Close()
+	store, ok := interfaces.As[interfaces.ClickStore](db)
+	require.True(t, ok, "хранилище должно принимать события переходов")
+	pipeline := clicks.NewPipeline(store, clicks.Options{})
+	pipeline.Start()
+	statsRoute := router.NewRouter(&statsCfg, db, url.NewSeededGenerator(testSeed, statsCfg.CodeLength), nil, zap.NewNop().Sugar(),
+		handlers.WithClickTracker(pipeline))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader("{\"url\": \"https://test.ru/stats\", \"alias\": \"with-stats\"}"))
+	w := httptest.NewRecorder()
+	statsRoute.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+	cookies := w.Result().Cookies()
+
+	for _, referrer := range []string{"https://google.com/search", "https://google.com/", ""} {
+		req = httptest.NewRequest(http.MethodGet, "/with-stats", nil)
+		req.Header.Set("Referer", referrer)
+		req.Header.Set("User-Agent", "curl/8.0")
+		w = httptest.NewRecorder()
+		statsRoute.ServeHTTP(w, req)
+		require.Equal(t, http.StatusTemporaryRedirect, w.Code, "Код ответа не совпадает с ожидаемым")
+	}
+	// Остановка дописывает накопленные события
+	pipeline.Stop()
+
+	type want struct {
+		statusCode int
+	}
+
+	tests := []struct {
+		name   string
+		action string
+		owner  bool
+		want   want
+	}{
+		{
+			name:   "Stats of another user",
+			action: "/api/user/urls/with-stats/stats",
+			want:   want{statusCode: http.StatusForbidden},
+		},
+		{
+			name:   "Stats with invalid bucket",
+			action: "/api/user/urls/with-stats/stats?bucket=minute",
+			owner:  true,
+			want:   want{statusCode: http.StatusBadRequest},
+		},
+		{
+			name:   "Stats with invalid range",
+			action: "/api/user/urls/with-stats/stats?from=yesterday",
+			owner:  true,
+			want:   want{statusCode: http.StatusBadRequest},
+		},
+		{
+			name:   "Stats of missing link",
+			action: "/api/user/urls/missing-link/stats",
+			owner:  true,
+			want:   want{statusCode: http.StatusNotFound},
+		},
+		{
+			name:   "Stats by hour",
+			action: "/api/user/urls/with-stats/stats?bucket=hour",
+			owner:  true,
+			want:   want{statusCode: http.StatusOK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.action, nil)
+			if tt.owner {
+				for _, cookie := range cookies {
+					req.AddCookie(cookie)
+				}
+			}
+			w := httptest.NewRecorder()
+
+			statsRoute.ServeHTTP(w, req)
+
+			require.Equal(t, tt.want.statusCode, w.Code, "Код ответа не совпадает с ожидаемым")
+		})
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user/urls/with-stats/stats?bucket=hour", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	statsRoute.ServeHTTP(w, req)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var stats models.LinkStats
+	require.NoError(t, stats.UnmarshalJSON(w.Body.Bytes()))
+	require.Equal(t, 3, stats.TotalClicks, "Должны учитываться все переходы")
+	require.Equal(t, 1, stats.UniqueVisitors)
+	require.Len(t, stats.Series, 24*7+1)
+	require.Equal(t, 3, stats.Series[len(stats.Series)-1].Clicks)
+	require.Equal(t, models.StatsCountList{{Name: "google.com", Clicks: 2}, {Name: "(direct)", Clicks: 1}}, stats.TopReferrers)
+	require.Equal(t, models.StatsCountList{{Name: "curl", Clicks: 3}}, stats.UserAgents)
+}
+
+func Test_Targeting(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader("{\"url\": \"https://ya.ru/app\", \"alias\": \"targeted\"}"))
+	w := httptest.NewRecorder()
+	route.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, "Код ответа не совпадает с ожидаемым")
+	cookies := w.Result().Cookies()
+	require.NotEmpty(t, cookies, "Ответ должен выдать cookie авторизации")
+
+	const (
+		iPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+		android = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36"
+		desktop = "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"
+		rules   = "[{\"os\": \"iOS\", \"url\": \"https://apps.apple.com/app/id1\"}, {\"os\": \"Android\", \"url\": \"https://play.google.com/store/apps/details?id=app\"}]"
+	)
+
+	type want struct {
+		statusCode int
+		location   string
+		vary       string
+	}
+
+	tests := []struct {
+		name      string
+		action    string
+		method    string
+		body      string
+		userAgent string
+		owner     bool
+		want      want
+	}{
+		{
+			name:   "Update targeting of another user",
+			action: "/api/user/urls/targeted/targeting",
+			method: http.MethodPut,
+			body:   rules,
+			want: want{
+				statusCode: http.StatusForbidden,
+			},
+		},
+		{
+			name:   "Update targeting with rule without conditions",
+			action: "/api/user/urls/targeted/targeting",
+			method: http.MethodPut,
+			body:   "[{\"url\": \"https://ya.ru/other\"}]",
+			owner:  true,
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name:   "Update targeting",
+			action: "/api/user/urls/targeted/targeting",
+			method: http.MethodPut,
+			body:   rules,
+			owner:  true,
+			want: want{
+				statusCode: http.StatusOK,
+			},
+		},
+		{
+			name:      "Redirect iPhone",
+			action:    "/targeted",
+			method:    http.MethodGet,
+			userAgent: iPhone,
+			want: want{
+				statusCode: http.StatusTemporaryRedirect,
+				location:   "https://apps.apple.com/app/id1",
+				vary:       "User-Agent, Accept-Language",
+			},
+		},
+		{
+			name:      "Redirect Android",
+			action:    "/targeted",
+			method:    http.MethodGet,
+			userAgent: android,
+			want: want{
+				statusCode: http.StatusTemporaryRedirect,
+				location:   "https://play.google.com/store/apps/details?id=app",
+				vary:       "User-Agent, Accept-Language",
+			},
+		},
+		{
+			name:      "Redirect desktop",
+			action:    "/targeted",
+			method:    http.MethodGet,
+			userAgent: desktop,
+			want: want{
+				statusCode: http.StatusTemporaryRedirect,
+				location:   "https://ya.ru/app",
+				vary:       "User-Agent, Accept-Language",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.action, strings.NewReader(tt.body))
+			req.Header.Set("User-Agent", tt.userAgent)
+			if tt.owner {
+				for _, cookie := range cookies {
+					req.AddCookie(cookie)
+				}
+			}
+			w := httptest.NewRecorder()
+
+			route.ServeHTTP(w, req)
+
+			require.Equal(t, tt.want.statusCode, w.Code, "Код ответа не совпадает с ожидаемым")
+			if tt.want.location != "" {
+				require.Equal(t, tt.want.location, w.Header().Get("Location"), "Location не совпадает с ожидаемым")
+			}
+			require.Equal(t, tt.want.vary, w.Header().Get("Vary"), "Vary не совпадает с ожидаемым")
+		})
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user/urls/targeted/targeting", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	route.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, "Код ответа не совпадает с ожидаемым")
+
+	var stored models.TargetingRuleList
+	require.NoError(t, stored.UnmarshalJSON(w.Body.Bytes()))
+	require.Len(t, stored, 2, "Должны вернуться все правила таргетинга")
+	require.Equal(t, "iOS", stored[0].OS)
+}
@@ -0,0 +1,105 @@
+// Package bloom implements a concurrent Bloom filter of strings.
+package bloom
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// Filter is a Bloom filter safe for concurrent use without locks.
+// It never reports an added string as missing, but may report
+// a string that was never added as present.
+type Filter struct {
+	words  []atomic.Uint64
+	size   uint64
+	hashes uint64
+	seed   maphash.Seed
+	added  atomic.Uint64
+}
+
+// New creates a filter sized to hold the expected number of strings
+// with the given false-positive rate.
+func New(expected int, rate float64) *Filter {
+	if expected < 1 {
+		expected = 1
+	}
+	if rate <= 0 || rate >= 1 {
+		rate = 0.01
+	}
+
+	size := uint64(math.Ceil(-float64(expected) * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(math.Round(float64(size) / float64(expected) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+
+	return &Filter{
+		words:  make([]atomic.Uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+		seed:   maphash.MakeSeed(),
+	}
+}
+
+// Add adds the string to the filter.
+func (f *Filter) Add(value string) {
+	h1, h2 := f.hash(value)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		word := &f.words[bit/64]
+		mask := uint64(1) << (bit % 64)
+		for {
+			old := word.Load()
+			if old&mask != 0 || word.CompareAndSwap(old, old|mask) {
+				break
+			}
+		}
+	}
+	f.added.Add(1)
+}
+
+// Contains reports whether the string may have been added to the filter.
+func (f *Filter) Contains(value string) bool {
+	h1, h2 := f.hash(value)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		if f.words[bit/64].Load()&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Added returns the number of Add calls, including repeated strings.
+func (f *Filter) Added() uint64 {
+	return f.added.Load()
+}
+
+// Size returns the number of bits in the filter.
+func (f *Filter) Size() uint64 {
+	return f.size
+}
+
+// Hashes returns the number of bits set per string.
+func (f *Filter) Hashes() uint64 {
+	return f.hashes
+}
+
+// EstimatedFalsePositiveRate estimates the probability that Contains
+// reports a string that was never added, from the share of set bits.
+func (f *Filter) EstimatedFalsePositiveRate() float64 {
+	var set int
+	for i := range f.words {
+		set += bits.OnesCount64(f.words[i].Load())
+	}
+	return math.Pow(float64(set)/float64(f.size), float64(f.hashes))
+}
+
+// hash returns two hashes of the value, combined as h1 + i*h2
+// to get the position of the i-th bit.
+func (f *Filter) hash(value string) (uint64, uint64) {
+	h := maphash.String(f.seed, value)
+	return h, h>>32 | h<<32 | 1
+}
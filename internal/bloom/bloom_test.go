@@ -0,0 +1,40 @@
+package bloom
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	const expected = 10000
+	filter := New(expected, 0.01)
+
+	for i := 0; i < expected; i++ {
+		filter.Add("known-" + strconv.Itoa(i))
+	}
+	for i := 0; i < expected; i++ {
+		require.True(t, filter.Contains("known-"+strconv.Itoa(i)), "добавленная строка не должна теряться")
+	}
+	require.EqualValues(t, expected, filter.Added())
+
+	var falsePositives int
+	for i := 0; i < expected; i++ {
+		if filter.Contains("unknown-" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+	rate := float64(falsePositives) / expected
+	require.Less(t, rate, 0.03, "доля ложных срабатываний должна быть близка к целевой")
+	require.InDelta(t, 0.01, filter.EstimatedFalsePositiveRate(), 0.01)
+}
+
+func TestFilter_Empty(t *testing.T) {
+	filter := New(0, 0)
+
+	require.False(t, filter.Contains("aaa"))
+	require.Zero(t, filter.EstimatedFalsePositiveRate())
+	require.Positive(t, filter.Size())
+	require.Positive(t, filter.Hashes())
+}
@@ -0,0 +1,208 @@
+// Package clicks collects click events of redirects and writes them
+// to the storage in the background, so that redirects never wait for it.
+package clicks
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thxhix/shortener/internal/database/interfaces"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+// Defaults of the pipeline used when Options leave a setting unset.
+const (
+	DefaultBufferSize    = 10000
+	DefaultWorkers       = 2
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = time.Second
+)
+
+// writeTimeout bounds a single write of a batch to the storage.
+const writeTimeout = 5 * time.Second
+
+// Options holds optional settings of Pipeline.
+type Options struct {
+	// BufferSize is the number of events waiting to be written, events
+	// tracked over it are dropped. DefaultBufferSize if not set.
+	BufferSize int
+
+	// Workers is the number of goroutines writing batches. DefaultWorkers if not set.
+	Workers int
+
+	// BatchSize is the number of events that triggers a write of the batch
+	// before FlushInterval passes. DefaultBatchSize if not set.
+	BatchSize int
+
+	// FlushInterval is how long a worker collects events before writing
+	// an incomplete batch. DefaultFlushInterval if not set.
+	FlushInterval time.Duration
+}
+
+// Stats holds the counters of the pipeline.
+type Stats struct {
+	// Tracked is the number of events accepted into the buffer.
+	Tracked int64 `json:"tracked"`
+
+	// Dropped is the number of events dropped because the buffer was full
+	// or the pipeline was stopped.
+	Dropped int64 `json:"dropped"`
+
+	// Written is the number of events written to the storage.
+	Written int64 `json:"written"`
+
+	// Failed is the number of events lost on failed writes.
+	Failed int64 `json:"failed"`
+}
+
+// Pipeline buffers click events in a bounded channel and writes them
+// to the ClickStore in batches from background workers.
+//
+// Track never blocks: when the buffer is full the event is dropped
+// and counted, drops are reported to the log by the workers.
+// Stop writes everything buffered before returning.
+type Pipeline struct {
+	store interfaces.ClickStore
+	opts  Options
+
+	// mutex guards closed against a concurrent Stop, so that
+	// Track never sends into the closed channel.
+	mutex  sync.RWMutex
+	closed bool
+	events chan models.ClickEvent
+	wg     sync.WaitGroup
+
+	tracked    atomic.Int64
+	dropped    atomic.Int64
+	written    atomic.Int64
+	failed     atomic.Int64
+	unreported atomic.Int64
+}
+
+// NewPipeline creates a Pipeline writing into the store.
+func NewPipeline(store interfaces.ClickStore, opts Options) *Pipeline {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+
+	return &Pipeline{
+		store:  store,
+		opts:   opts,
+		events: make(chan models.ClickEvent, opts.BufferSize),
+	}
+}
+
+// Start launches the workers.
+func (p *Pipeline) Start() {
+	for i := 0; i < p.opts.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+}
+
+// Stop stops accepting events, waits until the workers write
+// all buffered events and exits.
+func (p *Pipeline) Stop() {
+	p.mutex.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.events)
+	}
+	p.mutex.Unlock()
+
+	p.wg.Wait()
+	p.reportDrops()
+}
+
+// Track enqueues the event without blocking and reports whether it was
+// accepted. The event is dropped if the buffer is full or the pipeline is stopped.
+func (p *Pipeline) Track(event models.ClickEvent) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if !p.closed {
+		select {
+		case p.events <- event:
+			p.tracked.Add(1)
+			return true
+		default:
+		}
+	}
+
+	p.dropped.Add(1)
+	p.unreported.Add(1)
+	return false
+}
+
+// Stats returns the current counters of the pipeline.
+func (p *Pipeline) Stats() Stats {
+	return Stats{
+		Tracked: p.tracked.Load(),
+		Dropped: p.dropped.Load(),
+		Written: p.written.Load(),
+		Failed:  p.failed.Load(),
+	}
+}
+
+func (p *Pipeline) worker() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make(models.ClickEventList, 0, p.opts.BatchSize)
+	for {
+		select {
+		case event, ok := <-p.events:
+			if !ok {
+				p.write(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= p.opts.BatchSize {
+				p.write(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			p.write(batch)
+			batch = batch[:0]
+			p.reportDrops()
+		}
+	}
+}
+
+// write stores the batch, the events of a failed write are lost.
+func (p *Pipeline) write(batch models.ClickEventList) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if err := p.store.AddClickEvents(ctx, batch); err != nil {
+		p.failed.Add(int64(len(batch)))
+		log.Printf("ошибка записи событий переходов (%d шт.): %v", len(batch), err)
+		return
+	}
+	p.written.Add(int64(len(batch)))
+}
+
+// reportDrops logs the number of events dropped since the last report.
+func (p *Pipeline) reportDrops() {
+	if dropped := p.unreported.Swap(0); dropped > 0 {
+		log.Printf("отброшено событий переходов: %d", dropped)
+	}
+}
@@ -0,0 +1,93 @@
+package clicks
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+// recordingStore keeps written batches and can hold writes until release is closed.
+type recordingStore struct {
+	mutex   sync.Mutex
+	events  models.ClickEventList
+	batches int
+	release chan struct{}
+}
+
+func (s *recordingStore) AddClickEvents(ctx context.Context, events models.ClickEventList) error {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = append(s.events, events...)
+	s.batches++
+	return nil
+}
+
+func (s *recordingStore) written() (int, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.events), s.batches
+}
+
+func TestPipeline_Batches(t *testing.T) {
+	store := &recordingStore{}
+	pipeline := NewPipeline(store, Options{BufferSize: 100, Workers: 1, BatchSize: 10, FlushInterval: time.Hour})
+	pipeline.Start()
+
+	for i := 0; i < 25; i++ {
+		require.True(t, pipeline.Track(models.ClickEvent{Hash: "h" + strconv.Itoa(i), Time: time.Now()}))
+	}
+	require.Eventually(t, func() bool {
+		events, _ := store.written()
+		return events == 20
+	}, time.Second, time.Millisecond, "полные пачки должны записываться сразу")
+
+	pipeline.Stop()
+	events, batches := store.written()
+	require.Equal(t, 25, events, "остаток буфера должен дописываться при остановке")
+	require.Equal(t, 3, batches)
+	require.Equal(t, Stats{Tracked: 25, Written: 25}, pipeline.Stats())
+
+	require.False(t, pipeline.Track(models.ClickEvent{Hash: "late"}), "после остановки события не принимаются")
+	require.EqualValues(t, 1, pipeline.Stats().Dropped)
+}
+
+func TestPipeline_FlushInterval(t *testing.T) {
+	store := &recordingStore{}
+	pipeline := NewPipeline(store, Options{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	pipeline.Start()
+	defer pipeline.Stop()
+
+	require.True(t, pipeline.Track(models.ClickEvent{Hash: "aaa"}))
+	require.Eventually(t, func() bool {
+		events, _ := store.written()
+		return events == 1
+	}, time.Second, time.Millisecond, "неполная пачка должна записываться по таймеру")
+}
+
+func TestPipeline_Drops(t *testing.T) {
+	store := &recordingStore{release: make(chan struct{})}
+	pipeline := NewPipeline(store, Options{BufferSize: 2, Workers: 1, BatchSize: 1, FlushInterval: time.Hour})
+	pipeline.Start()
+
+	// Первое событие забирает воркер и зависает на записи, два заполняют буфер
+	require.True(t, pipeline.Track(models.ClickEvent{Hash: "aaa"}))
+	require.Eventually(t, func() bool { return len(pipeline.events) == 0 }, time.Second, time.Millisecond)
+	require.True(t, pipeline.Track(models.ClickEvent{Hash: "bbb"}))
+	require.True(t, pipeline.Track(models.ClickEvent{Hash: "ccc"}))
+
+	start := time.Now()
+	require.False(t, pipeline.Track(models.ClickEvent{Hash: "ddd"}), "при полном буфере событие отбрасывается")
+	require.Less(t, time.Since(start), 100*time.Millisecond, "Track не должен ждать хранилище")
+
+	close(store.release)
+	pipeline.Stop()
+	require.Equal(t, Stats{Tracked: 3, Dropped: 1, Written: 3}, pipeline.Stats())
+}
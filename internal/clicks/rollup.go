@@ -0,0 +1,114 @@
+package clicks
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/thxhix/shortener/internal/database/interfaces"
+)
+
+// Defaults of the rollup job used when RollupOptions leave a setting unset.
+const (
+	DefaultRollupInterval = 5 * time.Minute
+	DefaultRollupLag      = 5 * time.Minute
+)
+
+// RollupOptions holds optional settings of Rollup.
+type RollupOptions struct {
+	// Interval is how often the job runs. DefaultRollupInterval if not set.
+	Interval time.Duration
+
+	// Lag is how long the job waits for events of an hour to be written before
+	// rolling the hour up. Events written later are kept raw only, so the lag
+	// must exceed the flush interval of the pipeline. DefaultRollupLag if not set.
+	Lag time.Duration
+
+	// Retention is how long raw events are kept after they are rolled up.
+	// Zero keeps them forever.
+	Retention time.Duration
+}
+
+// Rollup periodically rolls click events up into hourly and daily summaries
+// and prunes raw events older than the retention.
+//
+// Only complete hours are rolled up, and the storage moves its watermark
+// together with the summaries, so an interrupted run is simply repeated
+// by the next one.
+type Rollup struct {
+	roller interfaces.ClickRoller
+	opts   RollupOptions
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRollup creates a Rollup of the storage.
+func NewRollup(roller interfaces.ClickRoller, opts RollupOptions) *Rollup {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultRollupInterval
+	}
+	if opts.Lag <= 0 {
+		opts.Lag = DefaultRollupLag
+	}
+
+	return &Rollup{
+		roller: roller,
+		opts:   opts,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start launches the rollup goroutine.
+func (r *Rollup) Start() {
+	r.wg.Add(1)
+	go r.loop()
+}
+
+// Stop stops the rollup goroutine and waits until it exits.
+func (r *Rollup) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+}
+
+// Run rolls up the complete hours that ended at least Lag before now, then
+// prunes the raw events older than the retention. Returns the number
+// of rolled up and of pruned events.
+func (r *Rollup) Run(ctx context.Context, now time.Time) (rolled int, pruned int, err error) {
+	until := now.UTC().Add(-r.opts.Lag).Truncate(time.Hour)
+	rolled, err = r.roller.RollupClicks(ctx, until)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if r.opts.Retention > 0 {
+		pruned, err = r.roller.PruneClicks(ctx, now.Add(-r.opts.Retention))
+	}
+	return rolled, pruned, err
+}
+
+func (r *Rollup) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), r.opts.Interval)
+			rolled, pruned, err := r.Run(ctx, now)
+			cancel()
+			if err != nil {
+				log.Printf("ошибка свёртки событий переходов: %v", err)
+				continue
+			}
+			if rolled > 0 || pruned > 0 {
+				log.Printf("свёрнуто событий переходов: %d, удалено: %d", rolled, pruned)
+			}
+		}
+	}
+}
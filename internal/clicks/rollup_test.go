@@ -0,0 +1,39 @@
+package clicks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thxhix/shortener/internal/database/drivers"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+func TestRollup_Run(t *testing.T) {
+	db, err := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	now := time.Date(2024, time.May, 15, 12, 3, 0, 0, time.UTC)
+	require.NoError(t, db.AddClickEvents(ctx, models.ClickEventList{
+		{Hash: "aaa", Time: now.Add(-50 * time.Hour)},
+		{Hash: "aaa", Time: now.Add(-2 * time.Hour)},
+		{Hash: "aaa", Time: now.Add(-time.Minute)},
+	}))
+
+	rollup := NewRollup(db, RollupOptions{Lag: 5 * time.Minute, Retention: 24 * time.Hour})
+	rolled, pruned, err := rollup.Run(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, 2, rolled, "час, не вышедший за задержку, не сворачивается")
+	require.Equal(t, 1, pruned)
+
+	watermark, err := db.ClickWatermark(ctx)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.May, 15, 11, 0, 0, 0, time.UTC), watermark, "сворачиваются только целые часы")
+
+	rolled, pruned, err = rollup.Run(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, rolled)
+	require.Zero(t, pruned)
+}
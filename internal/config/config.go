@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"github.com/caarlos0/env/v11"
+	"time"
 )
 
 // Config holds application configuration parameters.
@@ -16,8 +17,147 @@ type Config struct {
 	BaseURL string `env:"BASE_URL" envDefault:"http://localhost:8080"`
 
 	// DBFileName specifies the path to the JSON file used by the file storage driver.
+	// If it points to a directory (or ends with a path separator), rows are stored
+	// in rotating segment files inside that directory.
 	DBFileName string `env:"FILE_STORAGE_PATH" envDefault:"./db.json"`
 
+	// FileSegmentSize is the segment size in bytes that triggers rotation in the directory mode.
+	FileSegmentSize int64 `env:"FILE_SEGMENT_SIZE" envDefault:"67108864"`
+
+	// FileMergeInterval is how often sealed segments are merged in the directory mode, e.g. "1m".
+	// Zero disables merges.
+	FileMergeInterval time.Duration `env:"FILE_MERGE_INTERVAL" envDefault:"1m"`
+
+	// FileCompactInterval enables periodic compaction of the file storage, e.g. "1h".
+	// Zero disables it.
+	FileCompactInterval time.Duration `env:"FILE_COMPACT_INTERVAL" envDefault:"0"`
+
+	// FileSyncMode defines when the file storage fsyncs written rows:
+	// "always" (every group of writes), "batched" (once per FileFlushInterval
+	// or FileFlushSize rows) or "none" (left to the OS).
+	FileSyncMode string `env:"FILE_SYNC_MODE" envDefault:"batched"`
+
+	// FileFlushInterval is how long the file storage collects writes before a flush, e.g. "5ms".
+	FileFlushInterval time.Duration `env:"FILE_FLUSH_INTERVAL" envDefault:"5ms"`
+
+	// FileFlushSize is the number of rows that triggers a flush before FileFlushInterval passes.
+	FileFlushSize int `env:"FILE_FLUSH_SIZE" envDefault:"1000"`
+
+	// MemorySnapshotPath enables persistence of the in-memory storage to the given
+	// snapshot file, e.g. "./memory.snapshot". The change log is kept next to it.
+	MemorySnapshotPath string `env:"MEMORY_SNAPSHOT_PATH"`
+
+	// MemorySnapshotInterval is how often the in-memory storage takes a snapshot, e.g. "5m".
+	// Zero disables periodic snapshots, one is still taken on shutdown.
+	MemorySnapshotInterval time.Duration `env:"MEMORY_SNAPSHOT_INTERVAL" envDefault:"5m"`
+
+	// MemoryShards switches the in-memory storage to the sharded implementation
+	// with the given number of shards if positive. It does not support snapshots.
+	MemoryShards int `env:"MEMORY_SHARDS" envDefault:"0"`
+
+	// MemoryMaxEntries bounds the number of rows in the sharded in-memory storage,
+	// zero means unbounded.
+	MemoryMaxEntries int `env:"MEMORY_MAX_ENTRIES" envDefault:"0"`
+
+	// MemoryEvictionPolicy is the eviction policy of the sharded in-memory storage: "lru" or "ttl".
+	MemoryEvictionPolicy string `env:"MEMORY_EVICTION_POLICY" envDefault:"lru"`
+
+	// MemoryTTL is the lifetime of a row for the "ttl" eviction policy, e.g. "24h".
+	MemoryTTL time.Duration `env:"MEMORY_TTL" envDefault:"0"`
+
+	// RedirectCacheSize enables the cache of hash lookups in front of the storage
+	// with the given number of entries if positive.
+	RedirectCacheSize int `env:"REDIRECT_CACHE_SIZE" envDefault:"0"`
+
+	// RedirectCacheTTL is the lifetime of a cached link, e.g. "1m". Zero means links
+	// stay cached until evicted, except with PostgreSQL, where it means one minute:
+	// other instances sharing the database change links without invalidating
+	// this cache, so the TTL bounds how long it serves them stale.
+	RedirectCacheTTL time.Duration `env:"REDIRECT_CACHE_TTL" envDefault:"1m"`
+
+	// RedirectCacheNegativeTTL is how long a missing hash is cached, e.g. "5s".
+	RedirectCacheNegativeTTL time.Duration `env:"REDIRECT_CACHE_NEGATIVE_TTL" envDefault:"5s"`
+
+	// BloomFilterSize enables the filter of known hashes in front of the storage,
+	// sized for the given number of links, if positive. Unknown hashes are rejected
+	// without querying the storage. With PostgreSQL, which may be shared by several
+	// replicas, an unknown hash is rejected once the filter has caught up with
+	// the links created since its previous catch-up.
+	BloomFilterSize int `env:"BLOOM_FILTER_SIZE" envDefault:"0"`
+
+	// BloomFilterRate is the target false-positive rate of the filter of known hashes.
+	BloomFilterRate float64 `env:"BLOOM_FILTER_RATE" envDefault:"0.01"`
+
+	// BloomFilterCatchUpInterval is the minimal interval between catch-ups of the filter
+	// of known hashes with PostgreSQL, e.g. "50ms". Unknown hashes wait for a catch-up.
+	BloomFilterCatchUpInterval time.Duration `env:"BLOOM_FILTER_CATCH_UP_INTERVAL" envDefault:"50ms"`
+
+	// CodeGenerator selects how short codes are generated: "random" (random base62
+	// codes of CodeLength), "counter" (a sequential counter obfuscated with CodeSalt)
+	// or "range" (sequential IDs reserved in the storage in blocks of CodeRangeSize,
+	// shared by all instances using the same PostgreSQL database).
+	CodeGenerator string `env:"CODE_GENERATOR" envDefault:"random"`
+
+	// CodeRangeSize is the number of IDs reserved at once by the "range" generator.
+	CodeRangeSize int `env:"CODE_RANGE_SIZE" envDefault:"1000"`
+
+	// CodeLength is the length of random short codes.
+	CodeLength int `env:"CODE_LENGTH" envDefault:"8"`
+
+	// CodeMaxAttempts is how many short codes are generated for a link
+	// before giving up if all of them are taken.
+	CodeMaxAttempts int `env:"CODE_MAX_ATTEMPTS" envDefault:"5"`
+
+	// CodeSalt obfuscates counter-based short codes. Changing it changes all future codes.
+	CodeSalt string `env:"CODE_SALT" envDefault:"shortener"`
+
+	// LinkSweepInterval is how often links that have passed their expiration time
+	// are marked as expired in the storage, e.g. "1m". Zero disables the sweeper,
+	// expired links are still rejected on redirect.
+	LinkSweepInterval time.Duration `env:"LINK_SWEEP_INTERVAL" envDefault:"1m"`
+
+	// LinkPasswordMaxAttempts is how many wrong passwords a protected link accepts
+	// within LinkPasswordAttemptWindow before further attempts are rejected.
+	LinkPasswordMaxAttempts int `env:"LINK_PASSWORD_MAX_ATTEMPTS" envDefault:"5"`
+
+	// LinkPasswordAttemptWindow is the period wrong passwords are counted over, e.g. "1m".
+	LinkPasswordAttemptWindow time.Duration `env:"LINK_PASSWORD_ATTEMPT_WINDOW" envDefault:"1m"`
+
+	// InactiveLinkFallbackURL is where links requested before their active window
+	// redirect to, e.g. "https://example.com/soon". If empty, such links get 404 Not Found.
+	InactiveLinkFallbackURL string `env:"INACTIVE_LINK_FALLBACK_URL"`
+
+	// ClickBufferSize is the number of click events waiting to be written,
+	// events over it are dropped. Zero disables click tracking.
+	ClickBufferSize int `env:"CLICK_BUFFER_SIZE" envDefault:"10000"`
+
+	// ClickWorkers is the number of goroutines writing click events.
+	ClickWorkers int `env:"CLICK_WORKERS" envDefault:"2"`
+
+	// ClickBatchSize is the number of click events written at once.
+	ClickBatchSize int `env:"CLICK_BATCH_SIZE" envDefault:"500"`
+
+	// ClickFlushInterval is how long click events are collected before
+	// an incomplete batch is written, e.g. "1s".
+	ClickFlushInterval time.Duration `env:"CLICK_FLUSH_INTERVAL" envDefault:"1s"`
+
+	// UserAgentRules is the path to a JSON file with rules classifying
+	// user agents, in the format of internal/useragent/rules.json.
+	// The bundled rules are used if empty.
+	UserAgentRules string `env:"USER_AGENT_RULES"`
+
+	// ClickRollupInterval is how often click events are rolled up into hourly
+	// and daily summaries, e.g. "5m". Zero disables rollups and retention.
+	ClickRollupInterval time.Duration `env:"CLICK_ROLLUP_INTERVAL" envDefault:"5m"`
+
+	// ClickRollupLag is how long after the end of an hour its click events
+	// are rolled up. Events written later are kept raw only.
+	ClickRollupLag time.Duration `env:"CLICK_ROLLUP_LAG" envDefault:"5m"`
+
+	// ClickRetention is how long raw click events are kept, e.g. "720h".
+	// Older events are deleted once rolled up. Zero keeps them forever.
+	ClickRetention time.Duration `env:"CLICK_RETENTION" envDefault:"720h"`
+
 	// PostgresQL is the PostgreSQL DSN (data source name).
 	// If set, the service will use PostgreSQL as the database backend.
 	PostgresQL string `env:"DATABASE_DSN"`
@@ -59,6 +199,8 @@ func (c *Config) parseFlags() {
 	dbFile := flag.String("f", c.DBFileName, "Путь к файлу БД (например, ./db.json)")
 	postgres := flag.String("d", c.PostgresQL, "PostgreSQL DSN")
 	enablePprof := flag.Bool("pprof", false, "Включить pprof (профайлер)")
+	snapshotPath := flag.String("snapshot", c.MemorySnapshotPath, "Путь к снапшоту in-memory БД (например, ./memory.snapshot)")
+	snapshotInterval := flag.Duration("snapshot-interval", c.MemorySnapshotInterval, "Интервал снапшотов in-memory БД (например, 5m)")
 
 	flag.Parse()
 
@@ -67,4 +209,6 @@ func (c *Config) parseFlags() {
 	c.DBFileName = *dbFile
 	c.PostgresQL = *postgres
 	c.EnableProfiler = *enablePprof
+	c.MemorySnapshotPath = *snapshotPath
+	c.MemorySnapshotInterval = *snapshotInterval
 }
@@ -1,26 +1,116 @@
 package database
 
 import (
+	"errors"
+	"expvar"
 	"github.com/thxhix/shortener/internal/config"
 	drivers2 "github.com/thxhix/shortener/internal/database/drivers"
 	"github.com/thxhix/shortener/internal/database/interfaces"
+	"os"
+	"strings"
 )
 
 // NewDatabase creates a new Database implementation based on the provided configuration.
 //
 // Priority of selection:
 //  1. If PostgresQL DSN is provided, returns a PostgreSQL-backed database.
-//  2. If a file path (DBFileName) is provided, returns a file-based database.
-//  3. Otherwise, returns an in-memory database (not persistent).
+//  2. If a file path (DBFileName) is provided, returns a file-based database,
+//     or a segmented one if the path is a directory.
+//  3. Otherwise, returns an in-memory database: a sharded bounded one
+//     if MemoryShards is set, or a single map persisted with snapshots
+//     if MemorySnapshotPath is set.
+//
+// If BloomFilterSize is set, the backend is wrapped with a filter of known hashes,
+// its counters are published as the "bloom_filter" expvar. With PostgreSQL, the filter
+// catches up with the links created by other replicas before rejecting a hash.
+// If RedirectCacheSize is set, the result is wrapped with a cache of hash lookups,
+// whose rows always expire with PostgreSQL.
 //
 // The returned value implements the Database interface. An error is returned
 // if the chosen backend cannot be initialized (e.g., failed to connect to PostgreSQL).
 func NewDatabase(config *config.Config) (interfaces.Database, error) {
+	db, err := newBackend(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.BloomFilterSize > 0 {
+		filtered, err := drivers2.NewFilteredDatabase(db, drivers2.FilterOptions{
+			ExpectedLinks:     config.BloomFilterSize,
+			FalsePositiveRate: config.BloomFilterRate,
+			CatchUpInterval:   config.BloomFilterCatchUpInterval,
+		})
+		if err != nil {
+			return nil, errors.Join(err, db.Close())
+		}
+		publish("bloom_filter", func() any { return filtered.Stats() })
+		db = filtered
+	}
+	if config.RedirectCacheSize > 0 {
+		return drivers2.NewCachedDatabase(db, drivers2.CacheOptions{
+			Size:        config.RedirectCacheSize,
+			TTL:         config.RedirectCacheTTL,
+			NegativeTTL: config.RedirectCacheNegativeTTL,
+			Shared:      config.PostgresQL != "",
+		}), nil
+	}
+	return db, nil
+}
+
+// newBackend creates the storage backend selected by the configuration.
+func newBackend(config *config.Config) (interfaces.Database, error) {
 	if config.PostgresQL != "" {
 		return drivers2.NewPQLDatabase(config.PostgresQL)
 	}
 	if config.DBFileName != "" {
-		return drivers2.NewFileDatabase(config.DBFileName)
+		syncMode, err := drivers2.ParseSyncMode(config.FileSyncMode)
+		if err != nil {
+			return nil, err
+		}
+		opts := drivers2.FileOptions{
+			CompactInterval: config.FileCompactInterval,
+			SyncMode:        syncMode,
+			FlushInterval:   config.FileFlushInterval,
+			FlushSize:       config.FileFlushSize,
+			SegmentSize:     config.FileSegmentSize,
+			MergeInterval:   config.FileMergeInterval,
+		}
+		if isDirectory(config.DBFileName) {
+			return drivers2.NewSegmentedFileDatabase(config.DBFileName, opts)
+		}
+		return drivers2.NewFileDatabase(config.DBFileName, opts)
+	}
+	if config.MemoryShards > 0 {
+		policy, err := drivers2.ParseEvictionPolicy(config.MemoryEvictionPolicy)
+		if err != nil {
+			return nil, err
+		}
+		return drivers2.NewShardedMemoryDatabase(drivers2.ShardedMemoryOptions{
+			Shards:     config.MemoryShards,
+			MaxEntries: config.MemoryMaxEntries,
+			Policy:     policy,
+			TTL:        config.MemoryTTL,
+		})
+	}
+	return drivers2.NewMemoryDatabase(drivers2.MemoryOptions{
+		SnapshotPath:     config.MemorySnapshotPath,
+		SnapshotInterval: config.MemorySnapshotInterval,
+	})
+}
+
+// publish exposes the metric at /debug/vars of the profiler server.
+// A metric published earlier under the same name is kept.
+func publish(name string, value func() any) {
+	if expvar.Get(name) == nil {
+		expvar.Publish(name, expvar.Func(value))
+	}
+}
+
+// isDirectory reports whether the storage path points to an existing
+// directory or ends with a path separator.
+func isDirectory(path string) bool {
+	if strings.HasSuffix(path, string(os.PathSeparator)) || strings.HasSuffix(path, "/") {
+		return true
 	}
-	return drivers2.NewMemoryDatabase()
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
 }
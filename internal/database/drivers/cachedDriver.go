@@ -0,0 +1,291 @@
+package drivers
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"github.com/thxhix/shortener/internal/database/interfaces"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+	"golang.org/x/sync/singleflight"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultNegativeTTL is how long a missing hash is cached
+// when CacheOptions.NegativeTTL is not set.
+const DefaultNegativeTTL = 5 * time.Second
+
+// DefaultSharedTTL is the lifetime of a cached row in front of a shared
+// storage when CacheOptions.TTL is not set.
+const DefaultSharedTTL = time.Minute
+
+// generationShards is the number of shards of the key space
+// with their own invalidation generation.
+const generationShards = 256
+
+// CacheOptions holds settings of CachedDatabase.
+type CacheOptions struct {
+	// Size is the maximum number of cached hashes.
+	Size int
+
+	// TTL is the lifetime of a cached row, zero means rows stay cached
+	// until they are evicted or invalidated. With a shared storage, zero
+	// means DefaultSharedTTL.
+	TTL time.Duration
+
+	// Shared is set if other processes write to the storage as well.
+	// Their writes do not invalidate the cache, so rows must expire.
+	Shared bool
+
+	// NegativeTTL is the lifetime of a cached miss, DefaultNegativeTTL if not set.
+	NegativeTTL time.Duration
+}
+
+// CacheStats holds counters of CachedDatabase.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// cacheEntry is a cached lookup result. A zero row with found unset
+// is a cached miss.
+type cacheEntry struct {
+	hash    string
+	row     models.DBShortenRow
+	found   bool
+	expires time.Time
+}
+
+// CachedDatabase wraps any Database with a bounded LRU cache of hash lookups.
+// Concurrent misses of the same hash are collapsed into a single query
+// to the wrapped database, and misses are cached briefly as well.
+//
+// Every write made through the decorator invalidates the affected hashes,
+// other methods are passed to the wrapped database as is.
+type CachedDatabase struct {
+	interfaces.Database
+
+	opts  CacheOptions
+	group singleflight.Group
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// generations are bumped on invalidation of a hash in their shard,
+	// so that a lookup started before it does not put a stale row into
+	// the cache, while lookups of unrelated hashes are not affected.
+	generations [generationShards]uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewCachedDatabase wraps db with a cache of the given options.
+func NewCachedDatabase(db interfaces.Database, opts CacheOptions) *CachedDatabase {
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = DefaultNegativeTTL
+	}
+	if opts.Shared && opts.TTL <= 0 {
+		opts.TTL = DefaultSharedTTL
+	}
+	if opts.Size <= 0 {
+		opts.Size = 1
+	}
+
+	return &CachedDatabase{
+		Database: db,
+		opts:     opts,
+		entries:  make(map[string]*list.Element, opts.Size),
+		lru:      list.New(),
+	}
+}
+
+// GetFullLink returns the row from the cache, or loads it from the wrapped
+// database on a miss. Returns ErrNotFound if the hash does not exist.
+func (db *CachedDatabase) GetFullLink(ctx context.Context, hash string) (models.DBShortenRow, error) {
+	if entry, ok := db.lookup(hash); ok {
+		db.hits.Add(1)
+		if !entry.found {
+			return models.DBShortenRow{}, customErrors.ErrNotFound
+		}
+		return entry.row, nil
+	}
+	db.misses.Add(1)
+
+	result, err, _ := db.group.Do(hash, func() (interface{}, error) {
+		shard := generationShard(hash)
+		db.mutex.Lock()
+		generation := db.generations[shard]
+		db.mutex.Unlock()
+
+		// Запрос разделяют все ожидающие, поэтому отмена первого из них не должна его прерывать
+		row, err := db.Database.GetFullLink(context.WithoutCancel(ctx), hash)
+		switch {
+		case err == nil:
+			db.store(generation, cacheEntry{hash: hash, row: row, found: true})
+		case errors.Is(err, customErrors.ErrNotFound):
+			db.store(generation, cacheEntry{hash: hash})
+		}
+		return row, err
+	})
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+	return result.(models.DBShortenRow), nil
+}
+
+// AddLink stores the link in the wrapped database and drops
+// a cached miss of its hash.
+func (db *CachedDatabase) AddLink(ctx context.Context, original string, shorten string, userID string, opts models.LinkOptions) (string, error) {
+	defer db.invalidate([]string{shorten})
+	return db.Database.AddLink(ctx, original, shorten, userID, opts)
+}
+
+// AddLinks stores the links in the wrapped database and drops
+// cached misses of their hashes.
+func (db *CachedDatabase) AddLinks(ctx context.Context, list models.DBShortenRowList, userID string) error {
+	hashes := make([]string, 0, len(list))
+	for _, link := range list {
+		hashes = append(hashes, link.Hash)
+	}
+	defer db.invalidate(hashes)
+	return db.Database.AddLinks(ctx, list, userID)
+}
+
+// RemoveUserLinks marks the links as deleted in the wrapped database
+// and drops them from the cache.
+func (db *CachedDatabase) RemoveUserLinks(ctx context.Context, userID string, ids []string) error {
+	defer db.invalidate(ids)
+	return db.Database.RemoveUserLinks(ctx, userID, ids)
+}
+
+// UpdateLink changes the link in the wrapped database and drops
+// the cached row, which redirects to the previous destination.
+func (db *CachedDatabase) UpdateLink(ctx context.Context, userID string, hash string, original string) (models.DBShortenRow, error) {
+	defer db.invalidate([]string{hash})
+	return db.Database.UpdateLink(ctx, userID, hash, original)
+}
+
+// UpdateLinkTargeting changes the rules in the wrapped database and drops
+// the cached row, which still carries the previous rules.
+func (db *CachedDatabase) UpdateLinkTargeting(ctx context.Context, userID string, hash string, rules models.TargetingRuleList) (models.DBShortenRow, error) {
+	defer db.invalidate([]string{hash})
+	return db.Database.UpdateLinkTargeting(ctx, userID, hash, rules)
+}
+
+// AddClick counts the redirect in the wrapped database and drops
+// the cached row, whose click counter is now stale.
+func (db *CachedDatabase) AddClick(ctx context.Context, hash string) error {
+	defer db.invalidate([]string{hash})
+	return db.Database.AddClick(ctx, hash)
+}
+
+// ExpireLinks marks expired links in the wrapped database
+// and drops them from the cache.
+func (db *CachedDatabase) ExpireLinks(ctx context.Context, now time.Time) ([]string, error) {
+	hashes, err := db.Database.ExpireLinks(ctx, now)
+	if len(hashes) > 0 {
+		db.invalidate(hashes)
+	}
+	return hashes, err
+}
+
+// Unwrap returns the wrapped database.
+func (db *CachedDatabase) Unwrap() interfaces.Database {
+	return db.Database
+}
+
+// Stats returns the number of cache hits and misses.
+func (db *CachedDatabase) Stats() CacheStats {
+	return CacheStats{
+		Hits:   db.hits.Load(),
+		Misses: db.misses.Load(),
+	}
+}
+
+// Len returns the number of cached hashes.
+func (db *CachedDatabase) Len() int {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	return db.lru.Len()
+}
+
+// lookup returns the cached entry of the hash if it has not expired
+// and marks it as recently used.
+func (db *CachedDatabase) lookup(hash string) (cacheEntry, bool) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	element, ok := db.entries[hash]
+	if !ok {
+		return cacheEntry{}, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		db.lru.Remove(element)
+		delete(db.entries, hash)
+		return cacheEntry{}, false
+	}
+
+	db.lru.MoveToFront(element)
+	return *entry, true
+}
+
+// store caches the entry unless its hash was invalidated after
+// the given generation of its shard, evicting the least recently used entry if full.
+func (db *CachedDatabase) store(generation uint64, entry cacheEntry) {
+	switch {
+	case !entry.found:
+		entry.expires = time.Now().Add(db.opts.NegativeTTL)
+	case db.opts.TTL > 0:
+		entry.expires = time.Now().Add(db.opts.TTL)
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if generation != db.generations[generationShard(entry.hash)] {
+		return
+	}
+
+	if element, ok := db.entries[entry.hash]; ok {
+		element.Value = &entry
+		db.lru.MoveToFront(element)
+		return
+	}
+
+	if db.lru.Len() >= db.opts.Size {
+		oldest := db.lru.Back()
+		db.lru.Remove(oldest)
+		delete(db.entries, oldest.Value.(*cacheEntry).hash)
+	}
+	db.entries[entry.hash] = db.lru.PushFront(&entry)
+}
+
+// invalidate drops the hashes from the cache and makes lookups
+// of them that are already running skip caching their results.
+func (db *CachedDatabase) invalidate(hashes []string) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, hash := range hashes {
+		db.generations[generationShard(hash)]++
+		if element, ok := db.entries[hash]; ok {
+			db.lru.Remove(element)
+			delete(db.entries, hash)
+		}
+		db.group.Forget(hash)
+	}
+}
+
+// generationShard returns the shard of the key space the hash belongs to.
+func generationShard(hash string) int {
+	h := fnv.New32a()
+	h.Write([]byte(hash))
+	return int(h.Sum32() % generationShards)
+}
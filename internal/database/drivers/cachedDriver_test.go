@@ -0,0 +1,245 @@
+package drivers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+// countingDatabase counts lookups that reach the wrapped database
+// and can hold them until release is closed.
+type countingDatabase struct {
+	*MemoryDatabase
+	lookups atomic.Int32
+	release chan struct{}
+}
+
+func (db *countingDatabase) GetFullLink(ctx context.Context, hash string) (models.DBShortenRow, error) {
+	db.lookups.Add(1)
+	if db.release != nil {
+		<-db.release
+	}
+	return db.MemoryDatabase.GetFullLink(ctx, hash)
+}
+
+func newCountingDatabase(t *testing.T) *countingDatabase {
+	memory, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+	return &countingDatabase{MemoryDatabase: memory}
+}
+
+func TestCachedDatabase_GetFullLink(t *testing.T) {
+	backend := newCountingDatabase(t)
+	db := NewCachedDatabase(backend, CacheOptions{Size: 2})
+	ctx := context.Background()
+
+	_, err := db.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = db.AddLink(ctx, "https://google.com", "bbb", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = db.AddLink(ctx, "https://test.ru", "ccc", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		row, err := db.GetFullLink(ctx, "aaa")
+		require.NoError(t, err)
+		require.Equal(t, "https://ya.ru", row.URL)
+	}
+	require.EqualValues(t, 1, backend.lookups.Load(), "повторные запросы должны обслуживаться кэшем")
+	require.Equal(t, CacheStats{Hits: 2, Misses: 1}, db.Stats())
+
+	_, err = db.GetFullLink(ctx, "bbb")
+	require.NoError(t, err)
+	_, err = db.GetFullLink(ctx, "ccc")
+	require.NoError(t, err)
+	require.Equal(t, 2, db.Len(), "размер кэша ограничен")
+
+	_, err = db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.EqualValues(t, 4, backend.lookups.Load(), "самая старая запись должна быть вытеснена")
+}
+
+func TestCachedDatabase_NegativeCache(t *testing.T) {
+	backend := newCountingDatabase(t)
+	db := NewCachedDatabase(backend, CacheOptions{Size: 10, NegativeTTL: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := db.GetFullLink(ctx, "aaa")
+		require.ErrorIs(t, err, customErrors.ErrNotFound)
+	}
+	require.EqualValues(t, 1, backend.lookups.Load(), "промах должен кэшироваться")
+
+	time.Sleep(60 * time.Millisecond)
+	_, err := db.GetFullLink(ctx, "aaa")
+	require.ErrorIs(t, err, customErrors.ErrNotFound)
+	require.EqualValues(t, 2, backend.lookups.Load(), "промах должен кэшироваться ненадолго")
+
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err, "добавление ссылки должно сбрасывать закэшированный промах")
+	require.Equal(t, "https://ya.ru", row.URL)
+}
+
+func TestCachedDatabase_RemoveUserLinks(t *testing.T) {
+	backend := newCountingDatabase(t)
+	db := NewCachedDatabase(backend, CacheOptions{Size: 10})
+	ctx := context.Background()
+
+	_, err := db.AddLink(ctx, "https://ya.ru", "aaa", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.False(t, row.IsDeleted)
+
+	require.NoError(t, db.RemoveUserLinks(ctx, "owner", []string{"aaa"}))
+	row, err = db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.True(t, row.IsDeleted, "удаление должно сбрасывать кэш")
+}
+
+func TestCachedDatabase_UpdateLink(t *testing.T) {
+	backend := newCountingDatabase(t)
+	db := NewCachedDatabase(backend, CacheOptions{Size: 10})
+	ctx := context.Background()
+
+	_, err := db.AddLink(ctx, "https://ya.ru/typo", "aaa", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+
+	_, err = db.UpdateLink(ctx, "owner", "aaa", "https://ya.ru/fixed")
+	require.NoError(t, err)
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru/fixed", row.URL, "изменение адреса должно сбрасывать кэш")
+}
+
+func TestCachedDatabase_UpdateLinkTargeting(t *testing.T) {
+	backend := newCountingDatabase(t)
+	db := NewCachedDatabase(backend, CacheOptions{Size: 10})
+	ctx := context.Background()
+
+	_, err := db.AddLink(ctx, "https://ya.ru/app", "aaa", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+
+	_, err = db.UpdateLinkTargeting(ctx, "owner", "aaa", appTargeting)
+	require.NoError(t, err)
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.Equal(t, appTargeting, row.Targeting, "изменение правил должно сбрасывать кэш")
+}
+
+func TestCachedDatabase_CollapseMisses(t *testing.T) {
+	backend := newCountingDatabase(t)
+	backend.release = make(chan struct{})
+	db := NewCachedDatabase(backend, CacheOptions{Size: 10})
+	ctx := context.Background()
+
+	_, err := backend.MemoryDatabase.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	const readers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			row, err := db.GetFullLink(ctx, "aaa")
+			require.NoError(t, err)
+			require.Equal(t, "https://ya.ru", row.URL)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return db.Stats().Misses == readers
+	}, time.Second, time.Millisecond)
+	// Даём читателям дойти от подсчёта промаха до ожидания общего запроса
+	time.Sleep(10 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	require.EqualValues(t, 1, backend.lookups.Load(), "одновременные промахи должны объединяться в один запрос")
+}
+
+func TestCachedDatabase_OneTimeLink(t *testing.T) {
+	memory, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+
+	requireSingleClick(t, NewCachedDatabase(memory, CacheOptions{Size: 10}))
+}
+
+func TestCachedDatabase_InvalidateOtherHash(t *testing.T) {
+	backend := newCountingDatabase(t)
+	backend.release = make(chan struct{})
+	db := NewCachedDatabase(backend, CacheOptions{Size: 10})
+	ctx := context.Background()
+	require.NotEqual(t, generationShard("aaa"), generationShard("bbb"))
+
+	_, err := backend.MemoryDatabase.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := db.GetFullLink(ctx, "aaa")
+		require.NoError(t, err)
+	}()
+	require.Eventually(t, func() bool { return backend.lookups.Load() == 1 }, time.Second, time.Millisecond)
+
+	// Запись другой ссылки во время запроса не должна мешать кэшировать его результат
+	_, err = db.AddLink(ctx, "https://google.com", "bbb", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	close(backend.release)
+	<-done
+
+	_, err = db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, backend.lookups.Load(), "результат запроса должен попасть в кэш")
+}
+
+func TestCachedDatabase_InvalidateDuringLookup(t *testing.T) {
+	backend := newCountingDatabase(t)
+	backend.release = make(chan struct{})
+	db := NewCachedDatabase(backend, CacheOptions{Size: 10})
+	ctx := context.Background()
+
+	_, err := backend.MemoryDatabase.AddLink(ctx, "https://ya.ru/typo", "aaa", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := db.GetFullLink(ctx, "aaa")
+		require.NoError(t, err)
+	}()
+	require.Eventually(t, func() bool { return backend.lookups.Load() == 1 }, time.Second, time.Millisecond)
+
+	db.invalidate([]string{"aaa"})
+	close(backend.release)
+	<-done
+
+	_, err = db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.EqualValues(t, 2, backend.lookups.Load(), "результат запроса, начатого до сброса, не должен кэшироваться")
+}
+
+func TestCachedDatabase_SharedTTL(t *testing.T) {
+	db := NewCachedDatabase(newCountingDatabase(t), CacheOptions{Size: 2})
+	require.Zero(t, db.opts.TTL, "без общего хранилища строки живут до вытеснения")
+
+	db = NewCachedDatabase(newCountingDatabase(t), CacheOptions{Size: 2, Shared: true})
+	require.Equal(t, DefaultSharedTTL, db.opts.TTL, "изменения других реплик не сбрасывают кэш, строки должны устаревать")
+
+	db = NewCachedDatabase(newCountingDatabase(t), CacheOptions{Size: 2, TTL: time.Second, Shared: true})
+	require.Equal(t, time.Second, db.opts.TTL)
+}
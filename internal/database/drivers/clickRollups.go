@@ -0,0 +1,286 @@
+package drivers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/thxhix/shortener/internal/models"
+)
+
+// clickRollups holds the number of clicks of every hash per hour and per day,
+// keyed by the Unix time of the bucket start in UTC, and the watermark
+// the events are rolled up to.
+type clickRollups struct {
+	Watermark time.Time                `json:"watermark"`
+	Hourly    map[string]map[int64]int `json:"hourly"`
+	Daily     map[string]map[int64]int `json:"daily"`
+}
+
+// newClickRollups creates empty rollups.
+func newClickRollups() clickRollups {
+	return clickRollups{
+		Hourly: make(map[string]map[int64]int),
+		Daily:  make(map[string]map[int64]int),
+	}
+}
+
+// clone returns a deep copy of the rollups.
+func (r clickRollups) clone() clickRollups {
+	result := newClickRollups()
+	result.Watermark = r.Watermark
+	for hash, buckets := range r.Hourly {
+		result.Hourly[hash] = cloneBuckets(buckets)
+	}
+	for hash, buckets := range r.Daily {
+		result.Daily[hash] = cloneBuckets(buckets)
+	}
+	return result
+}
+
+func cloneBuckets(buckets map[int64]int) map[int64]int {
+	result := make(map[int64]int, len(buckets))
+	for start, clicks := range buckets {
+		result[start] = clicks
+	}
+	return result
+}
+
+// add counts a click of the hash that happened at t.
+func (r clickRollups) add(hash string, t time.Time) {
+	t = t.UTC()
+	hour := t.Truncate(time.Hour).Unix()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix()
+
+	if r.Hourly[hash] == nil {
+		r.Hourly[hash] = make(map[int64]int)
+	}
+	if r.Daily[hash] == nil {
+		r.Daily[hash] = make(map[int64]int)
+	}
+	r.Hourly[hash][hour]++
+	r.Daily[hash][day]++
+}
+
+// rolledUp returns the rollups with the events in [watermark, until) added
+// and the number of these events. The log itself is not changed,
+// the caller holds rollupMutex.
+func (l *clickLog) rolledUp(until time.Time) (clickRollups, int) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	next := l.rollups.clone()
+	if !until.After(next.Watermark) {
+		return next, 0
+	}
+
+	count := 0
+	for hash, events := range l.byHash {
+		for _, event := range events {
+			if event.Time.Before(next.Watermark) || !event.Time.Before(until) {
+				continue
+			}
+			next.add(hash, event.Time)
+			count++
+		}
+	}
+	next.Watermark = until
+	return next, count
+}
+
+// setRollups replaces the rollups of the log.
+func (l *clickLog) setRollups(rollups clickRollups) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rollups = rollups
+}
+
+// rollup rolls the events in [watermark, until) up in memory.
+func (l *clickLog) rollup(until time.Time) int {
+	l.rollupMutex.Lock()
+	defer l.rollupMutex.Unlock()
+
+	next, count := l.rolledUp(until)
+	l.setRollups(next)
+	return count
+}
+
+// pruneCutoff returns the moment events before which may be pruned:
+// the requested one, but never after the watermark.
+func (l *clickLog) pruneCutoff(before time.Time) time.Time {
+	if before.After(l.rollups.Watermark) {
+		return l.rollups.Watermark
+	}
+	return before
+}
+
+// retained returns the events that would be left after a prune
+// and the number of the pruned ones.
+func (l *clickLog) retained(before time.Time) (models.ClickEventList, int) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	cutoff := l.pruneCutoff(before)
+	var kept models.ClickEventList
+	pruned := 0
+	for _, events := range l.byHash {
+		for _, event := range events {
+			if event.Time.Before(cutoff) {
+				pruned++
+				continue
+			}
+			kept = append(kept, event)
+		}
+	}
+	return kept, pruned
+}
+
+// prune removes the rolled up events that happened before the given moment
+// and returns their number.
+func (l *clickLog) prune(before time.Time) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	cutoff := l.pruneCutoff(before)
+	pruned := 0
+	for hash, events := range l.byHash {
+		kept := events[:0]
+		for _, event := range events {
+			if event.Time.Before(cutoff) {
+				pruned++
+				continue
+			}
+			kept = append(kept, event)
+		}
+		if len(kept) == 0 {
+			delete(l.byHash, hash)
+			continue
+		}
+		l.byHash[hash] = kept
+	}
+	return pruned
+}
+
+// watermark returns the moment the events are rolled up to.
+func (l *clickLog) watermark() time.Time {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.rollups.Watermark
+}
+
+// rollupBuckets returns the rollups of the hash with starts in [from, to), oldest first.
+func (l *clickLog) rollupBuckets(hash string, granularity models.RollupGranularity, from time.Time, to time.Time) models.StatsBucketList {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	buckets := l.rollups.Hourly[hash]
+	if granularity == models.RollupDay {
+		buckets = l.rollups.Daily[hash]
+	}
+
+	var result models.StatsBucketList
+	for start, clicks := range buckets {
+		t := time.Unix(start, 0).UTC()
+		if !t.Before(from) && t.Before(to) {
+			result = append(result, models.StatsBucket{Start: t, Clicks: clicks})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result
+}
+
+// rollupsPath returns the path of the rollups file.
+func (f *clickFile) rollupsPath() string {
+	return f.path + ".rollups"
+}
+
+// RollupClicks rolls the events in [watermark, until) up. The new rollups are
+// written to a temporary file, fsynced and renamed over the old ones together
+// with the watermark, so a crash leaves either the old or the new state.
+func (f *clickFile) RollupClicks(ctx context.Context, until time.Time) (int, error) {
+	f.rollupMutex.Lock()
+	defer f.rollupMutex.Unlock()
+
+	next, count := f.rolledUp(until)
+	if count == 0 && next.Watermark.Equal(f.watermark()) {
+		return 0, nil
+	}
+	if err := writeClickRollups(f.rollupsPath(), next); err != nil {
+		return 0, err
+	}
+	f.setRollups(next)
+	return count, nil
+}
+
+// PruneClicks rewrites the click file without the rolled up events
+// that happened before the given moment and returns their number.
+// The file is replaced atomically via rename.
+func (f *clickFile) PruneClicks(ctx context.Context, before time.Time) (int, error) {
+	f.rollupMutex.Lock()
+	defer f.rollupMutex.Unlock()
+	f.writeMutex.Lock()
+	defer f.writeMutex.Unlock()
+
+	kept, pruned := f.retained(before)
+	if pruned == 0 {
+		return 0, nil
+	}
+
+	tmpPath := f.path + ".prune"
+	file, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeClickEvents(file, kept); err != nil {
+		return 0, errors.Join(err, file.Close(), os.Remove(tmpPath))
+	}
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		return 0, errors.Join(err, file.Close(), os.Remove(tmpPath))
+	}
+	if err := syncDir(filepath.Dir(f.path)); err != nil {
+		return 0, errors.Join(err, file.Close())
+	}
+
+	old := f.file
+	f.file = file
+	f.prune(before)
+	return pruned, old.Close()
+}
+
+// readClickRollups reads the rollups file, empty rollups if it does not exist.
+func readClickRollups(path string) (clickRollups, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return newClickRollups(), nil
+	}
+	if err != nil {
+		return clickRollups{}, err
+	}
+
+	rollups := newClickRollups()
+	if err := json.Unmarshal(data, &rollups); err != nil {
+		return clickRollups{}, err
+	}
+	if rollups.Hourly == nil {
+		rollups.Hourly = make(map[string]map[int64]int)
+	}
+	if rollups.Daily == nil {
+		rollups.Daily = make(map[string]map[int64]int)
+	}
+	return rollups, nil
+}
+
+// writeClickRollups replaces the rollups file atomically.
+func writeClickRollups(path string, rollups clickRollups) error {
+	data, err := json.Marshal(rollups)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
@@ -0,0 +1,180 @@
+package drivers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thxhix/shortener/internal/models"
+)
+
+// clickLog keeps click events of redirects in memory, grouped by hash,
+// together with their rollups.
+type clickLog struct {
+	mutex   sync.RWMutex
+	byHash  map[string]models.ClickEventList
+	rollups clickRollups
+
+	// rollupMutex serializes rollups and prunes, which read the log
+	// first and change it afterwards.
+	rollupMutex sync.Mutex
+}
+
+// newClickLog creates an empty clickLog.
+func newClickLog() *clickLog {
+	return &clickLog{
+		byHash:  make(map[string]models.ClickEventList),
+		rollups: newClickRollups(),
+	}
+}
+
+// add appends copies of the events to the log.
+func (l *clickLog) add(events models.ClickEventList) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, event := range events {
+		l.byHash[event.Hash] = append(l.byHash[event.Hash], event)
+	}
+}
+
+// events returns copies of the events of the hash that happened in [from, to),
+// oldest first. Several workers write events, so they are not stored in order.
+func (l *clickLog) events(hash string, from time.Time, to time.Time) models.ClickEventList {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return eventsBetween(l.byHash[hash], from, to)
+}
+
+// eventsBetween returns copies of the events that happened in [from, to),
+// oldest first.
+func eventsBetween(events models.ClickEventList, from time.Time, to time.Time) models.ClickEventList {
+	var result models.ClickEventList
+	for _, event := range events {
+		if !event.Time.Before(from) && event.Time.Before(to) {
+			result = append(result, event)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result
+}
+
+// clickFile is a clickLog persisted to an append-only JSON-lines file.
+// The file is replayed into the log on open. Rollups are kept in a separate
+// JSON file next to it, which is replaced as a whole on every rollup.
+type clickFile struct {
+	*clickLog
+
+	// writeMutex serializes appends to the file.
+	writeMutex sync.Mutex
+	file       *os.File
+	path       string
+}
+
+// openClickFile opens the click file at path, creating it if needed,
+// and replays it. A line torn by a crash at the end of the file is truncated,
+// corrupt lines in the middle are skipped.
+func openClickFile(path string) (*clickFile, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	clicks := &clickFile{clickLog: newClickLog(), file: file, path: path}
+	if err := clicks.replay(); err != nil {
+		return nil, errors.Join(err, file.Close())
+	}
+	if clicks.rollups, err = readClickRollups(clicks.rollupsPath()); err != nil {
+		return nil, errors.Join(err, file.Close())
+	}
+	return clicks, nil
+}
+
+// replay reads the events of the file into the log and positions
+// the file at the end of the last complete line.
+func (f *clickFile) replay() error {
+	reader := bufio.NewReader(f.file)
+	var offset int64
+	var events models.ClickEventList
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				log.Printf("обрезана неполная запись в конце файла переходов: %d байт", len(line))
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(line))
+
+		var event models.ClickEvent
+		if err := json.Unmarshal(bytes.TrimSpace(line), &event); err != nil {
+			log.Printf("пропущена повреждённая запись файла переходов: %v", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	f.add(events)
+
+	if err := f.file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := f.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// AddClickEvents appends the events to the file, fsyncs it
+// and then adds them to the in-memory log. On error, the file
+// is truncated back to its previous size.
+func (f *clickFile) AddClickEvents(ctx context.Context, events models.ClickEventList) error {
+	f.writeMutex.Lock()
+	defer f.writeMutex.Unlock()
+
+	offset, err := f.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if err := writeClickEvents(f.file, events); err != nil {
+		_, seekErr := f.file.Seek(offset, io.SeekStart)
+		return errors.Join(err, f.file.Truncate(offset), seekErr)
+	}
+
+	f.add(events)
+	return nil
+}
+
+// writeClickEvents encodes events as JSON lines into file through a buffer
+// and fsyncs the file.
+func writeClickEvents(file *os.File, events models.ClickEventList) error {
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for i := range events {
+		if err := encoder.Encode(&events[i]); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Close closes the file.
+func (f *clickFile) Close() error {
+	f.writeMutex.Lock()
+	defer f.writeMutex.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,167 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/thxhix/shortener/internal/models"
+	"time"
+)
+
+// SyncMode defines when the group committer flushes written rows to disk.
+type SyncMode string
+
+const (
+	// SyncAlways writes and fsyncs every group as soon as it is collected.
+	SyncAlways SyncMode = "always"
+
+	// SyncBatched waits up to the flush interval (or until the size threshold)
+	// to collect a bigger group, then writes and fsyncs it once.
+	SyncBatched SyncMode = "batched"
+
+	// SyncNone writes groups without fsync and leaves flushing to the OS.
+	SyncNone SyncMode = "none"
+)
+
+// ErrStorageClosed is returned when a write is requested after Close.
+var ErrStorageClosed = errors.New("хранилище закрыто")
+
+// ParseSyncMode converts a config value into SyncMode.
+// An empty value means SyncBatched.
+func ParseSyncMode(value string) (SyncMode, error) {
+	switch mode := SyncMode(value); mode {
+	case "":
+		return SyncBatched, nil
+	case SyncAlways, SyncBatched, SyncNone:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("неизвестный режим синхронизации: %q", value)
+	}
+}
+
+// logSink is the storage a groupCommitter writes into.
+type logSink interface {
+	// appendRows writes rows at the end of the log and fsyncs it if sync is set.
+	// On error, the log must be left as it was before the call.
+	appendRows(rows models.DBShortenRowList, sync bool) error
+}
+
+type commitRequest struct {
+	rows models.DBShortenRowList
+	done chan error
+}
+
+// groupCommitter queues rows of concurrent writers and writes them
+// to the sink in groups, so that a single fsync covers many callers.
+// Every caller is answered only after its group has been written.
+type groupCommitter struct {
+	sink     logSink
+	mode     SyncMode
+	interval time.Duration
+	maxRows  int
+	requests chan commitRequest
+	finished chan struct{}
+}
+
+// newGroupCommitter starts a committer goroutine writing into sink.
+func newGroupCommitter(sink logSink, mode SyncMode, interval time.Duration, maxRows int) *groupCommitter {
+	if maxRows <= 0 {
+		maxRows = 1
+	}
+
+	c := &groupCommitter{
+		sink:     sink,
+		mode:     mode,
+		interval: interval,
+		maxRows:  maxRows,
+		requests: make(chan commitRequest, maxRows),
+		finished: make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// enqueue schedules rows for writing and returns a channel
+// that receives the result once the rows are written.
+// An empty rows list works as a barrier: it is answered after
+// all previously enqueued rows are written.
+func (c *groupCommitter) enqueue(rows models.DBShortenRowList) <-chan error {
+	done := make(chan error, 1)
+	c.requests <- commitRequest{rows: rows, done: done}
+	return done
+}
+
+// close stops accepting requests, writes the queued ones and waits
+// for the committer goroutine to exit. The caller must guarantee
+// that enqueue is not called concurrently or afterwards.
+func (c *groupCommitter) close() {
+	close(c.requests)
+	<-c.finished
+}
+
+func (c *groupCommitter) run() {
+	defer close(c.finished)
+
+	for req := range c.requests {
+		group := []commitRequest{req}
+		size := len(req.rows)
+
+		switch c.mode {
+		case SyncBatched:
+			group, size = c.collectFor(group, size)
+		default:
+			group, size = c.collectQueued(group, size)
+		}
+
+		rows := make(models.DBShortenRowList, 0, size)
+		for _, r := range group {
+			rows = append(rows, r.rows...)
+		}
+
+		var err error
+		if len(rows) > 0 {
+			err = c.sink.appendRows(rows, c.mode != SyncNone)
+		}
+		for _, r := range group {
+			r.done <- err
+		}
+	}
+}
+
+// collectFor adds requests to the group until the flush interval passes
+// or the size threshold is reached.
+func (c *groupCommitter) collectFor(group []commitRequest, size int) ([]commitRequest, int) {
+	timer := time.NewTimer(c.interval)
+	defer timer.Stop()
+
+	for size < c.maxRows {
+		select {
+		case req, ok := <-c.requests:
+			if !ok {
+				return group, size
+			}
+			group = append(group, req)
+			size += len(req.rows)
+		case <-timer.C:
+			return group, size
+		}
+	}
+	return group, size
+}
+
+// collectQueued adds the requests that are already waiting in the queue
+// without blocking.
+func (c *groupCommitter) collectQueued(group []commitRequest, size int) ([]commitRequest, int) {
+	for size < c.maxRows {
+		select {
+		case req, ok := <-c.requests:
+			if !ok {
+				return group, size
+			}
+			group = append(group, req)
+			size += len(req.rows)
+		default:
+			return group, size
+		}
+	}
+	return group, size
+}
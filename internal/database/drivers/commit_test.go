@@ -0,0 +1,76 @@
+package drivers
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+type countingSink struct {
+	mutex sync.Mutex
+	calls int
+	syncs int
+	rows  int
+	err   error
+}
+
+func (s *countingSink) appendRows(rows models.DBShortenRowList, sync bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls++
+	s.rows += len(rows)
+	if sync {
+		s.syncs++
+	}
+	return s.err
+}
+
+func TestGroupCommitter_Batched(t *testing.T) {
+	sink := &countingSink{}
+	c := newGroupCommitter(sink, SyncBatched, 50*time.Millisecond, 1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, <-c.enqueue(models.DBShortenRowList{{Hash: "h"}}))
+		}()
+	}
+	wg.Wait()
+	c.close()
+
+	require.Equal(t, 100, sink.rows)
+	require.Less(t, sink.syncs, 100, "записи должны объединяться в группы")
+	require.Equal(t, sink.calls, sink.syncs)
+}
+
+func TestGroupCommitter_SizeThreshold(t *testing.T) {
+	sink := &countingSink{}
+	c := newGroupCommitter(sink, SyncBatched, time.Hour, 2)
+	defer c.close()
+
+	// Порог по размеру срабатывает раньше интервала
+	done := c.enqueue(models.DBShortenRowList{{Hash: "a"}, {Hash: "b"}})
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("группа не записана по достижении порога")
+	}
+}
+
+func TestGroupCommitter_NoneAndError(t *testing.T) {
+	sink := &countingSink{err: errors.New("disk full")}
+	c := newGroupCommitter(sink, SyncNone, 0, 10)
+
+	require.EqualError(t, <-c.enqueue(models.DBShortenRowList{{Hash: "a"}}), "disk full")
+	c.close()
+
+	require.Equal(t, 1, sink.calls)
+	require.Zero(t, sink.syncs)
+}
@@ -2,179 +2,254 @@ package drivers
 
 import (
 	"bufio"
-	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
-	"github.com/thxhix/shortener/internal/database/interfaces"
 	"github.com/thxhix/shortener/internal/models"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 )
 
 // ErrUserNotFound is returned when no records exist for the given user ID.
 var ErrUserNotFound = errors.New("user not found")
 
+// FileOptions holds optional settings of FileDatabase.
+type FileOptions struct {
+	// CompactInterval enables periodic background compaction if positive.
+	CompactInterval time.Duration
+
+	// SyncMode defines when written rows are fsynced, SyncBatched by default.
+	SyncMode SyncMode
+
+	// FlushInterval is how long SyncBatched waits to collect a group of writes.
+	FlushInterval time.Duration
+
+	// FlushSize is the number of rows that triggers a flush before FlushInterval passes.
+	FlushSize int
+
+	// SegmentSize is the size in bytes that triggers rotation of the active
+	// segment. Used by SegmentedFileDatabase only, DefaultSegmentSize if not set.
+	SegmentSize int64
+
+	// MergeInterval enables periodic background merges of sealed segments
+	// if positive. Used by SegmentedFileDatabase only.
+	MergeInterval time.Duration
+}
+
 // FileDatabase implements the Database interface, and using a JSON-lines file.
 // Each record is stored as a single JSON object per line.
+//
+// The file is an append-only log: changes of an existing link (e.g. deletion)
+// are appended as a new record with the same hash, and the latest record wins.
+// The log is replayed into an in-memory index on startup, and all lookups
+// are served from it. Compact rewrites the file without superseded records.
+//
+// Click events of redirects are kept in a separate JSON-lines file next to it
+// with the ".clicks" suffix, the high-water mark of allocated ID ranges
+// in a file with the ".ranges" suffix.
+//
+// The storage is guarded by an advisory lock, so only one process can use it.
+// On open, a recovery pass truncates a record torn by a crash and reports
+// corrupt lines, see Recovery.
+//
+// Writes go through a group committer: rows of concurrent callers are
+// written and fsynced together, and every caller returns only after its rows
+// are durable. Readers see rows only once they are durable as well.
 type FileDatabase struct {
-	file    *os.File
-	encoder *json.Encoder
+	logStore
+
+	path     string
+	file     *os.File
+	lock     *os.File
+	recovery RecoveryReport
+
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
 // NewFileDatabase creates a new FileDatabase instance for the given file path.
 // If the file does not exist, it will be created.
-// Returns an error if the file cannot be opened.g
-func NewFileDatabase(filePath string) (interfaces.Database, error) {
-	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0666)
+// Returns ErrStorageLocked if the file is used by another process,
+// or an error if the file cannot be opened or read.
+func NewFileDatabase(filePath string, opts FileOptions) (*FileDatabase, error) {
+	lock, err := acquireLock(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	return &FileDatabase{
-		file:    file,
-		encoder: json.NewEncoder(file),
-	}, nil
-}
-
-// RunMigrations is a no-op for FileDatabase.
-// Always returns nil.
-func (db *FileDatabase) RunMigrations() error {
-	return nil
-}
+	file, err := openLogFile(filePath)
+	if err != nil {
+		return nil, errors.Join(err, releaseLock(lock))
+	}
 
-// Close closes the underlying file used by FileDatabase.
-func (db *FileDatabase) Close() error {
-	return db.file.Close()
-}
+	db := &FileDatabase{
+		path: filePath,
+		file: file,
+		lock: lock,
+		stop: make(chan struct{}),
+	}
+	db.index = newLinkIndex()
 
-// WriteRow appends a new DBShortenRow to the file as a JSON object.
-// The file is synced after each write.
-func (db *FileDatabase) WriteRow(row *models.DBShortenRow) error {
-	err := db.encoder.Encode(row)
+	db.recovery, err = replayLog(file, func(row models.DBShortenRow) {
+		db.index.put(row)
+	})
 	if err != nil {
-		return err
+		return nil, errors.Join(err, file.Close(), releaseLock(lock))
 	}
-	return db.file.Sync()
-}
 
-// FindByHash scans the file and returns the first record matching the hash.
-// Returns an error if not found or if JSON decoding fails.
-func (db *FileDatabase) FindByHash(hash string) (*models.DBShortenRow, error) {
-	_, err := db.file.Seek(0, 0)
+	db.clicks, err = openClickFile(filePath + ".clicks")
 	if err != nil {
-		return nil, err
+		return nil, errors.Join(err, file.Close(), releaseLock(lock))
 	}
 
-	scanner := bufio.NewScanner(db.file)
-	for scanner.Scan() {
-		var row models.DBShortenRow
-		err := json.Unmarshal(scanner.Bytes(), &row)
-		if err != nil {
-			continue
-		}
-
-		if row.Hash == hash {
-			return &row, nil
-		}
+	if err := db.ranges.load(filePath + ".ranges"); err != nil {
+		return nil, errors.Join(err, file.Close(), db.clicks.Close(), releaseLock(lock))
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	db.start(db, opts)
+
+	if opts.CompactInterval > 0 {
+		db.wg.Add(1)
+		go db.compactLoop(opts.CompactInterval)
 	}
-	return nil, errors.New("запись не найдена")
+
+	return db, nil
 }
 
-// FindByUserID scans the file and returns all records for the given user ID.
-// Returns ErrUserNotFound if no records exist.
-func (db *FileDatabase) FindByUserID(userID string) (models.DBShortenRowList, error) {
-	result := models.DBShortenRowList{}
+func openLogFile(filePath string) (*os.File, error) {
+	return os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+}
 
-	_, err := db.file.Seek(0, 0)
-	if err != nil {
-		return nil, err
-	}
+// Close stops background compaction, writes the queued rows
+// and closes the underlying files.
+func (db *FileDatabase) Close() error {
+	close(db.stop)
+	db.wg.Wait()
 
-	scanner := bufio.NewScanner(db.file)
-	for scanner.Scan() {
-		var row models.DBShortenRow
-		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
-			log.Printf("ошибка чтения строки из файла: %v", err)
-			continue
-		}
+	db.shutdown()
+	return errors.Join(db.file.Close(), db.clicks.Close(), releaseLock(db.lock))
+}
 
-		if row.UserID == userID {
-			result = append(result, row)
-		}
+// Recovery returns the report of the recovery pass run on open.
+func (db *FileDatabase) Recovery() RecoveryReport {
+	return db.recovery
+}
+
+// appendRows writes rows at the end of the file and fsyncs it if sync is set.
+// On error, the file is truncated back to its previous size.
+// It is called by the group committer only.
+func (db *FileDatabase) appendRows(rows models.DBShortenRowList, sync bool) error {
+	offset, err := db.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	err = writeRows(db.file, rows, sync)
+	if err != nil {
+		return errors.Join(err, db.file.Truncate(offset))
 	}
+	return nil
+}
 
-	if len(result) == 0 {
-		return nil, ErrUserNotFound
+// Compact rewrites the file so that it only holds the latest record
+// of every link. Deleted links are kept as tombstones, so they still
+// answer as deleted and their hashes are not reused. The new file
+// is written next to the old one and swapped in atomically via rename,
+// readers are served from the index meanwhile.
+func (db *FileDatabase) Compact() error {
+	db.writeMutex.Lock()
+	defer db.writeMutex.Unlock()
+
+	// Дожидаемся записи всего, что уже стоит в очереди, в старый файл
+	if err := db.barrier(); err != nil {
+		return err
 	}
 
-	return result, nil
-}
+	rows := db.snapshot()
 
-// AddLink stores a single shortened link in the file.
-// Returns the hash of the link or an error if writing fails.
-func (db *FileDatabase) AddLink(ctx context.Context, original string, shorten string, userID string) (string, error) {
-	err := db.WriteRow(&models.DBShortenRow{
-		Hash:   shorten,
-		URL:    original,
-		UserID: userID,
-	})
+	tmpPath := db.path + ".compact"
+	if err := writeLogFile(tmpPath, rows); err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+	if err := os.Rename(tmpPath, db.path); err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+	if err := syncDir(filepath.Dir(db.path)); err != nil {
+		return err
+	}
+
+	file, err := openLogFile(db.path)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return shorten, nil
+
+	old := db.file
+	db.file = file
+
+	return old.Close()
 }
 
-// AddLinks stores multiple shortened links in the file.
-// Returns an error if any write fails.
-func (db *FileDatabase) AddLinks(ctx context.Context, list models.DBShortenRowList, userID string) error {
-	for _, link := range list {
+func (db *FileDatabase) compactLoop(interval time.Duration) {
+	defer db.wg.Done()
 
-		link.UserID = userID
-		err := db.WriteRow(&link)
-		if err != nil {
-			return err
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			if err := db.Compact(); err != nil {
+				log.Printf("ошибка компактификации файла БД: %v", err)
+			}
 		}
 	}
-
-	return nil
 }
 
-// GetFullLink retrieves the original URL by its short hash.
-// Returns an error if the hash does not exist.
-func (db *FileDatabase) GetFullLink(ctx context.Context, hash string) (models.DBShortenRow, error) {
-	byHash, err := db.FindByHash(hash)
+// writeLogFile writes rows into a new JSON-lines file and syncs it to disk.
+func writeLogFile(path string, rows models.DBShortenRowList) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
-		return models.DBShortenRow{}, err
+		return err
 	}
-	return models.DBShortenRow{URL: byHash.URL}, nil
-}
 
-// GetUserFullLinks retrieves all links belonging to the specified user ID.
-// Returns ErrUserNotFound if no records exist.
-func (db *FileDatabase) GetUserFullLinks(ctx context.Context, userID string) (models.DBShortenRowList, error) {
-	return db.FindByUserID(userID)
+	if err := writeRows(file, rows, true); err != nil {
+		return errors.Join(err, file.Close())
+	}
+	return file.Close()
 }
 
-// RemoveUserLinks is not implemented for FileDatabase.
-// Always returns nil.
-func (db *FileDatabase) RemoveUserLinks(ctx context.Context, userID string, ids []string) error {
+// writeRows encodes rows as JSON lines into file through a buffer
+// and fsyncs the file if sync is set.
+func writeRows(file *os.File, rows models.DBShortenRowList, sync bool) error {
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for i := range rows {
+		if err := encoder.Encode(&rows[i]); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if sync {
+		return file.Sync()
+	}
 	return nil
 }
 
-// PingConnection always succeeds for FileDatabase.
-// It returns nil to indicate the database is available.
-func (db *FileDatabase) PingConnection() error {
-	return nil
+// syncDir flushes directory metadata, so that a rename survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		return errors.Join(err, d.Close())
+	}
+	return d.Close()
 }
-
-// GetDriver always returns nil for FileDatabase since it has no SQL driver.
-func (db *FileDatabase) GetDriver() *sql.DB { return nil }
@@ -0,0 +1,424 @@
+package drivers
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+func TestFileDatabase_Duplicate(t *testing.T) {
+	db, err := NewFileDatabase(filepath.Join(t.TempDir(), "db.json"), FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	hash, err := db.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "aaa", hash)
+
+	hash, err = db.AddLink(ctx, "https://ya.ru", "bbb", "user", models.LinkOptions{})
+	require.ErrorIs(t, err, customErrors.ErrDuplicate)
+	require.Equal(t, "aaa", hash)
+
+	err = db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "ccc", URL: "https://google.com"},
+		{Hash: "ddd", URL: "https://ya.ru"},
+	}, "user")
+	require.ErrorIs(t, err, customErrors.ErrDuplicate)
+
+	_, err = db.GetFullLink(ctx, "ccc")
+	require.ErrorIs(t, err, customErrors.ErrNotFound)
+
+	_, err = db.AddLink(ctx, "https://google.com", "aaa", "user", models.LinkOptions{})
+	require.ErrorIs(t, err, customErrors.ErrHashCollision)
+
+	err = db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "ccc", URL: "https://google.com"},
+		{Hash: "aaa", URL: "https://test.ru"},
+	}, "user")
+	require.ErrorIs(t, err, customErrors.ErrHashCollision)
+
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru", row.URL, "занятый хэш не должен перезаписываться")
+}
+
+func TestFileDatabase_RemoveUserLinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = db.AddLink(ctx, "https://google.com", "bbb", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, db.RemoveUserLinks(ctx, "stranger", []string{"aaa"}))
+	require.NoError(t, db.RemoveUserLinks(ctx, "owner", []string{"bbb"}))
+	require.NoError(t, db.Close())
+
+	// Удаление должно пережить переоткрытие файла
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.False(t, row.IsDeleted)
+
+	row, err = db.GetFullLink(ctx, "bbb")
+	require.NoError(t, err)
+	require.True(t, row.IsDeleted)
+	require.Equal(t, "owner", row.UserID)
+
+	links, err := db.GetUserFullLinks(ctx, "owner")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+}
+
+func TestFileDatabase_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = db.AddLink(ctx, "https://google.com", "bbb", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, db.RemoveUserLinks(ctx, "owner", []string{"bbb"}))
+
+	require.NoError(t, db.Compact())
+
+	row, err := db.GetFullLink(ctx, "bbb")
+	require.NoError(t, err, "удалённая ссылка должна остаться надгробием")
+	require.True(t, row.IsDeleted)
+	_, err = db.AddLink(ctx, "https://test.ru/other", "bbb", "owner", models.LinkOptions{})
+	require.ErrorIs(t, err, customErrors.ErrHashCollision, "хэш удалённой ссылки не должен переиспользоваться")
+
+	// После компактификации запись продолжает работать в новый файл
+	_, err = db.AddLink(ctx, "https://test.ru", "ccc", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	lines, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 3, bytes.Count(lines, []byte("\n")), "в файле должна остаться одна запись на ссылку")
+
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	links, err := db.GetUserFullLinks(ctx, "owner")
+	require.NoError(t, err)
+	require.Len(t, links, 3)
+	require.Equal(t, "aaa", links[0].Hash)
+	require.Equal(t, "bbb", links[1].Hash)
+	require.True(t, links[1].IsDeleted, "удаление должно пережить компактификацию и переоткрытие")
+	require.Equal(t, "ccc", links[2].Hash)
+}
+
+func TestFileDatabase_CompactPendingWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{SyncMode: SyncAlways})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Запись уже на диске, но писатель ещё не получил ответ, когда начинается компактификация
+	db.writeMutex.Lock()
+	pending, err := db.stage(models.DBShortenRowList{{Hash: "aaa", URL: "https://ya.ru", UserID: "owner"}})
+	db.writeMutex.Unlock()
+	require.NoError(t, err)
+
+	require.NoError(t, db.Compact())
+	require.NoError(t, db.await(pending))
+	require.NoError(t, db.Close())
+
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err, "подтверждённая запись должна пережить компактификацию")
+	require.Equal(t, "https://ya.ru", row.URL)
+}
+
+func TestFileDatabase_ConcurrentAddLink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{SyncMode: SyncBatched, FlushInterval: 5 * time.Millisecond, FlushSize: 100})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := db.AddLink(ctx, "https://ya.ru/"+strconv.Itoa(i), "h"+strconv.Itoa(i), "user", models.LinkOptions{})
+			require.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	require.NoError(t, db.Close())
+
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	links, err := db.GetUserFullLinks(ctx, "user")
+	require.NoError(t, err)
+	require.Len(t, links, 50)
+}
+
+func TestFileDatabase_Lock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+
+	_, err = NewFileDatabase(path, FileOptions{})
+	require.ErrorIs(t, err, ErrStorageLocked)
+
+	require.NoError(t, db.Close())
+
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err, "после закрытия блокировка должна сниматься")
+	require.NoError(t, db.Close())
+}
+
+func TestFileDatabase_Recovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	content := `{"id":1,"hash":"aaa","url":"https://ya.ru","user_id":"user"}
+not a json
+{"id":2,"hash":"bbb","url":"https://google.com","user_id":"user"}
+{"id":3,"hash":"ccc","url":"https://te`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0666))
+
+	db, err := NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	report := db.Recovery()
+	require.Equal(t, 2, report.Rows)
+	require.Equal(t, 2, report.CorruptLines)
+	require.Equal(t, int64(len(`{"id":3,"hash":"ccc","url":"https://te`)), report.TruncatedBytes)
+
+	_, err = db.AddLink(ctx, "https://test.ru", "ddd", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.Equal(t, 1, db.Recovery().CorruptLines, "обрезанная запись не должна ломать следующую")
+	row, err := db.GetFullLink(ctx, "ddd")
+	require.NoError(t, err)
+	require.Equal(t, 3, row.ID)
+}
+
+func TestFileDatabase_LinkLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{MaxClicks: 1})
+	require.NoError(t, err)
+	_, err = db.AddLink(ctx, "https://google.com", "bbb", "user", models.LinkOptions{ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddClick(ctx, "aaa"))
+	require.ErrorIs(t, db.AddClick(ctx, "aaa"), customErrors.ErrLinkExpired)
+
+	hashes, err := db.ExpireLinks(ctx, expiresAt)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bbb"}, hashes)
+	require.NoError(t, db.Close())
+
+	// Лимиты и состояние должны пережить переоткрытие файла
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.Equal(t, 1, row.MaxClicks)
+	require.Equal(t, 1, row.Clicks)
+	require.True(t, row.IsExpired)
+
+	row, err = db.GetFullLink(ctx, "bbb")
+	require.NoError(t, err)
+	require.NotNil(t, row.ExpiresAt)
+	require.True(t, expiresAt.Equal(*row.ExpiresAt))
+	require.True(t, row.IsExpired)
+}
+
+func TestFileDatabase_OneTimeLink(t *testing.T) {
+	db, err := NewFileDatabase(filepath.Join(t.TempDir(), "db.json"), FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	requireSingleClick(t, db)
+}
+
+func TestFileDatabase_UpdateLink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+
+	requireEditableLink(t, db)
+	require.NoError(t, db.Close())
+
+	// История должна пережить переоткрытие файла
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	row, err := db.GetFullLink(ctx, "edit")
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru/fixed", row.URL)
+	require.Equal(t, 2, row.CurrentVersion())
+
+	history, err := db.GetLinkHistory(ctx, "edit")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, "https://ya.ru/typo", history[0].URL)
+
+	_, err = db.UpdateLink(ctx, "owner", "edit", "https://ya.ru/typo")
+	require.ErrorIs(t, err, customErrors.ErrDuplicate, "индекс адресов должен восстановиться после переоткрытия")
+}
+
+func TestFileDatabase_UpdateLinkTargeting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+
+	requireTargetableLink(t, db)
+	require.NoError(t, db.Close())
+
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	row, err := db.GetFullLink(context.Background(), "target")
+	require.NoError(t, err)
+	require.Equal(t, appTargeting, row.Targeting, "правила должны пережить переоткрытие файла")
+}
+
+func TestFileDatabase_ClickEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	err = db.AddClickEvents(ctx, models.ClickEventList{
+		{Hash: "aaa", Time: now, Referrer: "https://google.com", UserAgent: "curl/8.0", IP: "10.0.0.1"},
+		{Hash: "aaa", Time: now.Add(time.Second)},
+		{Hash: "bbb", Time: now},
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// Оборванная запись в конце файла переходов
+	clicks, err := os.OpenFile(path+".clicks", os.O_WRONLY|os.O_APPEND, 0666)
+	require.NoError(t, err)
+	_, err = clicks.WriteString(`{"hash":"ccc","ti`)
+	require.NoError(t, err)
+	require.NoError(t, clicks.Close())
+
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.Len(t, db.clicks.byHash["aaa"], 2, "события переходов должны пережить переоткрытие файла")
+	require.Equal(t, "https://google.com", db.clicks.byHash["aaa"][0].Referrer)
+	require.True(t, now.Equal(db.clicks.byHash["aaa"][0].Time))
+	require.Len(t, db.clicks.byHash["bbb"], 1)
+	require.Empty(t, db.clicks.byHash["ccc"], "оборванная запись должна отбрасываться")
+
+	require.NoError(t, db.AddClickEvents(ctx, models.ClickEventList{{Hash: "ccc", Time: now}}))
+	events, err := db.GetClickEvents(ctx, "ccc", now, now.Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	content, err := os.ReadFile(path + ".clicks")
+	require.NoError(t, err)
+	require.Equal(t, 4, bytes.Count(content, []byte("\n")), "новая запись не должна склеиваться с оборванной")
+}
+
+func TestFileDatabase_ClickRollups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	day := time.Date(2024, time.May, 15, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, db.AddClickEvents(ctx, models.ClickEventList{
+		{Hash: "aaa", Time: day.Add(time.Hour)},
+		{Hash: "aaa", Time: day.Add(2 * time.Hour)},
+		{Hash: "aaa", Time: day.Add(3 * time.Hour)},
+	}))
+	rolled, err := db.RollupClicks(ctx, day.Add(3*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, rolled)
+	pruned, err := db.PruneClicks(ctx, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, pruned, "удаляются только свёрнутые события")
+	require.NoError(t, db.AddClickEvents(ctx, models.ClickEventList{{Hash: "aaa", Time: day.Add(4 * time.Hour)}}))
+	require.NoError(t, db.Close())
+
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	watermark, err := db.ClickWatermark(ctx)
+	require.NoError(t, err)
+	require.True(t, watermark.Equal(day.Add(3*time.Hour)), "водяной знак должен пережить переоткрытие")
+	daily, err := db.GetClickRollups(ctx, "aaa", models.RollupDay, day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, models.StatsBucketList{{Start: day, Clicks: 2}}, daily)
+	events, err := db.GetClickEvents(ctx, "aaa", day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 2, "в файле переходов остаются несвёрнутые события")
+
+	rolled, err = db.RollupClicks(ctx, day.Add(5*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, rolled, "после переоткрытия свёртка продолжается с водяного знака")
+	daily, err = db.GetClickRollups(ctx, "aaa", models.RollupDay, day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, models.StatsBucketList{{Start: day, Clicks: 4}}, daily)
+}
+
+func TestFileDatabase_AllocateRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	db, err := NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	start, err := db.AllocateRange(ctx, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, start)
+	require.NoError(t, db.Close())
+
+	// ID диапазона не попали в ссылки, но после перезапуска выдаваться не должны
+	db, err = NewFileDatabase(path, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	start, err = db.AllocateRange(ctx, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 11, start, "диапазон должен начинаться после выданных до перезапуска")
+}
@@ -0,0 +1,365 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/thxhix/shortener/internal/bloom"
+	"github.com/thxhix/shortener/internal/database/interfaces"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultFilterRate is the target false-positive rate of the filter
+// when FilterOptions.FalsePositiveRate is not set.
+const DefaultFilterRate = 0.01
+
+// DefaultCatchUpInterval is the minimal interval between catch-ups with
+// a shared storage when FilterOptions.CatchUpInterval is not set.
+const DefaultCatchUpInterval = 50 * time.Millisecond
+
+// catchUpOverlap is how long hashes are listed again by the following
+// catch-ups, so that rows committed out of the order of their IDs
+// by other replicas are not skipped.
+const catchUpOverlap = 10 * time.Second
+
+// FilterOptions holds settings of FilteredDatabase.
+type FilterOptions struct {
+	// ExpectedLinks is the number of links the filter is sized for.
+	// Once exceeded, the false-positive rate grows.
+	ExpectedLinks int
+
+	// FalsePositiveRate is the target false-positive rate, DefaultFilterRate if not set.
+	FalsePositiveRate float64
+
+	// CatchUpInterval is the minimal interval between catch-ups with a shared
+	// storage, DefaultCatchUpInterval if not set. Longer intervals query
+	// the storage less often but delay the answers for unknown hashes.
+	CatchUpInterval time.Duration
+}
+
+// FilterStats holds counters of FilteredDatabase.
+type FilterStats struct {
+	// Lookups is the number of hash lookups.
+	Lookups uint64 `json:"lookups"`
+
+	// Rejected is the number of lookups answered by the filter alone.
+	Rejected uint64 `json:"rejected"`
+
+	// FalsePositives is the number of lookups passed by the filter
+	// that the storage did not find.
+	FalsePositives uint64 `json:"false_positives"`
+
+	// FalsePositiveRate is the observed share of unknown hashes passed
+	// by the filter: FalsePositives / (FalsePositives + Rejected).
+	FalsePositiveRate float64 `json:"false_positive_rate"`
+
+	// EstimatedFalsePositiveRate is the rate estimated from the filter fill.
+	EstimatedFalsePositiveRate float64 `json:"estimated_false_positive_rate"`
+
+	// Added is the number of hashes added to the filter.
+	Added uint64 `json:"added"`
+
+	// CatchUps is the number of catch-ups with a shared storage.
+	CatchUps uint64 `json:"catch_ups"`
+
+	// Ready reports whether the filter has been built from the storage.
+	Ready bool `json:"ready"`
+}
+
+// FilteredDatabase wraps a Database with a Bloom filter of known hashes,
+// so that lookups of unknown hashes are rejected without touching
+// the storage. The filter is filled on every AddLink and AddLinks
+// and is built from the storage by RunMigrations, which runs at startup
+// once the schema is ready. Until then all lookups go to the storage.
+//
+// A storage shared by several replicas, a HashTailer, also gets links
+// that were not added through the filter. A hash the filter has not seen
+// is then rejected only after a catch-up started after the lookup: the filter
+// gets the hashes added since the previous catch-up with one query. Lookups
+// waiting at the same time share a catch-up, and catch-ups run at most once
+// per CatchUpInterval, so unknown hashes cost the storage a bounded number
+// of cheap queries. Every catch-up lists the hashes of the previous
+// catchUpOverlap once more, which catches the rows that other replicas
+// committed out of the order of their IDs.
+//
+// Other methods are passed to the wrapped database as is.
+type FilteredDatabase struct {
+	interfaces.Database
+
+	lister interfaces.HashLister
+	tailer interfaces.HashTailer
+	filter *bloom.Filter
+	ready  atomic.Bool
+
+	// tailMutex guards the cursor of the catch-ups and the marks to move
+	// it to once they are older than catchUpOverlap.
+	tailMutex sync.Mutex
+	cursor    int
+	marks     []cursorMark
+
+	// roundMutex guards the catch-up the lookups wait for and whether
+	// the goroutine running catch-ups is active.
+	roundMutex      sync.Mutex
+	next            *catchUpRound
+	running         bool
+	lastCatchUp     time.Time
+	catchUpInterval time.Duration
+
+	lookups        atomic.Uint64
+	rejected       atomic.Uint64
+	falsePositives atomic.Uint64
+	catchUps       atomic.Uint64
+}
+
+// cursorMark is the greatest ID listed by a catch-up started at the moment.
+type cursorMark struct {
+	id int
+	at time.Time
+}
+
+// catchUpRound is a catch-up the lookups wait for, done is closed once it ends.
+type catchUpRound struct {
+	done chan struct{}
+	err  error
+}
+
+// NewFilteredDatabase wraps db with a filter of the given options.
+// The database must implement HashTailer or HashLister.
+func NewFilteredDatabase(db interfaces.Database, opts FilterOptions) (*FilteredDatabase, error) {
+	tailer, shared := db.(interfaces.HashTailer)
+	lister, ok := db.(interfaces.HashLister)
+	if !shared && !ok {
+		return nil, fmt.Errorf("хранилище %T не поддерживает фильтр хэшей", db)
+	}
+	if opts.FalsePositiveRate <= 0 {
+		opts.FalsePositiveRate = DefaultFilterRate
+	}
+	if opts.CatchUpInterval <= 0 {
+		opts.CatchUpInterval = DefaultCatchUpInterval
+	}
+
+	return &FilteredDatabase{
+		Database:        db,
+		lister:          lister,
+		tailer:          tailer,
+		filter:          bloom.New(opts.ExpectedLinks, opts.FalsePositiveRate),
+		catchUpInterval: opts.CatchUpInterval,
+	}, nil
+}
+
+// RunMigrations runs migrations of the wrapped database
+// and builds the filter from the stored hashes.
+func (db *FilteredDatabase) RunMigrations() error {
+	if err := db.Database.RunMigrations(); err != nil {
+		return err
+	}
+	return db.Build(context.Background())
+}
+
+// Build adds all stored hashes to the filter and enables rejections.
+// Links added concurrently are not lost, as they go to the filter directly.
+func (db *FilteredDatabase) Build(ctx context.Context) error {
+	var err error
+	if db.tailer != nil {
+		err = db.buildTail(ctx)
+	} else {
+		err = db.lister.ListHashes(ctx, func(hash string) error {
+			db.filter.Add(hash)
+			return nil
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось построить фильтр хэшей: %w", err)
+	}
+
+	db.ready.Store(true)
+	log.Printf("фильтр хэшей построен: %d хэшей", db.filter.Added())
+	return nil
+}
+
+// GetFullLink returns ErrNotFound without querying the wrapped database
+// if the filter has never seen the hash, even after a catch-up with
+// a shared storage, otherwise it queries the wrapped database.
+func (db *FilteredDatabase) GetFullLink(ctx context.Context, hash string) (models.DBShortenRow, error) {
+	db.lookups.Add(1)
+
+	if db.ready.Load() && !db.known(ctx, hash) {
+		db.rejected.Add(1)
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+
+	row, err := db.Database.GetFullLink(ctx, hash)
+	if errors.Is(err, customErrors.ErrNotFound) && db.ready.Load() {
+		db.falsePositives.Add(1)
+	}
+	return row, err
+}
+
+// known reports whether the filter has seen the hash. With a shared storage,
+// a hash it has not seen is looked for among the hashes added by other
+// replicas since the previous catch-up. If the catch-up fails, the hash
+// is reported as known, so that the storage answers for it.
+func (db *FilteredDatabase) known(ctx context.Context, hash string) bool {
+	if db.filter.Contains(hash) {
+		return true
+	}
+	if db.tailer == nil {
+		return false
+	}
+	if err := db.caughtUp(ctx); err != nil {
+		return true
+	}
+	return db.filter.Contains(hash)
+}
+
+// caughtUp waits for a catch-up that starts after the call.
+func (db *FilteredDatabase) caughtUp(ctx context.Context) error {
+	db.roundMutex.Lock()
+	round := db.next
+	if round == nil {
+		round = &catchUpRound{done: make(chan struct{})}
+		db.next = round
+		if !db.running {
+			db.running = true
+			go db.runCatchUps()
+		}
+	}
+	db.roundMutex.Unlock()
+
+	select {
+	case <-round.done:
+		return round.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runCatchUps runs the catch-ups the lookups wait for, at most one
+// per catchUpInterval, and exits once nobody waits.
+func (db *FilteredDatabase) runCatchUps() {
+	for {
+		// Пауза между догонами собирает ожидающих в один запрос
+		if wait := db.catchUpInterval - time.Since(db.lastCatchUp); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		db.roundMutex.Lock()
+		round := db.next
+		if round == nil {
+			db.running = false
+			db.roundMutex.Unlock()
+			return
+		}
+		db.next = nil
+		db.lastCatchUp = time.Now()
+		db.roundMutex.Unlock()
+
+		round.err = db.catchUp(context.Background())
+		if round.err != nil {
+			log.Printf("ошибка догона фильтра хэшей: %v", round.err)
+		}
+		close(round.done)
+	}
+}
+
+// buildTail adds all hashes of the shared storage to the filter and starts
+// the catch-ups after them. The hashes are listed once more catchUpOverlap
+// later, as rows committed out of the order of their IDs meanwhile may be
+// behind the cursor.
+func (db *FilteredDatabase) buildTail(ctx context.Context) error {
+	db.tailMutex.Lock()
+	defer db.tailMutex.Unlock()
+
+	last, err := db.tail(ctx, 0)
+	if err != nil {
+		return err
+	}
+	db.cursor = last
+
+	time.AfterFunc(catchUpOverlap, func() {
+		db.tailMutex.Lock()
+		defer db.tailMutex.Unlock()
+		if _, err := db.tail(context.Background(), 0); err != nil {
+			log.Printf("ошибка повторного построения фильтра хэшей: %v", err)
+		}
+	})
+	return nil
+}
+
+// catchUp adds the hashes added to the shared storage after the cursor
+// to the filter. The cursor follows the listed IDs with a delay
+// of catchUpOverlap.
+func (db *FilteredDatabase) catchUp(ctx context.Context) error {
+	db.tailMutex.Lock()
+	defer db.tailMutex.Unlock()
+
+	now := time.Now()
+	last, err := db.tail(ctx, db.cursor)
+	if err != nil {
+		return err
+	}
+	db.catchUps.Add(1)
+
+	db.marks = append(db.marks, cursorMark{id: last, at: now})
+	for len(db.marks) > 0 && now.Sub(db.marks[0].at) >= catchUpOverlap {
+		db.cursor = db.marks[0].id
+		db.marks = db.marks[1:]
+	}
+	return nil
+}
+
+// tail adds the hashes with IDs greater than after to the filter
+// and returns the greatest ID listed. The caller must hold tailMutex.
+func (db *FilteredDatabase) tail(ctx context.Context, after int) (int, error) {
+	return db.tailer.ListHashesAfter(ctx, after, func(hash string) error {
+		// Хэши из перекрытия уже в фильтре, не учитываем их повторно
+		if !db.filter.Contains(hash) {
+			db.filter.Add(hash)
+		}
+		return nil
+	})
+}
+
+// AddLink adds the hash to the filter and stores the link
+// in the wrapped database.
+func (db *FilteredDatabase) AddLink(ctx context.Context, original string, shorten string, userID string, opts models.LinkOptions) (string, error) {
+	// Хэш добавляем до записи, чтобы ссылка была доступна сразу после сохранения
+	db.filter.Add(shorten)
+	return db.Database.AddLink(ctx, original, shorten, userID, opts)
+}
+
+// AddLinks adds the hashes to the filter and stores the links
+// in the wrapped database.
+func (db *FilteredDatabase) AddLinks(ctx context.Context, list models.DBShortenRowList, userID string) error {
+	for _, link := range list {
+		db.filter.Add(link.Hash)
+	}
+	return db.Database.AddLinks(ctx, list, userID)
+}
+
+// Unwrap returns the wrapped database.
+func (db *FilteredDatabase) Unwrap() interfaces.Database {
+	return db.Database
+}
+
+// Stats returns the filter counters and false-positive rates.
+func (db *FilteredDatabase) Stats() FilterStats {
+	stats := FilterStats{
+		Lookups:                    db.lookups.Load(),
+		Rejected:                   db.rejected.Load(),
+		FalsePositives:             db.falsePositives.Load(),
+		EstimatedFalsePositiveRate: db.filter.EstimatedFalsePositiveRate(),
+		Added:                      db.filter.Added(),
+		CatchUps:                   db.catchUps.Load(),
+		Ready:                      db.ready.Load(),
+	}
+	if negatives := stats.Rejected + stats.FalsePositives; negatives > 0 {
+		stats.FalsePositiveRate = float64(stats.FalsePositives) / float64(negatives)
+	}
+	return stats
+}
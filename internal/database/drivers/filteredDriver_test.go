@@ -0,0 +1,141 @@
+package drivers
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+func TestFilteredDatabase_GetFullLink(t *testing.T) {
+	backend := newCountingDatabase(t)
+	ctx := context.Background()
+
+	_, err := backend.MemoryDatabase.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	db, err := NewFilteredDatabase(backend, FilterOptions{ExpectedLinks: 100})
+	require.NoError(t, err)
+
+	_, err = db.GetFullLink(ctx, "zzz")
+	require.ErrorIs(t, err, customErrors.ErrNotFound)
+	require.EqualValues(t, 1, backend.lookups.Load(), "до построения фильтра запросы идут в хранилище")
+
+	require.NoError(t, db.RunMigrations())
+
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err, "хэш из хранилища должен попасть в фильтр")
+	require.Equal(t, "https://ya.ru", row.URL)
+
+	backend.lookups.Store(0)
+	for _, hash := range []string{"bbb", "ccc", "ddd"} {
+		_, err = db.GetFullLink(ctx, hash)
+		require.ErrorIs(t, err, customErrors.ErrNotFound)
+	}
+	stats := db.Stats()
+	require.EqualValues(t, backend.lookups.Load(), stats.FalsePositives, "неизвестные хэши должны отсекаться фильтром")
+	require.EqualValues(t, 3, stats.Rejected+stats.FalsePositives)
+	require.True(t, stats.Ready)
+
+	_, err = db.AddLink(ctx, "https://google.com", "bbb", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "ccc", URL: "https://test.ru"},
+	}, "user"))
+
+	for _, hash := range []string{"bbb", "ccc"} {
+		_, err = db.GetFullLink(ctx, hash)
+		require.NoError(t, err, "новые хэши должны попадать в фильтр")
+	}
+}
+
+func TestFilteredDatabase_Unsupported(t *testing.T) {
+	_, err := NewFilteredDatabase(struct{ *CachedDatabase }{}, FilterOptions{})
+	require.Error(t, err)
+}
+
+// sharedDatabase is a storage shared with other replicas, which add links
+// with the given IDs behind the back of the filter.
+type sharedDatabase struct {
+	*countingDatabase
+
+	mutex  sync.Mutex
+	hashes map[int]string
+}
+
+func (db *sharedDatabase) addByReplica(t *testing.T, id int, original string, hash string) {
+	_, err := db.MemoryDatabase.AddLink(context.Background(), original, hash, "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.hashes[id] = hash
+}
+
+func (db *sharedDatabase) ListHashesAfter(ctx context.Context, after int, fn func(hash string) error) (int, error) {
+	db.mutex.Lock()
+	listed := make(map[int]string)
+	ids := make([]int, 0, len(db.hashes))
+	for id, hash := range db.hashes {
+		if id > after {
+			listed[id] = hash
+			ids = append(ids, id)
+		}
+	}
+	db.mutex.Unlock()
+	sort.Ints(ids)
+
+	last := after
+	for _, id := range ids {
+		if err := fn(listed[id]); err != nil {
+			return last, err
+		}
+		last = id
+	}
+	return last, nil
+}
+
+func TestFilteredDatabase_SharedStorage(t *testing.T) {
+	backend := &sharedDatabase{countingDatabase: newCountingDatabase(t), hashes: make(map[int]string)}
+	backend.addByReplica(t, 1, "https://ya.ru", "aaa")
+	ctx := context.Background()
+
+	db, err := NewFilteredDatabase(backend, FilterOptions{ExpectedLinks: 100, CatchUpInterval: time.Millisecond})
+	require.NoError(t, err)
+	require.NoError(t, db.RunMigrations())
+
+	_, err = db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+
+	backend.addByReplica(t, 3, "https://google.com", "ccc")
+	row, err := db.GetFullLink(ctx, "ccc")
+	require.NoError(t, err, "ссылка другой реплики должна находиться после догона")
+	require.Equal(t, "https://google.com", row.URL)
+
+	// Строка с меньшим ID зафиксирована другой репликой позже
+	backend.addByReplica(t, 2, "https://test.ru", "bbb")
+	_, err = db.GetFullLink(ctx, "bbb")
+	require.NoError(t, err, "догон не должен пропускать строки, зафиксированные не по порядку ID")
+
+	backend.lookups.Store(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.GetFullLink(ctx, "zzz")
+			require.ErrorIs(t, err, customErrors.ErrNotFound)
+		}()
+	}
+	wg.Wait()
+
+	stats := db.Stats()
+	require.EqualValues(t, backend.lookups.Load(), stats.FalsePositives, "неизвестные хэши должны отсекаться фильтром")
+	require.EqualValues(t, 20, stats.Rejected+stats.FalsePositives)
+	require.Less(t, stats.CatchUps, uint64(20), "одновременные промахи должны делить догон")
+}
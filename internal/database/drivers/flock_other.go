@@ -0,0 +1,15 @@
+//go:build !unix
+
+package drivers
+
+import "os"
+
+// lockFile is a no-op on platforms without flock(2).
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without flock(2).
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,231 @@
+package drivers
+
+import (
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+	"time"
+)
+
+// linkIndex keeps full DBShortenRow records by hash together with
+// secondary indexes by original URL and by user ID.
+//
+// linkIndex is not safe for concurrent use, the owning driver
+// is responsible for locking.
+type linkIndex struct {
+	rows       map[string]models.DBShortenRow
+	byOriginal map[string]string
+	byUser     map[string][]string
+	lastID     int
+}
+
+// validateBatch checks a batch of new links against the storage and itself.
+// Duplicate original URLs are reported first as ErrDuplicate, since they
+// cannot be resolved by the caller, then taken hashes as HashCollisionError.
+func validateBatch(list models.DBShortenRowList, originalExists func(string) bool, hashExists func(string) bool) error {
+	originals := make(map[string]struct{}, len(list))
+	for _, link := range list {
+		if _, exists := originals[link.URL]; exists || originalExists(link.URL) {
+			return customErrors.ErrDuplicate
+		}
+		originals[link.URL] = struct{}{}
+	}
+
+	hashes := make(map[string]struct{}, len(list))
+	for _, link := range list {
+		if _, exists := hashes[link.Hash]; exists || hashExists(link.Hash) {
+			return &customErrors.HashCollisionError{Hash: link.Hash}
+		}
+		hashes[link.Hash] = struct{}{}
+	}
+	return nil
+}
+
+// countClick counts a redirect of the row against its MaxClicks and marks
+// the row as expired once the limit is reached. Returns ErrLinkExpired
+// if the row has already expired or has no clicks left.
+func countClick(row *models.DBShortenRow) error {
+	if row.IsExpired || row.MaxClicks > 0 && row.Clicks >= row.MaxClicks {
+		return customErrors.ErrLinkExpired
+	}
+
+	row.Clicks++
+	if row.MaxClicks > 0 && row.Clicks >= row.MaxClicks {
+		row.IsExpired = true
+	}
+	return nil
+}
+
+// expiresBy reports whether a live row should be marked as expired at now.
+func expiresBy(row models.DBShortenRow, now time.Time) bool {
+	return !row.IsDeleted && !row.IsExpired && row.ExpiredAt(now)
+}
+
+// editRow returns the row redirecting to original, with the current destination
+// moved to the history. Returns ErrNotFound if the row is deleted and ErrNotOwner
+// if it belongs to another user. A row already redirecting to original is
+// returned as is.
+func editRow(row models.DBShortenRow, userID string, original string, now time.Time) (models.DBShortenRow, error) {
+	if row.IsDeleted {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	if row.UserID == "" || row.UserID != userID {
+		return models.DBShortenRow{}, customErrors.ErrNotOwner
+	}
+	if row.URL == original {
+		return row, nil
+	}
+
+	replacedAt := now
+	// Копируем историю, чтобы не изменить массив, общий с прежней версией строки
+	history := make(models.LinkVersionList, 0, len(row.History)+1)
+	history = append(history, row.History...)
+	row.History = append(history, models.LinkVersion{
+		Version:    row.CurrentVersion(),
+		URL:        row.URL,
+		ReplacedAt: &replacedAt,
+	})
+	row.Version = row.CurrentVersion() + 1
+	row.URL = original
+	return row, nil
+}
+
+// retargetRow returns the row with the targeting rules replaced. Returns
+// ErrNotFound if the row is deleted and ErrNotOwner if it belongs to another user.
+func retargetRow(row models.DBShortenRow, userID string, rules models.TargetingRuleList) (models.DBShortenRow, error) {
+	if row.IsDeleted {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	if row.UserID == "" || row.UserID != userID {
+		return models.DBShortenRow{}, customErrors.ErrNotOwner
+	}
+
+	// Копируем правила, чтобы строка не делила массив с вызывающим
+	row.Targeting = append(models.TargetingRuleList(nil), rules...)
+	return row, nil
+}
+
+// newLinkIndex creates an empty linkIndex.
+func newLinkIndex() *linkIndex {
+	return &linkIndex{
+		rows:       make(map[string]models.DBShortenRow),
+		byOriginal: make(map[string]string),
+		byUser:     make(map[string][]string),
+	}
+}
+
+// get returns the row stored under the given hash.
+func (idx *linkIndex) get(hash string) (models.DBShortenRow, bool) {
+	row, ok := idx.rows[hash]
+	return row, ok
+}
+
+// hashByOriginal returns the hash of the row that shortens the given original URL.
+func (idx *linkIndex) hashByOriginal(original string) (string, bool) {
+	hash, ok := idx.byOriginal[original]
+	return hash, ok
+}
+
+// put inserts the row or replaces the one stored under the same hash,
+// keeping the secondary indexes in sync. A zero ID is replaced with the next
+// sequential one. The stored row is returned.
+func (idx *linkIndex) put(row models.DBShortenRow) models.DBShortenRow {
+	if row.ID == 0 {
+		idx.lastID++
+		row.ID = idx.lastID
+	} else if row.ID > idx.lastID {
+		idx.lastID = row.ID
+	}
+
+	if old, ok := idx.rows[row.Hash]; ok {
+		if old.URL != row.URL && idx.byOriginal[old.URL] == old.Hash {
+			delete(idx.byOriginal, old.URL)
+		}
+		if old.UserID != row.UserID {
+			idx.unlinkUser(old.UserID, old.Hash)
+			idx.linkUser(row.UserID, row.Hash)
+		}
+	} else {
+		idx.linkUser(row.UserID, row.Hash)
+	}
+
+	idx.rows[row.Hash] = row
+	idx.byOriginal[row.URL] = row.Hash
+	return row
+}
+
+// hashes returns the hashes of all rows, including deleted ones.
+func (idx *linkIndex) hashes() []string {
+	hashes := make([]string, 0, len(idx.rows))
+	for hash := range idx.rows {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// expiring returns copies of the rows that expire by now, already marked
+// as expired. The index itself is not changed.
+func (idx *linkIndex) expiring(now time.Time) models.DBShortenRowList {
+	var rows models.DBShortenRowList
+	for _, row := range idx.rows {
+		if expiresBy(row, now) {
+			row.IsExpired = true
+			rows = append(rows, row)
+		}
+	}
+	sortByID(rows)
+	return rows
+}
+
+// remove drops the row stored under the given hash from all indexes.
+func (idx *linkIndex) remove(hash string) {
+	row, ok := idx.rows[hash]
+	if !ok {
+		return
+	}
+	delete(idx.rows, hash)
+	if idx.byOriginal[row.URL] == hash {
+		delete(idx.byOriginal, row.URL)
+	}
+	idx.unlinkUser(row.UserID, hash)
+}
+
+// userRows returns all rows of the given user in insertion order.
+func (idx *linkIndex) userRows(userID string) models.DBShortenRowList {
+	hashes := idx.byUser[userID]
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	result := make(models.DBShortenRowList, 0, len(hashes))
+	for _, hash := range hashes {
+		result = append(result, idx.rows[hash])
+	}
+	return result
+}
+
+// len returns the number of stored rows.
+func (idx *linkIndex) len() int {
+	return len(idx.rows)
+}
+
+func (idx *linkIndex) linkUser(userID string, hash string) {
+	if userID == "" {
+		return
+	}
+	idx.byUser[userID] = append(idx.byUser[userID], hash)
+}
+
+func (idx *linkIndex) unlinkUser(userID string, hash string) {
+	hashes := idx.byUser[userID]
+	for i, h := range hashes {
+		if h == hash {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			break
+		}
+	}
+	if len(hashes) == 0 {
+		delete(idx.byUser, userID)
+		return
+	}
+	idx.byUser[userID] = hashes
+}
@@ -0,0 +1,597 @@
+package drivers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// logStore implements the Database interface on top of an append-only log
+// of DBShortenRow records. The log is replayed into an in-memory index,
+// all lookups are served from it, and writes are staged in the index and
+// handed over to a group committer. The log itself is provided by the
+// embedding driver through the logSink interface.
+//
+// Writers check conflicts against the staged rows, while readers are served
+// the last committed version of every hash with writes in flight, so they
+// never see a row that may still be lost.
+type logStore struct {
+	index     *linkIndex
+	committer *groupCommitter
+	closed    bool
+	ranges    localRange
+	clicks    *clickFile
+
+	// pending holds the committed state of the hashes with staged writes,
+	// writes holds the staged writes not settled yet by their seq numbers.
+	pending map[string]*pendingHash
+	writes  map[uint64]*pendingWrite
+	seq     uint64
+
+	// writeMutex serializes staging of writes and maintenance,
+	// mutex guards the index for readers.
+	writeMutex sync.Mutex
+	mutex      sync.RWMutex
+}
+
+// pendingHash is the committed state of a hash with staged writes.
+type pendingHash struct {
+	// row is the last committed version, exists is unset if there is none.
+	row    models.DBShortenRow
+	exists bool
+
+	// seq is the sequence of the write row comes from,
+	// inflight is the number of staged rows not settled yet.
+	seq      uint64
+	inflight int
+}
+
+// start launches the group committer writing into sink.
+func (s *logStore) start(sink logSink, opts FileOptions) {
+	mode := opts.SyncMode
+	if mode == "" {
+		mode = SyncBatched
+	}
+	s.committer = newGroupCommitter(sink, mode, opts.FlushInterval, opts.FlushSize)
+	s.pending = make(map[string]*pendingHash)
+	s.writes = make(map[uint64]*pendingWrite)
+}
+
+// shutdown stops accepting writes and waits until the queued rows are written.
+func (s *logStore) shutdown() {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	s.closed = true
+	s.committer.close()
+}
+
+// barrier waits until every row enqueued so far is written and settles
+// the staged writes, so that the committed view holds every row that
+// is durable, even if its writer has not been answered yet.
+// The caller must hold writeMutex.
+func (s *logStore) barrier() error {
+	if s.closed {
+		return ErrStorageClosed
+	}
+	if err := <-s.committer.enqueue(nil); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, pending := range s.writes {
+		s.settle(pending)
+	}
+	return nil
+}
+
+// snapshot returns the latest committed version of every link ordered by ID.
+// Deleted links are kept as tombstones, so that they keep answering
+// as deleted and their hashes stay taken.
+func (s *logStore) snapshot() models.DBShortenRowList {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	rows := make(models.DBShortenRowList, 0, s.index.len())
+	for hash := range s.index.rows {
+		if row, ok := s.committed(hash); ok {
+			rows = append(rows, row)
+		}
+	}
+	sortByID(rows)
+	return rows
+}
+
+// committed returns the last committed version of the hash.
+// The caller must hold mutex.
+func (s *logStore) committed(hash string) (models.DBShortenRow, bool) {
+	if entry, ok := s.pending[hash]; ok {
+		return entry.row, entry.exists
+	}
+	return s.index.get(hash)
+}
+
+// RunMigrations is a no-op for log based storages.
+// Always returns nil.
+func (s *logStore) RunMigrations() error {
+	return nil
+}
+
+// PingConnection always succeeds for log based storages.
+// It returns nil to indicate the database is available.
+func (s *logStore) PingConnection() error {
+	return nil
+}
+
+// GetDriver always returns nil for log based storages since they have no SQL driver.
+func (s *logStore) GetDriver() *sql.DB { return nil }
+
+// FindByHash returns the latest record matching the hash.
+// Returns ErrNotFound if there is no such record.
+func (s *logStore) FindByHash(hash string) (*models.DBShortenRow, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	row, ok := s.committed(hash)
+	if !ok {
+		return nil, customErrors.ErrNotFound
+	}
+	return &row, nil
+}
+
+// FindByUserID returns all records for the given user ID.
+// Returns ErrUserNotFound if no records exist.
+func (s *logStore) FindByUserID(userID string) (models.DBShortenRowList, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var result models.DBShortenRowList
+	for _, staged := range s.index.userRows(userID) {
+		if row, ok := s.committed(staged.Hash); ok {
+			result = append(result, row)
+		}
+	}
+	if len(result) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return result, nil
+}
+
+// AddLink stores a single shortened link in the log.
+// If the original URL already exists, it returns the existing shorten hash
+// and ErrDuplicate. Returns ErrHashCollision if the hash already exists.
+func (s *logStore) AddLink(ctx context.Context, original string, shorten string, userID string, opts models.LinkOptions) (string, error) {
+	s.writeMutex.Lock()
+	if existing, ok := s.index.hashByOriginal(original); ok {
+		s.writeMutex.Unlock()
+		return existing, customErrors.ErrDuplicate
+	}
+	if _, exists := s.index.get(shorten); exists {
+		s.writeMutex.Unlock()
+		return "", &customErrors.HashCollisionError{Hash: shorten}
+	}
+
+	pending, err := s.stage(models.DBShortenRowList{{
+		Hash:        shorten,
+		URL:         original,
+		UserID:      userID,
+		Time:        time.Now(),
+		LinkOptions: opts,
+	}})
+	s.writeMutex.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.await(pending); err != nil {
+		return "", err
+	}
+	return shorten, nil
+}
+
+// AddLinks stores multiple shortened links in the log.
+// Returns ErrDuplicate without writing anything if any original URL
+// already exists, or ErrHashCollision if any hash is taken.
+// Returns an error if any write fails.
+func (s *logStore) AddLinks(ctx context.Context, list models.DBShortenRowList, userID string) error {
+	s.writeMutex.Lock()
+	pending, err := s.stageLinks(list, userID)
+	s.writeMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return s.await(pending)
+}
+
+// stageLinks validates a batch of new links and stages it.
+// The caller must hold writeMutex.
+func (s *logStore) stageLinks(list models.DBShortenRowList, userID string) (*pendingWrite, error) {
+	err := validateBatch(list,
+		func(original string) bool {
+			_, exists := s.index.hashByOriginal(original)
+			return exists
+		},
+		func(hash string) bool {
+			_, exists := s.index.get(hash)
+			return exists
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rows := make(models.DBShortenRowList, 0, len(list))
+	for _, link := range list {
+		rows = append(rows, models.DBShortenRow{
+			Hash:        link.Hash,
+			URL:         link.URL,
+			UserID:      userID,
+			Time:        now,
+			LinkOptions: link.LinkOptions,
+		})
+	}
+
+	return s.stage(rows)
+}
+
+// pendingWrite is a group of staged rows waiting for the committer.
+type pendingWrite struct {
+	done <-chan error
+	rows models.DBShortenRowList
+	seq  uint64
+
+	// once reads the result from done into err, so that both the writer
+	// and a barrier can get it. settled is guarded by writeMutex.
+	once    sync.Once
+	err     error
+	settled bool
+}
+
+// result waits until the rows are written and returns the error of the write.
+func (p *pendingWrite) result() error {
+	p.once.Do(func() { p.err = <-p.done })
+	return p.err
+}
+
+// stage publishes rows in the index for writers and enqueues them
+// to the committer. IDs are assigned to new rows. The caller must hold
+// writeMutex, and must pass the result to await after releasing it.
+func (s *logStore) stage(rows models.DBShortenRowList) (*pendingWrite, error) {
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	s.seq++
+	pending := &pendingWrite{
+		rows: make(models.DBShortenRowList, 0, len(rows)),
+		seq:  s.seq,
+	}
+
+	s.mutex.Lock()
+	for _, row := range rows {
+		entry, ok := s.pending[row.Hash]
+		if !ok {
+			entry = &pendingHash{}
+			entry.row, entry.exists = s.index.get(row.Hash)
+			s.pending[row.Hash] = entry
+		}
+		entry.inflight++
+		pending.rows = append(pending.rows, s.index.put(row))
+	}
+	s.mutex.Unlock()
+
+	pending.done = s.committer.enqueue(pending.rows)
+	s.writes[pending.seq] = pending
+	return pending, nil
+}
+
+// await waits until the staged rows are written, settles them unless
+// a barrier already did and returns the error of the write.
+func (s *logStore) await(pending *pendingWrite) error {
+	err := pending.result()
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.settle(pending)
+	return err
+}
+
+// settle makes the written rows visible to readers. Once the last staged
+// row of a hash is settled, the index is reset to its last committed
+// version, which rolls back the rows that failed to be written.
+// The result of the write must be ready. The caller must hold writeMutex
+// and mutex.
+func (s *logStore) settle(pending *pendingWrite) {
+	if pending.settled {
+		return
+	}
+	pending.settled = true
+	delete(s.writes, pending.seq)
+
+	err := pending.result()
+	for _, row := range pending.rows {
+		entry := s.pending[row.Hash]
+		entry.inflight--
+		// Ответы на записи приходят в произвольном порядке,
+		// поэтому более ранняя запись не должна затирать более позднюю
+		if err == nil && pending.seq >= entry.seq {
+			entry.row, entry.exists, entry.seq = row, true, pending.seq
+		}
+		if entry.inflight > 0 {
+			continue
+		}
+
+		delete(s.pending, row.Hash)
+		if entry.exists {
+			s.index.put(entry.row)
+		} else {
+			s.index.remove(row.Hash)
+		}
+	}
+}
+
+// GetFullLink retrieves the latest record by its short hash.
+// Returns an error if the hash does not exist.
+func (s *logStore) GetFullLink(ctx context.Context, hash string) (models.DBShortenRow, error) {
+	byHash, err := s.FindByHash(hash)
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+	return *byHash, nil
+}
+
+// GetUserFullLinks retrieves all links belonging to the specified user ID.
+// Returns ErrUserNotFound if no records exist.
+func (s *logStore) GetUserFullLinks(ctx context.Context, userID string) (models.DBShortenRowList, error) {
+	return s.FindByUserID(userID)
+}
+
+// RemoveUserLinks marks user links as deleted by appending a copy
+// of each row with IsDeleted set. Links that do not exist
+// or belong to another user are skipped.
+func (s *logStore) RemoveUserLinks(ctx context.Context, userID string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	s.writeMutex.Lock()
+	var rows models.DBShortenRowList
+	for _, id := range ids {
+		row, ok := s.index.get(id)
+		if !ok || row.UserID != userID || row.IsDeleted {
+			continue
+		}
+		row.IsDeleted = true
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		s.writeMutex.Unlock()
+		return nil
+	}
+
+	pending, err := s.stage(rows)
+	s.writeMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return s.await(pending)
+}
+
+// UpdateLink changes the original URL of the user's link by appending
+// a new version of the row, which keeps the previous one in its history.
+func (s *logStore) UpdateLink(ctx context.Context, userID string, hash string, original string) (models.DBShortenRow, error) {
+	s.writeMutex.Lock()
+	row, ok := s.index.get(hash)
+	if !ok {
+		s.writeMutex.Unlock()
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	updated, err := editRow(row, userID, original, time.Now())
+	if err != nil || updated.URL == row.URL {
+		s.writeMutex.Unlock()
+		return updated, err
+	}
+	if _, exists := s.index.hashByOriginal(original); exists {
+		s.writeMutex.Unlock()
+		return models.DBShortenRow{}, customErrors.ErrDuplicate
+	}
+
+	pending, err := s.stage(models.DBShortenRowList{updated})
+	s.writeMutex.Unlock()
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+
+	if err := s.await(pending); err != nil {
+		return models.DBShortenRow{}, err
+	}
+	return pending.rows[0], nil
+}
+
+// UpdateLinkTargeting replaces the targeting rules of the user's link
+// by appending a new version of the row.
+func (s *logStore) UpdateLinkTargeting(ctx context.Context, userID string, hash string, rules models.TargetingRuleList) (models.DBShortenRow, error) {
+	s.writeMutex.Lock()
+	row, ok := s.index.get(hash)
+	if !ok {
+		s.writeMutex.Unlock()
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	updated, err := retargetRow(row, userID, rules)
+	if err != nil {
+		s.writeMutex.Unlock()
+		return models.DBShortenRow{}, err
+	}
+
+	pending, err := s.stage(models.DBShortenRowList{updated})
+	s.writeMutex.Unlock()
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+
+	if err := s.await(pending); err != nil {
+		return models.DBShortenRow{}, err
+	}
+	return pending.rows[0], nil
+}
+
+// GetLinkHistory returns the previous destinations of the link, oldest first.
+// Returns ErrNotFound if the hash does not exist.
+func (s *logStore) GetLinkHistory(ctx context.Context, hash string) (models.LinkVersionList, error) {
+	row, err := s.FindByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return append(models.LinkVersionList(nil), row.History...), nil
+}
+
+// AddClickEvents stores the click events in the click file of the driver.
+func (s *logStore) AddClickEvents(ctx context.Context, events models.ClickEventList) error {
+	return s.clicks.AddClickEvents(ctx, events)
+}
+
+// GetClickEvents returns the click events of the link that happened in [from, to).
+func (s *logStore) GetClickEvents(ctx context.Context, hash string, from time.Time, to time.Time) (models.ClickEventList, error) {
+	return s.clicks.events(hash, from, to), nil
+}
+
+// RollupClicks rolls the click events in [watermark, until) up into the rollups file.
+func (s *logStore) RollupClicks(ctx context.Context, until time.Time) (int, error) {
+	return s.clicks.RollupClicks(ctx, until)
+}
+
+// PruneClicks removes the rolled up click events before the given moment from the click file.
+func (s *logStore) PruneClicks(ctx context.Context, before time.Time) (int, error) {
+	return s.clicks.PruneClicks(ctx, before)
+}
+
+// ClickWatermark returns the moment the click events are rolled up to.
+func (s *logStore) ClickWatermark(ctx context.Context) (time.Time, error) {
+	return s.clicks.watermark(), nil
+}
+
+// GetClickRollups returns the rollups of the link with starts in [from, to).
+func (s *logStore) GetClickRollups(ctx context.Context, hash string, granularity models.RollupGranularity, from time.Time, to time.Time) (models.StatsBucketList, error) {
+	return s.clicks.rollupBuckets(hash, granularity, from, to), nil
+}
+
+// AddClick counts a redirect of the link against its MaxClicks
+// by appending a new version of the row. Returns ErrNotFound if the hash
+// does not exist and ErrLinkExpired if the link has no clicks left.
+func (s *logStore) AddClick(ctx context.Context, hash string) error {
+	s.writeMutex.Lock()
+	row, ok := s.index.get(hash)
+	if !ok {
+		s.writeMutex.Unlock()
+		return customErrors.ErrNotFound
+	}
+	if err := countClick(&row); err != nil {
+		s.writeMutex.Unlock()
+		return err
+	}
+
+	pending, err := s.stage(models.DBShortenRowList{row})
+	s.writeMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return s.await(pending)
+}
+
+// ExpireLinks marks links whose ExpiresAt or ActiveUntil is not after now
+// as expired by appending a new version of each row, and returns their hashes.
+func (s *logStore) ExpireLinks(ctx context.Context, now time.Time) ([]string, error) {
+	s.writeMutex.Lock()
+	rows := s.index.expiring(now)
+	if len(rows) == 0 {
+		s.writeMutex.Unlock()
+		return nil, nil
+	}
+
+	pending, err := s.stage(rows)
+	s.writeMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.await(pending); err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(rows))
+	for _, row := range rows {
+		hashes = append(hashes, row.Hash)
+	}
+	return hashes, nil
+}
+
+// AllocateRange reserves size IDs for short codes in this process,
+// which is the only one using the storage.
+func (s *logStore) AllocateRange(ctx context.Context, size int) (uint64, error) {
+	s.mutex.RLock()
+	lastID := s.index.lastID
+	s.mutex.RUnlock()
+
+	return s.ranges.allocate(lastID, size)
+}
+
+// ListHashes calls fn for every stored hash, including deleted links.
+func (s *logStore) ListHashes(ctx context.Context, fn func(hash string) error) error {
+	s.mutex.RLock()
+	hashes := make([]string, 0, s.index.len())
+	for hash := range s.index.rows {
+		if _, ok := s.committed(hash); ok {
+			hashes = append(hashes, hash)
+		}
+	}
+	s.mutex.RUnlock()
+
+	return listHashes(ctx, hashes, fn)
+}
+
+// listHashes calls fn for every hash until it fails or ctx is done.
+func listHashes(ctx context.Context, hashes []string, fn func(hash string) error) error {
+	for _, hash := range hashes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortByID orders rows by ID in place.
+func sortByID(rows models.DBShortenRowList) {
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID < rows[j].ID })
+}
+
+// sameRow reports whether both rows are the same version of a link.
+// Rows are compared by their JSON form, which ignores the monotonic clock
+// and the location of time values.
+func sameRow(a, b models.DBShortenRow) bool {
+	left, err := a.MarshalJSON()
+	if err != nil {
+		return false
+	}
+	right, err := b.MarshalJSON()
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(left, right)
+}
@@ -0,0 +1,104 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+// gatedSink holds every group until the test answers it.
+type gatedSink struct {
+	entered chan models.DBShortenRowList
+	results chan error
+}
+
+func newGatedSink() *gatedSink {
+	return &gatedSink{
+		entered: make(chan models.DBShortenRowList),
+		results: make(chan error),
+	}
+}
+
+func (s *gatedSink) appendRows(rows models.DBShortenRowList, sync bool) error {
+	s.entered <- rows
+	return <-s.results
+}
+
+func (s *logStore) inflight(hash string) int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if entry, ok := s.pending[hash]; ok {
+		return entry.inflight
+	}
+	return 0
+}
+
+func TestLogStore_FailedWrites(t *testing.T) {
+	store := &logStore{index: newLinkIndex()}
+	store.index.put(models.DBShortenRow{Hash: "aaa", URL: "https://ya.ru", UserID: "user", LinkOptions: models.LinkOptions{MaxClicks: 5}})
+	sink := newGatedSink()
+	store.start(sink, FileOptions{SyncMode: SyncAlways})
+	ctx := context.Background()
+	diskFull := errors.New("disk full")
+
+	first := make(chan error, 1)
+	go func() { first <- store.AddClick(ctx, "aaa") }()
+	<-sink.entered
+
+	second := make(chan error, 1)
+	go func() { second <- store.AddClick(ctx, "aaa") }()
+	require.Eventually(t, func() bool { return store.inflight("aaa") == 2 }, time.Second, time.Millisecond)
+
+	created := make(chan error, 1)
+	go func() {
+		_, err := store.AddLink(ctx, "https://google.com", "bbb", "user", models.LinkOptions{})
+		created <- err
+	}()
+	require.Eventually(t, func() bool { return store.inflight("bbb") == 1 }, time.Second, time.Millisecond)
+
+	// Пока запись не стала надёжной, читатели видят прежнее состояние
+	row, err := store.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.Zero(t, row.Clicks, "незаписанный переход не должен быть виден читателям")
+	_, err = store.GetFullLink(ctx, "bbb")
+	require.ErrorIs(t, err, customErrors.ErrNotFound, "незаписанная ссылка не должна быть видна читателям")
+	links, err := store.GetUserFullLinks(ctx, "user")
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+
+	sink.results <- diskFull
+	require.ErrorIs(t, <-first, diskFull)
+	<-sink.entered
+	sink.results <- diskFull
+	require.ErrorIs(t, <-second, diskFull)
+	<-sink.entered
+	sink.results <- diskFull
+	require.ErrorIs(t, <-created, diskFull)
+
+	// Обе неудачные записи откатываются к записанной версии, а не друг к другу
+	row, err = store.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.Zero(t, row.Clicks)
+	staged, ok := store.index.get("aaa")
+	require.True(t, ok)
+	require.Zero(t, staged.Clicks, "в индексе не должна остаться неудачная запись")
+	_, ok = store.index.get("bbb")
+	require.False(t, ok, "хэш неудачно созданной ссылки должен освободиться")
+
+	done := make(chan error, 1)
+	go func() { done <- store.AddClick(ctx, "aaa") }()
+	<-sink.entered
+	sink.results <- nil
+	require.NoError(t, <-done)
+
+	row, err = store.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.Equal(t, 1, row.Clicks)
+
+	store.shutdown()
+}
@@ -6,24 +6,75 @@ import (
 	"errors"
 	customErrors "github.com/thxhix/shortener/internal/errors"
 	"github.com/thxhix/shortener/internal/models"
+	"log"
 	"sync"
+	"time"
 )
 
 // MemoryDatabase implements the Database interface using
-// an in-memory map with synchronization via RWMutex.
-// Data is not persisted and will be lost when the process exits.
+// an in-memory index of full rows with synchronization via RWMutex.
+// Rows are additionally indexed by original URL and by user ID,
+// so the driver follows the same semantics as PostgresQLDatabase.
+//
+// By default data is not persisted and will be lost when the process exits.
+// Click events of redirects are always kept in memory only.
+// If MemoryOptions.SnapshotPath is set, the driver periodically writes
+// point-in-time snapshots and logs every change made since the last one,
+// both are replayed on startup.
 type MemoryDatabase struct {
-	storage map[string]string
+	index   *linkIndex
 	mutex   sync.RWMutex
+	persist *memoryPersistence
+	ranges  localRange
+	clicks  *clickLog
+
+	// snapshotMutex serializes snapshots.
+	snapshotMutex sync.Mutex
+	stop          chan struct{}
+	wg            sync.WaitGroup
+}
+
+// MemoryOptions holds optional settings of MemoryDatabase.
+type MemoryOptions struct {
+	// SnapshotPath enables persistence to the given snapshot file if set.
+	// The change log is kept next to it with the ".log" suffix, the high-water
+	// mark of allocated ID ranges with the ".ranges" suffix.
+	SnapshotPath string
+
+	// SnapshotInterval enables periodic snapshots if positive.
+	SnapshotInterval time.Duration
 }
 
 // NewMemoryDatabase creates and returns a new MemoryDatabase instance.
-// The storage is initialized as an empty map.
-func NewMemoryDatabase() (*MemoryDatabase, error) {
-	return &MemoryDatabase{
-		storage: make(map[string]string),
-		mutex:   sync.RWMutex{}, // для явности
-	}, nil
+// The storage is initialized as an empty index, or restored from
+// the snapshot and the change log if persistence is enabled.
+func NewMemoryDatabase(opts MemoryOptions) (*MemoryDatabase, error) {
+	db := &MemoryDatabase{
+		index:  newLinkIndex(),
+		mutex:  sync.RWMutex{}, // для явности
+		clicks: newClickLog(),
+		stop:   make(chan struct{}),
+	}
+
+	if opts.SnapshotPath == "" {
+		return db, nil
+	}
+
+	var err error
+	db.persist, err = openMemoryPersistence(opts.SnapshotPath, db.index)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.ranges.load(opts.SnapshotPath + ".ranges"); err != nil {
+		return nil, errors.Join(err, db.persist.close())
+	}
+
+	if opts.SnapshotInterval > 0 {
+		db.wg.Add(1)
+		go db.snapshotLoop(opts.SnapshotInterval)
+	}
+
+	return db, nil
 }
 
 // RunMigrations is a no-op for MemoryDatabase.
@@ -33,51 +84,159 @@ func (db *MemoryDatabase) RunMigrations() error {
 }
 
 // AddLink stores a single shortened link in memory.
-// Returns ErrDuplicate if the hash already exists.
-func (db *MemoryDatabase) AddLink(ctx context.Context, original string, shorten string, userID string) (string, error) {
+// If the original URL already exists, it returns the existing shorten hash
+// and ErrDuplicate. Returns ErrHashCollision if the hash already exists.
+func (db *MemoryDatabase) AddLink(ctx context.Context, original string, shorten string, userID string, opts models.LinkOptions) (string, error) {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
-	if _, exists := db.storage[shorten]; exists {
-		return "", customErrors.ErrDuplicate
+	if existing, ok := db.index.hashByOriginal(original); ok {
+		return existing, customErrors.ErrDuplicate
+	}
+	if _, exists := db.index.get(shorten); exists {
+		return "", &customErrors.HashCollisionError{Hash: shorten}
+	}
+
+	row := db.index.put(models.DBShortenRow{
+		Hash:        shorten,
+		URL:         original,
+		UserID:      userID,
+		Time:        time.Now(),
+		LinkOptions: opts,
+	})
+	if err := db.logChanges(models.DBShortenRowList{row}, nil); err != nil {
+		return "", err
 	}
-	db.storage[shorten] = original
 	return shorten, nil
 }
 
 // AddLinks stores multiple shortened links in memory.
-// Returns ErrDuplicate if any hash already exists.
+// The batch is validated before anything is stored, so on ErrDuplicate
+// (an existing original URL) or ErrHashCollision (an existing hash)
+// the storage is left untouched.
 func (db *MemoryDatabase) AddLinks(ctx context.Context, list models.DBShortenRowList, userID string) error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
+	err := validateBatch(list,
+		func(original string) bool {
+			_, exists := db.index.hashByOriginal(original)
+			return exists
+		},
+		func(hash string) bool {
+			_, exists := db.index.get(hash)
+			return exists
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rows := make(models.DBShortenRowList, 0, len(list))
 	for _, link := range list {
-		if _, exists := db.storage[link.Hash]; exists {
-			return customErrors.ErrDuplicate
+		rows = append(rows, db.index.put(models.DBShortenRow{
+			Hash:        link.Hash,
+			URL:         link.URL,
+			UserID:      userID,
+			Time:        now,
+			LinkOptions: link.LinkOptions,
+		}))
+	}
+
+	return db.logChanges(rows, nil)
+}
+
+// logChanges appends changed rows to the change log if persistence is enabled.
+// If writing fails, the rows are rolled back to their previous versions
+// or removed if they are new. The caller must hold the write lock.
+func (db *MemoryDatabase) logChanges(rows models.DBShortenRowList, previous map[string]models.DBShortenRow) error {
+	if db.persist == nil {
+		return nil
+	}
+
+	err := db.persist.append(rows)
+	if err == nil {
+		return nil
+	}
+
+	for _, row := range rows {
+		if old, ok := previous[row.Hash]; ok {
+			db.index.put(old)
+		} else {
+			db.index.remove(row.Hash)
 		}
-		db.storage[link.Hash] = link.URL
 	}
+	return err
+}
 
-	return nil
+// Snapshot writes all rows into a new snapshot and starts a new change log.
+// Rows are captured at a single point in time, writers are blocked only
+// while the rows are copied. Does nothing if persistence is disabled.
+func (db *MemoryDatabase) Snapshot() error {
+	if db.persist == nil {
+		return nil
+	}
+
+	db.snapshotMutex.Lock()
+	defer db.snapshotMutex.Unlock()
+
+	db.mutex.Lock()
+	rows := make(models.DBShortenRowList, 0, db.index.len())
+	for _, row := range db.index.rows {
+		rows = append(rows, row)
+	}
+	err := db.persist.rotateLog()
+	db.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	sortByID(rows)
+	return db.persist.writeSnapshot(rows)
+}
+
+func (db *MemoryDatabase) snapshotLoop(interval time.Duration) {
+	defer db.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			if err := db.Snapshot(); err != nil {
+				log.Printf("ошибка создания снапшота: %v", err)
+			}
+		}
+	}
 }
 
-// GetFullLink retrieves the original URL by hash from memory.
-// Returns an error if the hash does not exist.
+// GetFullLink retrieves the full row by hash from memory.
+// Returns ErrNotFound if the hash does not exist.
 func (db *MemoryDatabase) GetFullLink(ctx context.Context, hash string) (models.DBShortenRow, error) {
 	db.mutex.RLock()
 	defer db.mutex.RUnlock()
 
-	value, ok := db.storage[hash]
-	if ok {
-		return models.DBShortenRow{URL: value}, nil
+	row, ok := db.index.get(hash)
+	if !ok {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
 	}
-	return models.DBShortenRow{}, errors.New("нет такой записи в БД")
+	return row, nil
 }
 
-// Close is a no-op for MemoryDatabase.
-// It always returns nil.
+// Close stops periodic snapshots. If persistence is enabled,
+// it takes a final snapshot and releases the files.
 func (db *MemoryDatabase) Close() error {
-	return nil
+	close(db.stop)
+	db.wg.Wait()
+
+	if db.persist == nil {
+		return nil
+	}
+	return errors.Join(db.Snapshot(), db.persist.close())
 }
 
 // PingConnection always succeeds for MemoryDatabase.
@@ -91,14 +250,195 @@ func (db *MemoryDatabase) GetDriver() *sql.DB {
 	return nil
 }
 
-// GetUserFullLinks is not implemented for MemoryDatabase.
-// Always returns nil.
+// GetUserFullLinks retrieves all links created by the given user,
+// including the deleted ones. Returns nil if the user has no links.
 func (db *MemoryDatabase) GetUserFullLinks(ctx context.Context, userID string) (models.DBShortenRowList, error) {
-	return nil, nil
+	if userID == "" {
+		return nil, nil
+	}
+
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.index.userRows(userID), nil
 }
 
-// RemoveUserLinks is not implemented for MemoryDatabase.
-// Always returns nil.
-func (db *MemoryDatabase) RemoveUserLinks(ctx context.Context, userID string, ids []string) error {
+// AllocateRange reserves size IDs for short codes in this process.
+func (db *MemoryDatabase) AllocateRange(ctx context.Context, size int) (uint64, error) {
+	db.mutex.RLock()
+	lastID := db.index.lastID
+	db.mutex.RUnlock()
+
+	return db.ranges.allocate(lastID, size)
+}
+
+// ListHashes calls fn for every stored hash, including deleted links.
+func (db *MemoryDatabase) ListHashes(ctx context.Context, fn func(hash string) error) error {
+	db.mutex.RLock()
+	hashes := db.index.hashes()
+	db.mutex.RUnlock()
+
+	return listHashes(ctx, hashes, fn)
+}
+
+// UpdateLink changes the original URL of the user's link, keeping
+// the previous one in the history of the row.
+func (db *MemoryDatabase) UpdateLink(ctx context.Context, userID string, hash string, original string) (models.DBShortenRow, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	row, ok := db.index.get(hash)
+	if !ok {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	updated, err := editRow(row, userID, original, time.Now())
+	if err != nil || updated.URL == row.URL {
+		return updated, err
+	}
+	if _, exists := db.index.hashByOriginal(original); exists {
+		return models.DBShortenRow{}, customErrors.ErrDuplicate
+	}
+
+	updated = db.index.put(updated)
+	if err := db.logChanges(models.DBShortenRowList{updated}, map[string]models.DBShortenRow{hash: row}); err != nil {
+		return models.DBShortenRow{}, err
+	}
+	return updated, nil
+}
+
+// UpdateLinkTargeting replaces the targeting rules of the user's link.
+func (db *MemoryDatabase) UpdateLinkTargeting(ctx context.Context, userID string, hash string, rules models.TargetingRuleList) (models.DBShortenRow, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	row, ok := db.index.get(hash)
+	if !ok {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	updated, err := retargetRow(row, userID, rules)
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+
+	updated = db.index.put(updated)
+	if err := db.logChanges(models.DBShortenRowList{updated}, map[string]models.DBShortenRow{hash: row}); err != nil {
+		return models.DBShortenRow{}, err
+	}
+	return updated, nil
+}
+
+// GetLinkHistory returns the previous destinations of the link, oldest first.
+// Returns ErrNotFound if the hash does not exist.
+func (db *MemoryDatabase) GetLinkHistory(ctx context.Context, hash string) (models.LinkVersionList, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	row, ok := db.index.get(hash)
+	if !ok {
+		return nil, customErrors.ErrNotFound
+	}
+	return append(models.LinkVersionList(nil), row.History...), nil
+}
+
+// AddClickEvents stores the click events in memory.
+func (db *MemoryDatabase) AddClickEvents(ctx context.Context, events models.ClickEventList) error {
+	db.clicks.add(events)
 	return nil
 }
+
+// GetClickEvents returns the click events of the link that happened in [from, to).
+func (db *MemoryDatabase) GetClickEvents(ctx context.Context, hash string, from time.Time, to time.Time) (models.ClickEventList, error) {
+	return db.clicks.events(hash, from, to), nil
+}
+
+// RollupClicks rolls the click events in [watermark, until) up in memory.
+func (db *MemoryDatabase) RollupClicks(ctx context.Context, until time.Time) (int, error) {
+	return db.clicks.rollup(until), nil
+}
+
+// PruneClicks removes the rolled up click events before the given moment.
+func (db *MemoryDatabase) PruneClicks(ctx context.Context, before time.Time) (int, error) {
+	return db.clicks.prune(before), nil
+}
+
+// ClickWatermark returns the moment the click events are rolled up to.
+func (db *MemoryDatabase) ClickWatermark(ctx context.Context) (time.Time, error) {
+	return db.clicks.watermark(), nil
+}
+
+// GetClickRollups returns the rollups of the link with starts in [from, to).
+func (db *MemoryDatabase) GetClickRollups(ctx context.Context, hash string, granularity models.RollupGranularity, from time.Time, to time.Time) (models.StatsBucketList, error) {
+	return db.clicks.rollupBuckets(hash, granularity, from, to), nil
+}
+
+// AddClick counts a redirect of the link against its MaxClicks.
+// Returns ErrNotFound if the hash does not exist and ErrLinkExpired
+// if the link has no clicks left.
+func (db *MemoryDatabase) AddClick(ctx context.Context, hash string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	row, ok := db.index.get(hash)
+	if !ok {
+		return customErrors.ErrNotFound
+	}
+	previous := row
+	if err := countClick(&row); err != nil {
+		return err
+	}
+
+	row = db.index.put(row)
+	return db.logChanges(models.DBShortenRowList{row}, map[string]models.DBShortenRow{hash: previous})
+}
+
+// ExpireLinks marks links whose ExpiresAt or ActiveUntil is not after now
+// as expired and returns their hashes.
+func (db *MemoryDatabase) ExpireLinks(ctx context.Context, now time.Time) ([]string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	rows := db.index.expiring(now)
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	previous := make(map[string]models.DBShortenRow, len(rows))
+	hashes := make([]string, 0, len(rows))
+	for _, row := range rows {
+		previous[row.Hash], _ = db.index.get(row.Hash)
+		db.index.put(row)
+		hashes = append(hashes, row.Hash)
+	}
+	if err := db.logChanges(rows, previous); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// RemoveUserLinks marks user links as deleted.
+// Links that do not exist or belong to another user are skipped.
+func (db *MemoryDatabase) RemoveUserLinks(ctx context.Context, userID string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var rows models.DBShortenRowList
+	previous := make(map[string]models.DBShortenRow)
+	for _, id := range ids {
+		row, ok := db.index.get(id)
+		if !ok || row.UserID != userID || row.IsDeleted {
+			continue
+		}
+		previous[row.Hash] = row
+		row.IsDeleted = true
+		rows = append(rows, db.index.put(row))
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.logChanges(rows, previous)
+}
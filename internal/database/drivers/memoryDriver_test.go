@@ -0,0 +1,458 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thxhix/shortener/internal/database/interfaces"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+func TestMemoryDatabase_AddLink(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	hash, err := db.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "aaa", hash)
+
+	hash, err = db.AddLink(ctx, "https://ya.ru", "bbb", "user", models.LinkOptions{})
+	require.ErrorIs(t, err, customErrors.ErrDuplicate)
+	require.Equal(t, "aaa", hash, "для дубликата должен вернуться существующий хэш")
+
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru", row.URL)
+	require.Equal(t, "user", row.UserID)
+	require.False(t, row.Time.IsZero())
+
+	_, err = db.GetFullLink(ctx, "bbb")
+	require.ErrorIs(t, err, customErrors.ErrNotFound)
+
+	hash, err = db.AddLink(ctx, "https://google.com", "aaa", "user", models.LinkOptions{})
+	require.ErrorIs(t, err, customErrors.ErrHashCollision, "занятый хэш не должен считаться дубликатом ссылки")
+	require.Empty(t, hash)
+}
+
+func TestMemoryDatabase_AddLinks(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	err = db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "bbb", URL: "https://google.com"},
+		{Hash: "ccc", URL: "https://ya.ru"},
+	}, "user")
+	require.ErrorIs(t, err, customErrors.ErrDuplicate)
+
+	_, err = db.GetFullLink(ctx, "bbb")
+	require.ErrorIs(t, err, customErrors.ErrNotFound, "batch с дубликатом не должен ничего сохранять")
+
+	err = db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "bbb", URL: "https://google.com"},
+		{Hash: "aaa", URL: "https://test.ru"},
+	}, "user")
+	require.ErrorIs(t, err, customErrors.ErrHashCollision)
+
+	err = db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "bbb", URL: "https://google.com"},
+		{Hash: "bbb", URL: "https://test.ru"},
+	}, "user")
+	require.ErrorIs(t, err, customErrors.ErrHashCollision, "хэши внутри batch тоже не должны повторяться")
+
+	_, err = db.GetFullLink(ctx, "bbb")
+	require.ErrorIs(t, err, customErrors.ErrNotFound, "batch с занятым хэшем не должен ничего сохранять")
+
+	err = db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "bbb", URL: "https://google.com"},
+		{Hash: "ccc", URL: "https://test.ru"},
+	}, "user")
+	require.NoError(t, err)
+
+	links, err := db.GetUserFullLinks(ctx, "user")
+	require.NoError(t, err)
+	require.Len(t, links, 3)
+}
+
+func TestMemoryDatabase_RemoveUserLinks(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = db.AddLink(ctx, "https://google.com", "bbb", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, db.RemoveUserLinks(ctx, "stranger", []string{"aaa"}))
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.False(t, row.IsDeleted, "чужой пользователь не может удалить ссылку")
+
+	require.NoError(t, db.RemoveUserLinks(ctx, "owner", []string{"aaa"}))
+	row, err = db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.True(t, row.IsDeleted)
+
+	links, err := db.GetUserFullLinks(ctx, "owner")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+}
+
+func TestMemoryDatabase_Snapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.snapshot")
+	opts := MemoryOptions{SnapshotPath: path}
+	db, err := NewMemoryDatabase(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, db.Snapshot())
+
+	// Изменения после снапшота попадают только в лог
+	_, err = db.AddLink(ctx, "https://google.com", "bbb", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, db.RemoveUserLinks(ctx, "owner", []string{"aaa"}))
+
+	// Имитируем падение: файлы освобождаем без финального снапшота
+	require.NoError(t, db.persist.close())
+
+	db, err = NewMemoryDatabase(opts)
+	require.NoError(t, err)
+
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.True(t, row.IsDeleted)
+	_, err = db.GetFullLink(ctx, "bbb")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = NewMemoryDatabase(opts)
+	require.NoError(t, err)
+	defer db.Close()
+
+	links, err := db.GetUserFullLinks(ctx, "owner")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+
+	hash, err := db.AddLink(ctx, "https://google.com", "ccc", "owner", models.LinkOptions{})
+	require.ErrorIs(t, err, customErrors.ErrDuplicate)
+	require.Equal(t, "bbb", hash)
+}
+
+func TestMemoryDatabase_AllocateRange(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	start, err := db.AllocateRange(ctx, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, start, "диапазон должен начинаться после сохранённых ID")
+
+	start, err = db.AllocateRange(ctx, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 12, start, "диапазоны не должны пересекаться")
+}
+
+func TestMemoryDatabase_AllocateRangeSnapshot(t *testing.T) {
+	opts := MemoryOptions{SnapshotPath: filepath.Join(t.TempDir(), "snapshot.json")}
+	db, err := NewMemoryDatabase(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = db.AllocateRange(ctx, 10)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = NewMemoryDatabase(opts)
+	require.NoError(t, err)
+	defer db.Close()
+
+	start, err := db.AllocateRange(ctx, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 11, start, "диапазон должен начинаться после выданных до перезапуска")
+}
+
+func TestMemoryDatabase_LinkLimits(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{MaxClicks: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddClick(ctx, "aaa"))
+	require.NoError(t, db.AddClick(ctx, "aaa"))
+	require.ErrorIs(t, db.AddClick(ctx, "aaa"), customErrors.ErrLinkExpired, "лимит переходов исчерпан")
+	require.ErrorIs(t, db.AddClick(ctx, "zzz"), customErrors.ErrNotFound)
+
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.Equal(t, 2, row.Clicks)
+	require.True(t, row.IsExpired, "ссылка должна истечь на последнем переходе")
+
+	now := time.Now()
+	expiresAt := now.Add(time.Minute)
+	err = db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "bbb", URL: "https://google.com", LinkOptions: models.LinkOptions{ExpiresAt: &expiresAt}},
+		{Hash: "ccc", URL: "https://test.ru"},
+	}, "user")
+	require.NoError(t, err)
+
+	hashes, err := db.ExpireLinks(ctx, now)
+	require.NoError(t, err)
+	require.Empty(t, hashes, "срок действия ещё не истёк")
+
+	hashes, err = db.ExpireLinks(ctx, expiresAt)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bbb"}, hashes)
+
+	row, err = db.GetFullLink(ctx, "bbb")
+	require.NoError(t, err)
+	require.True(t, row.IsExpired)
+
+	hashes, err = db.ExpireLinks(ctx, expiresAt)
+	require.NoError(t, err)
+	require.Empty(t, hashes, "истёкшие ссылки не должны помечаться повторно")
+}
+
+// requireSingleClick checks that a one-time link is followed exactly once
+// when it is clicked concurrently.
+func requireSingleClick(t *testing.T, db interfaces.Database) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := db.AddLink(ctx, "https://ya.ru/once", "once", "user", models.LinkOptions{MaxClicks: 1})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var succeeded, expired atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch err := db.AddClick(ctx, "once"); {
+			case err == nil:
+				succeeded.Add(1)
+			case errors.Is(err, customErrors.ErrLinkExpired):
+				expired.Add(1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.EqualValues(t, 1, succeeded.Load(), "одноразовая ссылка должна открыться ровно один раз")
+	require.EqualValues(t, 49, expired.Load())
+}
+
+func TestMemoryDatabase_OneTimeLink(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+
+	requireSingleClick(t, db)
+}
+
+func TestMemoryDatabase_ExpireActiveWindow(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	now := time.Now()
+	from := now.Add(time.Hour)
+	until := now.Add(2 * time.Hour)
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "user", models.LinkOptions{ActiveFrom: &from, ActiveUntil: &until})
+	require.NoError(t, err)
+
+	hashes, err := db.ExpireLinks(ctx, from)
+	require.NoError(t, err)
+	require.Empty(t, hashes, "ссылка внутри окна не должна истекать")
+
+	hashes, err = db.ExpireLinks(ctx, until)
+	require.NoError(t, err)
+	require.Equal(t, []string{"aaa"}, hashes)
+}
+
+// requireEditableLink checks that the owner can change the destination of a link
+// and that the previous destinations are kept in its history.
+func requireEditableLink(t *testing.T, db interfaces.Database) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := db.AddLink(ctx, "https://ya.ru/typo", "edit", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = db.AddLink(ctx, "https://google.com", "other", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+
+	_, err = db.UpdateLink(ctx, "stranger", "edit", "https://ya.ru/fixed")
+	require.ErrorIs(t, err, customErrors.ErrNotOwner, "чужую ссылку менять нельзя")
+	_, err = db.UpdateLink(ctx, "owner", "missing", "https://ya.ru/fixed")
+	require.ErrorIs(t, err, customErrors.ErrNotFound)
+	_, err = db.UpdateLink(ctx, "owner", "edit", "https://google.com")
+	require.ErrorIs(t, err, customErrors.ErrDuplicate, "адрес уже сокращён другой ссылкой")
+
+	row, err := db.UpdateLink(ctx, "owner", "edit", "https://ya.ru/fixed")
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru/fixed", row.URL)
+	require.Equal(t, 2, row.CurrentVersion())
+
+	row, err = db.UpdateLink(ctx, "owner", "edit", "https://ya.ru/fixed")
+	require.NoError(t, err)
+	require.Equal(t, 2, row.CurrentVersion(), "тот же адрес не создаёт новую версию")
+
+	row, err = db.GetFullLink(ctx, "edit")
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru/fixed", row.URL)
+
+	history, err := db.GetLinkHistory(ctx, "edit")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, 1, history[0].Version)
+	require.Equal(t, "https://ya.ru/typo", history[0].URL)
+	require.NotNil(t, history[0].ReplacedAt)
+
+	// Прежний адрес освобождается и может быть сокращён заново
+	_, err = db.AddLink(ctx, "https://ya.ru/typo", "again", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+
+	_, err = db.GetLinkHistory(ctx, "missing")
+	require.ErrorIs(t, err, customErrors.ErrNotFound)
+}
+
+func TestMemoryDatabase_UpdateLink(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+
+	requireEditableLink(t, db)
+}
+
+// appTargeting sends iOS and Android clients to the app stores.
+var appTargeting = models.TargetingRuleList{
+	{OS: "iOS", URL: "https://apps.apple.com/app/id1"},
+	{OS: "Android", URL: "https://play.google.com/store/apps/details?id=app"},
+}
+
+// requireTargetableLink checks UpdateLinkTargeting of the database
+// on the link "target", which is left with appTargeting.
+func requireTargetableLink(t *testing.T, db interfaces.Database) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := db.AddLink(ctx, "https://ya.ru/app", "target", "owner", models.LinkOptions{
+		Targeting: models.TargetingRuleList{{Device: "desktop", URL: "https://ya.ru/desktop"}},
+	})
+	require.NoError(t, err)
+	row, err := db.GetFullLink(ctx, "target")
+	require.NoError(t, err)
+	require.Len(t, row.Targeting, 1, "правила сохраняются вместе со ссылкой")
+
+	_, err = db.UpdateLinkTargeting(ctx, "stranger", "target", appTargeting)
+	require.ErrorIs(t, err, customErrors.ErrNotOwner, "чужие правила менять нельзя")
+	_, err = db.UpdateLinkTargeting(ctx, "owner", "missing", appTargeting)
+	require.ErrorIs(t, err, customErrors.ErrNotFound)
+
+	row, err = db.UpdateLinkTargeting(ctx, "owner", "target", appTargeting)
+	require.NoError(t, err)
+	require.Equal(t, appTargeting, row.Targeting)
+	require.Equal(t, "https://ya.ru/app", row.URL, "адрес ссылки не меняется")
+
+	row, err = db.GetFullLink(ctx, "target")
+	require.NoError(t, err)
+	require.Equal(t, appTargeting, row.Targeting)
+}
+
+func TestMemoryDatabase_UpdateLinkTargeting(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+
+	requireTargetableLink(t, db)
+}
+
+func TestMemoryDatabase_ClickEvents(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	now := time.Now()
+	// Воркеры пишут пачки независимо, поэтому события приходят не по порядку
+	require.NoError(t, db.AddClickEvents(ctx, models.ClickEventList{{Hash: "aaa", Time: now.Add(time.Minute)}}))
+	require.NoError(t, db.AddClickEvents(ctx, models.ClickEventList{
+		{Hash: "aaa", Time: now},
+		{Hash: "bbb", Time: now},
+		{Hash: "aaa", Time: now.Add(time.Hour)},
+	}))
+
+	events, err := db.GetClickEvents(ctx, "aaa", now, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 2, "конец периода не включается")
+	require.True(t, events[0].Time.Equal(now), "события возвращаются по времени")
+	require.True(t, events[1].Time.Equal(now.Add(time.Minute)))
+
+	events, err = db.GetClickEvents(ctx, "zzz", now, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
+
+func TestMemoryDatabase_ClickRollups(t *testing.T) {
+	db, err := NewMemoryDatabase(MemoryOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	day := time.Date(2024, time.May, 15, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, db.AddClickEvents(ctx, models.ClickEventList{
+		{Hash: "aaa", Time: day.Add(10*time.Hour + time.Minute)},
+		{Hash: "aaa", Time: day.Add(10*time.Hour + 59*time.Minute)},
+		{Hash: "aaa", Time: day.Add(11 * time.Hour)},
+		{Hash: "bbb", Time: day.Add(10 * time.Hour)},
+	}))
+
+	watermark, err := db.ClickWatermark(ctx)
+	require.NoError(t, err)
+	require.True(t, watermark.IsZero(), "до первой свёртки водяной знак пуст")
+
+	rolled, err := db.RollupClicks(ctx, day.Add(11*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 3, rolled, "конец периода не сворачивается")
+	rolled, err = db.RollupClicks(ctx, day.Add(11*time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, rolled, "повторная свёртка не должна считать события дважды")
+
+	rolled, err = db.RollupClicks(ctx, day.Add(12*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, rolled)
+
+	hourly, err := db.GetClickRollups(ctx, "aaa", models.RollupHour, day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, models.StatsBucketList{
+		{Start: day.Add(10 * time.Hour), Clicks: 2},
+		{Start: day.Add(11 * time.Hour), Clicks: 1},
+	}, hourly)
+	daily, err := db.GetClickRollups(ctx, "aaa", models.RollupDay, day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, models.StatsBucketList{{Start: day, Clicks: 3}}, daily, "дневная сводка накапливается за несколько свёрток")
+
+	// Событие после водяного знака не удаляется, даже если старше срока хранения
+	require.NoError(t, db.AddClickEvents(ctx, models.ClickEventList{{Hash: "aaa", Time: day.Add(13 * time.Hour)}}))
+	pruned, err := db.PruneClicks(ctx, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 4, pruned)
+	events, err := db.GetClickEvents(ctx, "aaa", day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 1, "несвёрнутые события должны сохраняться")
+}
@@ -0,0 +1,168 @@
+package drivers
+
+import (
+	"errors"
+	"github.com/thxhix/shortener/internal/models"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// memoryPersistence keeps MemoryDatabase on disk as a point-in-time snapshot
+// plus an append-only log of the rows changed since that snapshot.
+//
+// Taking a snapshot first moves the current log aside (path.log.old)
+// and starts a new one, then writes the snapshot and drops the old log.
+// Replaying the snapshot, the old log and the current log in this order
+// restores the latest state, since every record is a full version of a row.
+type memoryPersistence struct {
+	path string
+	lock *os.File
+	log  *os.File
+}
+
+// openMemoryPersistence takes the lock on the snapshot path
+// and replays the snapshot and the logs into the index.
+func openMemoryPersistence(path string, index *linkIndex) (*memoryPersistence, error) {
+	lock, err := acquireLock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &memoryPersistence{
+		path: path,
+		lock: lock,
+	}
+
+	apply := func(row models.DBShortenRow) {
+		index.put(row)
+	}
+
+	for _, name := range []string{p.path, p.oldLogPath()} {
+		if err := replayFile(name, apply); err != nil {
+			return nil, errors.Join(err, releaseLock(lock))
+		}
+	}
+
+	p.log, err = openLogFile(p.logPath())
+	if err != nil {
+		return nil, errors.Join(err, releaseLock(lock))
+	}
+	if _, err := replayLog(p.log, apply); err != nil {
+		return nil, errors.Join(err, p.log.Close(), releaseLock(lock))
+	}
+
+	return p, nil
+}
+
+func (p *memoryPersistence) logPath() string {
+	return p.path + ".log"
+}
+
+func (p *memoryPersistence) oldLogPath() string {
+	return p.path + ".log.old"
+}
+
+// append writes changed rows to the log. The log is not fsynced,
+// flushing is left to the OS until the next snapshot.
+// On error, the log is truncated back to its previous size.
+func (p *memoryPersistence) append(rows models.DBShortenRowList) error {
+	offset, err := p.log.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	if err := writeRows(p.log, rows, false); err != nil {
+		return errors.Join(err, p.log.Truncate(offset))
+	}
+	return nil
+}
+
+// rotateLog moves the current log aside and starts a new one.
+// If an old log is left by a failed snapshot, the current log is appended
+// to it instead, so that no change is lost before the next snapshot.
+// The caller must make sure no rows are appended concurrently.
+func (p *memoryPersistence) rotateLog() error {
+	if err := p.log.Sync(); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(p.oldLogPath()); err == nil {
+		if err := p.appendToOldLog(); err != nil {
+			return err
+		}
+		return p.log.Truncate(0)
+	}
+
+	if err := p.log.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(p.logPath(), p.oldLogPath()); err != nil {
+		return err
+	}
+
+	var err error
+	p.log, err = openLogFile(p.logPath())
+	if err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(p.path))
+}
+
+func (p *memoryPersistence) appendToOldLog() error {
+	old, err := os.OpenFile(p.oldLogPath(), os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.log.Seek(0, io.SeekStart); err != nil {
+		return errors.Join(err, old.Close())
+	}
+	if _, err := io.Copy(old, p.log); err != nil {
+		return errors.Join(err, old.Close())
+	}
+	if err := old.Sync(); err != nil {
+		return errors.Join(err, old.Close())
+	}
+	return old.Close()
+}
+
+// writeSnapshot replaces the snapshot with the given rows atomically
+// and drops the old log, which the snapshot now covers.
+func (p *memoryPersistence) writeSnapshot(rows models.DBShortenRowList) error {
+	tmpPath := p.path + ".tmp"
+	if err := writeLogFile(tmpPath, rows); err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+	if err := os.Rename(tmpPath, p.path); err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+	if err := syncDir(filepath.Dir(p.path)); err != nil {
+		return err
+	}
+
+	err := os.Remove(p.oldLogPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// close closes the log and releases the lock.
+func (p *memoryPersistence) close() error {
+	return errors.Join(p.log.Close(), releaseLock(p.lock))
+}
+
+// replayFile replays the JSON-lines file if it exists.
+func replayFile(path string, apply func(row models.DBShortenRow)) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = replayLog(file, apply)
+	return errors.Join(err, file.Close())
+}
@@ -3,6 +3,9 @@ package drivers
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -13,6 +16,12 @@ import (
 	"time"
 )
 
+// shortenRangeName is the id_ranges row that holds IDs of short codes.
+const shortenRangeName = "shortener"
+
+// pgerrUniqueViolation is the SQLSTATE of a unique constraint violation.
+const pgerrUniqueViolation = "23505"
+
 // PostgresQLDatabase implements the Database interface using PostgreSQL.
 // It stores shortened URLs in the "shortener" table and supports
 // migrations, transactions, and user-specific queries.
@@ -54,8 +63,8 @@ func (db *PostgresQLDatabase) RunMigrations() error {
 
 // AddLink inserts a single link into the database.
 // If the original URL already exists, it returns the existing shorten hash
-// and ErrDuplicate.
-func (db *PostgresQLDatabase) AddLink(ctx context.Context, original string, shorten string, userID string) (string, error) {
+// and ErrDuplicate. Returns ErrHashCollision if the hash already exists.
+func (db *PostgresQLDatabase) AddLink(ctx context.Context, original string, shorten string, userID string, opts models.LinkOptions) (string, error) {
 	var user interface{}
 	if userID == "" {
 		user = nil
@@ -63,17 +72,24 @@ func (db *PostgresQLDatabase) AddLink(ctx context.Context, original string, shor
 		user = userID
 	}
 
+	targeting, err := targetingValue(opts.Targeting)
+	if err != nil {
+		return "", err
+	}
+
 	query := `
-        INSERT INTO shortener (original, shorten, user_id)
-        VALUES ($1, $2, $3)
+        INSERT INTO shortener (original, shorten, user_id, expires_at, max_clicks, password_hash, active_from, active_until,
+                               redirect_code, cache_max_age, targeting)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
         ON CONFLICT (original) DO UPDATE
         SET original = EXCLUDED.original
         RETURNING shorten
     `
 	var insertedShorten string
-	err := db.driver.QueryRowContext(ctx, query, original, shorten, user).Scan(&insertedShorten)
+	err = db.driver.QueryRowContext(ctx, query, original, shorten, user, opts.ExpiresAt, opts.MaxClicks, opts.PasswordHash,
+		opts.ActiveFrom, opts.ActiveUntil, opts.RedirectCode, opts.CacheMaxAge, targeting).Scan(&insertedShorten)
 	if err != nil {
-		return "", err
+		return "", uniqueViolation(err, shorten)
 	}
 
 	if insertedShorten != shorten {
@@ -84,7 +100,8 @@ func (db *PostgresQLDatabase) AddLink(ctx context.Context, original string, shor
 }
 
 // AddLinks inserts multiple links into the database in a transaction.
-// If any insert fails, the transaction is rolled back.
+// If any insert fails, the transaction is rolled back. An existing original URL
+// is reported as ErrDuplicate and an existing hash as ErrHashCollision.
 func (db *PostgresQLDatabase) AddLinks(ctx context.Context, list models.DBShortenRowList, userID string) (err error) {
 	tx, err := db.driver.BeginTx(ctx, nil)
 	if err != nil {
@@ -107,7 +124,11 @@ func (db *PostgresQLDatabase) AddLinks(ctx context.Context, list models.DBShorte
 		user = userID
 	}
 
-	stmt, err := tx.PrepareContext(ctx, "INSERT INTO shortener (original, shorten, user_id) VALUES($1, $2, $3)")
+	stmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO shortener (original, shorten, user_id, expires_at, max_clicks, password_hash, active_from, active_until,
+                               redirect_code, cache_max_age, targeting)
+        VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    `)
 
 	if err != nil {
 		return err
@@ -120,10 +141,15 @@ func (db *PostgresQLDatabase) AddLinks(ctx context.Context, list models.DBShorte
 	}()
 
 	for _, row := range list {
-		_, err = stmt.ExecContext(ctx, row.URL, row.Hash, user)
-		if err != nil {
+		var targeting string
+		if targeting, err = targetingValue(row.Targeting); err != nil {
 			return err
 		}
+		_, err = stmt.ExecContext(ctx, row.URL, row.Hash, user, row.ExpiresAt, row.MaxClicks, row.PasswordHash,
+			row.ActiveFrom, row.ActiveUntil, row.RedirectCode, row.CacheMaxAge, targeting)
+		if err != nil {
+			return uniqueViolation(err, row.Hash)
+		}
 	}
 
 	err = tx.Commit()
@@ -134,28 +160,487 @@ func (db *PostgresQLDatabase) AddLinks(ctx context.Context, list models.DBShorte
 	return
 }
 
+// uniqueViolation converts a violation of the unique constraints
+// of the shortener table, caused by inserting the given hash,
+// into ErrDuplicate or HashCollisionError. Other errors are returned as is.
+func uniqueViolation(err error, hash string) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != pgerrUniqueViolation {
+		return err
+	}
+
+	switch pqErr.Constraint {
+	case "shortener_original_key":
+		return customErrors.ErrDuplicate
+	case "shortener_shorten_key":
+		return &customErrors.HashCollisionError{Hash: hash}
+	default:
+		return err
+	}
+}
+
 // GetFullLink retrieves a link by its hash.
-// Returns an error if the hash is not found.
+// Returns ErrNotFound if the hash is not found.
 func (db *PostgresQLDatabase) GetFullLink(ctx context.Context, hash string) (models.DBShortenRow, error) {
-	query := "SELECT id, original, shorten, is_deleted, created_at FROM shortener WHERE (shorten) LIKE ($1)"
+	query := `SELECT id, original, shorten, user_id, is_deleted, created_at, expires_at, max_clicks, clicks, is_expired,
+	                 password_hash, active_from, active_until, redirect_code, cache_max_age, version, targeting
+	          FROM shortener WHERE shorten = $1`
 
 	row := db.driver.QueryRowContext(ctx, query, hash)
 
-	var data models.DBShortenRow
+	var (
+		data  models.DBShortenRow
+		owner sql.NullString
+	)
 	err := row.Scan(
 		&data.ID,
 		&data.URL,
 		&data.Hash,
+		&owner,
 		&data.IsDeleted,
 		&data.Time,
+		&data.ExpiresAt,
+		&data.MaxClicks,
+		&data.Clicks,
+		&data.IsExpired,
+		&data.PasswordHash,
+		&data.ActiveFrom,
+		&data.ActiveUntil,
+		&data.RedirectCode,
+		&data.CacheMaxAge,
+		&data.Version,
+		targetingColumn{&data.Targeting},
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
 	if err != nil {
 		return models.DBShortenRow{}, err
 	}
+	data.UserID = owner.String
 
 	return data, nil
 }
 
+// UpdateLink changes the original URL of the user's link in a transaction,
+// moving the previous one to the shortener_history table.
+func (db *PostgresQLDatabase) UpdateLink(ctx context.Context, userID string, hash string, original string) (models.DBShortenRow, error) {
+	if err := db.updateLink(ctx, userID, hash, original); err != nil {
+		return models.DBShortenRow{}, err
+	}
+	return db.GetFullLink(ctx, hash)
+}
+
+func (db *PostgresQLDatabase) updateLink(ctx context.Context, userID string, hash string, original string) (err error) {
+	tx, err := db.driver.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if RBError := tx.Rollback(); RBError != nil {
+				log.Printf("ошибка при rollback: %v", RBError)
+			}
+		}
+	}()
+
+	var (
+		row   models.DBShortenRow
+		owner sql.NullString
+	)
+	err = tx.QueryRowContext(ctx, `
+        SELECT original, user_id, is_deleted, version
+        FROM shortener WHERE shorten = $1 FOR UPDATE
+    `, hash).Scan(&row.URL, &owner, &row.IsDeleted, &row.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return customErrors.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	row.UserID = owner.String
+
+	updated, err := editRow(row, userID, original, time.Now())
+	if err != nil {
+		return err
+	}
+	if updated.URL == row.URL {
+		return tx.Commit()
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO shortener_history (shorten, version, original)
+        VALUES ($1, $2, $3)
+    `, hash, row.CurrentVersion(), row.URL)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE shortener SET original = $1, version = $2 WHERE shorten = $3",
+		updated.URL, updated.Version, hash)
+	if err != nil {
+		return uniqueViolation(err, hash)
+	}
+
+	return tx.Commit()
+}
+
+// UpdateLinkTargeting replaces the targeting rules of the user's link.
+func (db *PostgresQLDatabase) UpdateLinkTargeting(ctx context.Context, userID string, hash string, rules models.TargetingRuleList) (models.DBShortenRow, error) {
+	row, err := db.GetFullLink(ctx, hash)
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+	if _, err := retargetRow(row, userID, rules); err != nil {
+		return models.DBShortenRow{}, err
+	}
+
+	targeting, err := targetingValue(rules)
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+	// Условие на владельца и удаление повторяется в запросе на случай гонки с удалением
+	result, err := db.driver.ExecContext(ctx,
+		"UPDATE shortener SET targeting = $1 WHERE shorten = $2 AND user_id = $3 AND NOT is_deleted",
+		targeting, hash, userID)
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+	if affected == 0 {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	return db.GetFullLink(ctx, hash)
+}
+
+// GetLinkHistory returns the previous destinations of the link, oldest first.
+// Returns ErrNotFound if the hash does not exist.
+func (db *PostgresQLDatabase) GetLinkHistory(ctx context.Context, hash string) (history models.LinkVersionList, err error) {
+	var exists bool
+	err = db.driver.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM shortener WHERE shorten = $1)", hash).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, customErrors.ErrNotFound
+	}
+
+	rows, err := db.driver.QueryContext(ctx, `
+        SELECT version, original, replaced_at
+        FROM shortener_history WHERE shorten = $1 ORDER BY version
+    `, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if CErr := rows.Close(); CErr != nil && err == nil {
+			err = CErr
+		}
+	}()
+
+	for rows.Next() {
+		var (
+			version    models.LinkVersion
+			replacedAt time.Time
+		)
+		if err := rows.Scan(&version.Version, &version.URL, &replacedAt); err != nil {
+			return nil, err
+		}
+		version.ReplacedAt = &replacedAt
+		history = append(history, version)
+	}
+	return history, rows.Err()
+}
+
+// AddClickEvents copies the click events into the clicks table in a transaction.
+func (db *PostgresQLDatabase) AddClickEvents(ctx context.Context, events models.ClickEventList) (err error) {
+	tx, err := db.driver.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if RBError := tx.Rollback(); RBError != nil {
+				log.Printf("ошибка при rollback: %v", RBError)
+			}
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("clicks", "shorten", "clicked_at", "referrer", "user_agent", "ip", "device", "os", "browser"))
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		_, err = stmt.ExecContext(ctx, event.Hash, event.Time, event.Referrer, event.UserAgent, event.IP, event.Device, event.OS, event.Browser)
+		if err != nil {
+			return errors.Join(err, stmt.Close())
+		}
+	}
+	// Пустой Exec завершает COPY и отправляет накопленные строки
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return errors.Join(err, stmt.Close())
+	}
+	if err = stmt.Close(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetClickEvents returns the click events of the link that happened in [from, to).
+func (db *PostgresQLDatabase) GetClickEvents(ctx context.Context, hash string, from time.Time, to time.Time) (events models.ClickEventList, err error) {
+	rows, err := db.driver.QueryContext(ctx, `
+        SELECT shorten, clicked_at, referrer, user_agent, ip, device, os, browser
+        FROM clicks WHERE shorten = $1 AND clicked_at >= $2 AND clicked_at < $3
+        ORDER BY clicked_at
+    `, hash, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if CErr := rows.Close(); CErr != nil && err == nil {
+			err = CErr
+		}
+	}()
+
+	for rows.Next() {
+		var event models.ClickEvent
+		if err := rows.Scan(&event.Hash, &event.Time, &event.Referrer, &event.UserAgent, &event.IP, &event.Device, &event.OS, &event.Browser); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// rollupTables maps the granularities of click rollups to their tables.
+var rollupTables = map[models.RollupGranularity]string{
+	models.RollupHour: "clicks_hourly",
+	models.RollupDay:  "clicks_daily",
+}
+
+// RollupClicks adds the click events in [watermark, until) to the hourly and
+// daily rollup tables and moves the watermark in one transaction. The watermark
+// row is locked first, so concurrent rollups from several instances run one
+// after another and never count an event twice.
+func (db *PostgresQLDatabase) RollupClicks(ctx context.Context, until time.Time) (count int, err error) {
+	tx, err := db.driver.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			if RBError := tx.Rollback(); RBError != nil {
+				log.Printf("ошибка при rollback: %v", RBError)
+			}
+		}
+	}()
+
+	var watermark sql.NullTime
+	if err = tx.QueryRowContext(ctx, `SELECT watermark FROM click_rollup_watermark FOR UPDATE`).Scan(&watermark); err != nil {
+		return 0, err
+	}
+	if watermark.Valid && !until.After(watermark.Time) {
+		return 0, tx.Commit()
+	}
+
+	where := `clicked_at < $2 AND ($1::timestamptz IS NULL OR clicked_at >= $1)`
+	if err = tx.QueryRowContext(ctx, `SELECT count(*) FROM clicks WHERE `+where, watermark, until).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	if count > 0 {
+		for granularity, table := range rollupTables {
+			query := fmt.Sprintf(`
+                INSERT INTO %[1]s (shorten, bucket_start, clicks)
+                SELECT shorten, date_trunc('%[2]s', clicked_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC', count(*)
+                FROM clicks WHERE %[3]s
+                GROUP BY 1, 2
+                ON CONFLICT (shorten, bucket_start) DO UPDATE SET clicks = %[1]s.clicks + EXCLUDED.clicks
+            `, table, granularity, where)
+			if _, err = tx.ExecContext(ctx, query, watermark, until); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE click_rollup_watermark SET watermark = $1`, until); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// PruneClicks deletes the rolled up click events before the given moment
+// and returns their number.
+func (db *PostgresQLDatabase) PruneClicks(ctx context.Context, before time.Time) (int, error) {
+	query := `
+        DELETE FROM clicks
+        WHERE clicked_at < LEAST($1, (SELECT COALESCE(watermark, '-infinity') FROM click_rollup_watermark))
+    `
+	result, err := db.driver.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	pruned, err := result.RowsAffected()
+	return int(pruned), err
+}
+
+// ClickWatermark returns the moment the click events are rolled up to.
+func (db *PostgresQLDatabase) ClickWatermark(ctx context.Context) (time.Time, error) {
+	var watermark sql.NullTime
+	err := db.driver.QueryRowContext(ctx, `SELECT watermark FROM click_rollup_watermark`).Scan(&watermark)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return watermark.Time, err
+}
+
+// GetClickRollups returns the rollups of the link with starts in [from, to).
+func (db *PostgresQLDatabase) GetClickRollups(ctx context.Context, hash string, granularity models.RollupGranularity, from time.Time, to time.Time) (buckets models.StatsBucketList, err error) {
+	table, ok := rollupTables[granularity]
+	if !ok {
+		return nil, fmt.Errorf("неизвестная гранулярность сводки: %s", granularity)
+	}
+
+	rows, err := db.driver.QueryContext(ctx, fmt.Sprintf(`
+        SELECT bucket_start, clicks
+        FROM %s WHERE shorten = $1 AND bucket_start >= $2 AND bucket_start < $3
+        ORDER BY bucket_start
+    `, table), hash, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if CErr := rows.Close(); CErr != nil && err == nil {
+			err = CErr
+		}
+	}()
+
+	for rows.Next() {
+		var bucket models.StatsBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Clicks); err != nil {
+			return nil, err
+		}
+		bucket.Start = bucket.Start.UTC()
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
+
+// AddClick counts a redirect of the link against its MaxClicks in a single
+// conditional update, so concurrent redirects never exceed the limit.
+// Returns ErrNotFound if the hash does not exist and ErrLinkExpired
+// if the link has no clicks left.
+func (db *PostgresQLDatabase) AddClick(ctx context.Context, hash string) error {
+	query := `
+        UPDATE shortener
+        SET clicks = clicks + 1,
+            is_expired = (max_clicks > 0 AND clicks + 1 >= max_clicks)
+        WHERE shorten = $1 AND NOT is_expired AND (max_clicks = 0 OR clicks < max_clicks)
+    `
+	result, err := db.driver.ExecContext(ctx, query, hash)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = db.driver.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM shortener WHERE shorten = $1)", hash).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return customErrors.ErrNotFound
+	}
+	return customErrors.ErrLinkExpired
+}
+
+// ExpireLinks marks links whose expires_at or active_until is not after now
+// as expired and returns their hashes.
+func (db *PostgresQLDatabase) ExpireLinks(ctx context.Context, now time.Time) (hashes []string, err error) {
+	query := `
+        UPDATE shortener SET is_expired = TRUE
+        WHERE NOT is_expired AND NOT is_deleted AND (expires_at <= $1 OR active_until <= $1)
+        RETURNING shorten
+    `
+	rows, err := db.driver.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if CErr := rows.Close(); CErr != nil && err == nil {
+			err = CErr
+		}
+	}()
+
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// AllocateRange reserves size IDs for short codes in the id_ranges table.
+// The row is updated atomically, so ranges of different instances never overlap.
+func (db *PostgresQLDatabase) AllocateRange(ctx context.Context, size int) (uint64, error) {
+	query := `
+        INSERT INTO id_ranges (name, next_id)
+        VALUES ($1, $2)
+        ON CONFLICT (name) DO UPDATE
+        SET next_id = id_ranges.next_id + EXCLUDED.next_id
+        RETURNING next_id
+    `
+	var next uint64
+	if err := db.driver.QueryRowContext(ctx, query, shortenRangeName, size).Scan(&next); err != nil {
+		return 0, err
+	}
+	return next - uint64(size), nil
+}
+
+// ListHashesAfter calls fn for every hash with an ID greater than after,
+// including deleted links, in the order of IDs and returns the greatest ID listed.
+func (db *PostgresQLDatabase) ListHashesAfter(ctx context.Context, after int, fn func(hash string) error) (last int, err error) {
+	rows, err := db.driver.QueryContext(ctx, "SELECT id, shorten FROM shortener WHERE id > $1 ORDER BY id", after)
+	if err != nil {
+		return after, err
+	}
+	defer func() {
+		if CErr := rows.Close(); CErr != nil && err == nil {
+			err = CErr
+		}
+	}()
+
+	last = after
+	for rows.Next() {
+		var id int
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return last, err
+		}
+		if err := fn(hash); err != nil {
+			return last, err
+		}
+		last = id
+	}
+	return last, rows.Err()
+}
+
 // Close closes the underlying PostgreSQL connection.
 func (db *PostgresQLDatabase) Close() error {
 	return db.driver.Close()
@@ -179,7 +664,7 @@ func (db *PostgresQLDatabase) GetUserFullLinks(ctx context.Context, userID strin
 		return nil, nil
 	}
 
-	query := `SELECT id, original, shorten, created_at FROM shortener WHERE user_id = $1`
+	query := `SELECT id, original, shorten, created_at, redirect_code, cache_max_age, targeting FROM shortener WHERE user_id = $1`
 
 	rows, err := db.driver.QueryContext(ctx, query, userID)
 	if err != nil {
@@ -191,7 +676,7 @@ func (db *PostgresQLDatabase) GetUserFullLinks(ctx context.Context, userID strin
 
 	for rows.Next() {
 		var row models.DBShortenRow
-		err := rows.Scan(&row.ID, &row.URL, &row.Hash, &row.Time)
+		err := rows.Scan(&row.ID, &row.URL, &row.Hash, &row.Time, &row.RedirectCode, &row.CacheMaxAge, targetingColumn{&row.Targeting})
 		if err != nil {
 			return nil, err
 		}
@@ -218,3 +703,45 @@ func (db *PostgresQLDatabase) RemoveUserLinks(ctx context.Context, userID string
 	_, err := db.driver.ExecContext(ctx, query, userID, pq.Array(ids))
 	return err
 }
+
+// targetingValue encodes the targeting rules for the JSONB column.
+// The text is passed as a string, since lib/pq sends []byte as bytea.
+func targetingValue(rules models.TargetingRuleList) (string, error) {
+	if len(rules) == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal(rules)
+	return string(data), err
+}
+
+// targetingColumn scans the JSONB column of targeting rules,
+// an empty list is scanned as nil.
+type targetingColumn struct {
+	rules *models.TargetingRuleList
+}
+
+// Scan implements sql.Scanner.
+func (c targetingColumn) Scan(src any) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*c.rules = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("неожиданный тип правил таргетинга: %T", src)
+	}
+
+	var rules models.TargetingRuleList
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		rules = nil
+	}
+	*c.rules = rules
+	return nil
+}
@@ -0,0 +1,99 @@
+package drivers
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+// newTestPostgres connects to the database from TEST_DATABASE_DSN and runs
+// the migrations, the test is skipped if the variable is not set.
+func newTestPostgres(t *testing.T) *PostgresQLDatabase {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN не задан, тест PostgreSQL пропущен")
+	}
+
+	db, err := NewPQLDatabase(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	// Миграции ищутся относительно корня модуля
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir("../../.."))
+	defer func() { require.NoError(t, os.Chdir(wd)) }()
+	require.NoError(t, db.RunMigrations())
+	return db
+}
+
+// removeTestLinks deletes the links with the hashes now and after the test.
+func removeTestLinks(t *testing.T, db *PostgresQLDatabase, hashes ...string) {
+	remove := func() {
+		_, err := db.driver.Exec("DELETE FROM shortener WHERE shorten = ANY($1)", pq.Array(hashes))
+		require.NoError(t, err)
+	}
+	remove()
+	t.Cleanup(remove)
+}
+
+func TestPostgresQLDatabase_GetFullLinkExactMatch(t *testing.T) {
+	db := newTestPostgres(t)
+	removeTestLinks(t, db, "pg_abc", "pg_a_c", "pg_a%")
+	ctx := context.Background()
+
+	_, err := db.AddLink(ctx, "https://ya.ru/pg-exact-abc", "pg_abc", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	// _ и % в алиасе не должны работать как шаблоны
+	for _, hash := range []string{"pg_a_c", "pg_a%"} {
+		_, err = db.GetFullLink(ctx, hash)
+		require.ErrorIs(t, err, customErrors.ErrNotFound, "алиас %s не должен находить чужую ссылку", hash)
+	}
+
+	_, err = db.AddLink(ctx, "https://ya.ru/pg-exact-a_c", "pg_a_c", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	row, err := db.GetFullLink(ctx, "pg_a_c")
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru/pg-exact-a_c", row.URL)
+	row, err = db.GetFullLink(ctx, "pg_abc")
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru/pg-exact-abc", row.URL)
+}
+
+func TestPostgresQLDatabase_ListHashesAfter(t *testing.T) {
+	db := newTestPostgres(t)
+	removeTestLinks(t, db, "pg_tail1", "pg_tail2")
+	ctx := context.Background()
+
+	_, err := db.AddLink(ctx, "https://ya.ru/pg-tail-1", "pg_tail1", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	var first int
+	require.NoError(t, db.driver.QueryRow("SELECT id FROM shortener WHERE shorten = $1", "pg_tail1").Scan(&first))
+	_, err = db.AddLink(ctx, "https://ya.ru/pg-tail-2", "pg_tail2", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	var hashes []string
+	last, err := db.ListHashesAfter(ctx, first-1, func(hash string) error {
+		hashes = append(hashes, hash)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"pg_tail1", "pg_tail2"}, hashes[:2], "хэши должны идти в порядке ID")
+	require.Greater(t, last, first)
+
+	hashes = nil
+	again, err := db.ListHashesAfter(ctx, last, func(hash string) error {
+		hashes = append(hashes, hash)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Empty(t, hashes)
+	require.Equal(t, last, again, "без новых хэшей курсор не должен меняться")
+}
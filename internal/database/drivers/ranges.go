@@ -0,0 +1,100 @@
+package drivers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// localRange hands out ID ranges for storages owned by a single process.
+// Ranges start after both the largest ID stored so far and the high-water
+// mark of the ranges handed out before, which is persisted to a file for
+// durable storages. IDs burned by failed writes or duplicates are thus
+// never handed out again after a restart.
+type localRange struct {
+	mutex sync.Mutex
+	next  uint64
+	path  string
+}
+
+// rangeMark is the persisted high-water mark of localRange.
+type rangeMark struct {
+	NextID uint64 `json:"next_id"`
+}
+
+// load makes the range persist its high-water mark to the file at path
+// and restores the mark from it. A missing file means no range has been
+// handed out yet.
+func (r *localRange) load(path string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var mark rangeMark
+	if err := json.Unmarshal(data, &mark); err != nil {
+		return fmt.Errorf("повреждён файл диапазонов ID: %w", err)
+	}
+	r.next = mark.NextID
+	return nil
+}
+
+// allocate reserves size IDs following both the previous range and lastID.
+// The new high-water mark is persisted before the range is returned.
+func (r *localRange) allocate(lastID int, size int) (uint64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	start := r.next
+	if start <= uint64(lastID) {
+		start = uint64(lastID) + 1
+	}
+	next := start + uint64(size)
+
+	if r.path != "" {
+		data, err := json.Marshal(rangeMark{NextID: next})
+		if err != nil {
+			return 0, err
+		}
+		if err := writeFileAtomic(r.path, data); err != nil {
+			return 0, fmt.Errorf("не удалось сохранить диапазон ID: %w", err)
+		}
+	}
+
+	r.next = next
+	return start, nil
+}
+
+// writeFileAtomic replaces the file at path with data: it is written to
+// a temporary file, fsynced and renamed over the old one, so a crash
+// leaves either the old or the new content.
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		return errors.Join(err, file.Close(), os.Remove(tmpPath))
+	}
+	if err := file.Sync(); err != nil {
+		return errors.Join(err, file.Close(), os.Remove(tmpPath))
+	}
+	if err := file.Close(); err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+	return syncDir(filepath.Dir(path))
+}
@@ -0,0 +1,103 @@
+package drivers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/thxhix/shortener/internal/models"
+	"io"
+	"log"
+	"os"
+)
+
+// ErrStorageLocked is returned when the storage is already opened by another process.
+var ErrStorageLocked = errors.New("хранилище уже используется другим процессом")
+
+// RecoveryReport describes what the recovery pass found in a JSON-lines log.
+type RecoveryReport struct {
+	// Rows is the number of records replayed successfully.
+	Rows int
+
+	// CorruptLines is the number of lines that could not be decoded,
+	// including a torn trailing record.
+	CorruptLines int
+
+	// TruncatedBytes is the size of the torn trailing record cut off the file.
+	TruncatedBytes int64
+}
+
+// acquireLock opens the lock file next to the storage path
+// and takes an exclusive lock on it. A separate lock file is used,
+// so that the lock survives the storage file being replaced by rename.
+func acquireLock(path string) (*os.File, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(lock); err != nil {
+		if errors.Is(err, ErrStorageLocked) {
+			err = fmt.Errorf("%w: %s", ErrStorageLocked, path)
+		}
+		return nil, errors.Join(err, lock.Close())
+	}
+	return lock, nil
+}
+
+// releaseLock releases and closes the lock file taken by acquireLock.
+func releaseLock(lock *os.File) error {
+	return errors.Join(unlockFile(lock), lock.Close())
+}
+
+// replayLog reads the JSON-lines log from the beginning and passes every
+// decoded record to apply. Lines that cannot be decoded are skipped and counted.
+// A trailing record without a newline is a write torn by a crash:
+// it is truncated, so that new records are appended after the last good one.
+func replayLog(file *os.File, apply func(row models.DBShortenRow)) (RecoveryReport, error) {
+	var report RecoveryReport
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return report, err
+	}
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				report.CorruptLines++
+				report.TruncatedBytes = int64(len(line))
+				if err := file.Truncate(offset); err != nil {
+					return report, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		offset += int64(len(line))
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var row models.DBShortenRow
+		if err := json.Unmarshal(line, &row); err != nil {
+			report.CorruptLines++
+			continue
+		}
+		apply(row)
+		report.Rows++
+	}
+
+	if report.CorruptLines > 0 {
+		log.Printf("восстановление %s: прочитано записей %d, повреждённых строк %d, обрезано байт %d",
+			file.Name(), report.Rows, report.CorruptLines, report.TruncatedBytes)
+	}
+	return report, nil
+}
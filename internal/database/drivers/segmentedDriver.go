@@ -0,0 +1,426 @@
+package drivers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/thxhix/shortener/internal/models"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	manifestName   = "MANIFEST"
+	clicksName     = "clicks.log"
+	rangesName     = "ranges.json"
+	segmentPrefix  = "segment-"
+	segmentSuffix  = ".jsonl"
+	minMergeInputs = 2
+
+	// DefaultSegmentSize is the size of a segment that triggers rotation
+	// when FileOptions.SegmentSize is not set.
+	DefaultSegmentSize = 64 << 20
+)
+
+// segmentManifest lists the segments of the storage in replay order.
+type segmentManifest struct {
+	Segments []string `json:"segments"`
+	NextID   int      `json:"next_id"`
+}
+
+// SegmentedFileDatabase implements the Database interface using a directory
+// of numbered JSON-lines segment files. Rows are appended to the active
+// (last) segment, which is rotated once it grows over the segment size.
+// The MANIFEST file lists the segments in replay order and is replaced
+// atomically, so a crash never leaves a half-applied rotation or merge.
+//
+// Sealed segments are merged in the background into a single segment
+// holding only the latest record of every link. Deleted links are kept
+// as tombstones, so they still answer as deleted and their hashes
+// are not reused.
+// Lookups are served from the in-memory index, which is not affected
+// by merges, so readers always see a consistent view.
+//
+// Click events of redirects are kept in a separate JSON-lines file
+// in the same directory, which is not affected by rotations and merges,
+// and so is the high-water mark of allocated ID ranges.
+type SegmentedFileDatabase struct {
+	logStore
+
+	dir         string
+	lock        *os.File
+	recovery    RecoveryReport
+	segmentSize int64
+
+	// segmentMutex guards the manifest and the active segment,
+	// mergeMutex serializes merges.
+	segmentMutex sync.Mutex
+	mergeMutex   sync.Mutex
+	manifest     segmentManifest
+	active       *os.File
+	activeSize   int64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewSegmentedFileDatabase opens the segmented storage in the given directory,
+// creating it if needed, and replays all segments listed in the manifest.
+// Returns ErrStorageLocked if the directory is used by another process.
+func NewSegmentedFileDatabase(dir string, opts FileOptions) (*SegmentedFileDatabase, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+
+	lock, err := acquireLock(filepath.Join(dir, "db"))
+	if err != nil {
+		return nil, err
+	}
+
+	db := &SegmentedFileDatabase{
+		dir:         dir,
+		lock:        lock,
+		segmentSize: opts.SegmentSize,
+		stop:        make(chan struct{}),
+	}
+	if db.segmentSize <= 0 {
+		db.segmentSize = DefaultSegmentSize
+	}
+	db.index = newLinkIndex()
+
+	if err := db.open(); err != nil {
+		if db.active != nil {
+			err = errors.Join(err, db.active.Close())
+		}
+		return nil, errors.Join(err, releaseLock(lock))
+	}
+
+	db.clicks, err = openClickFile(filepath.Join(dir, clicksName))
+	if err != nil {
+		return nil, errors.Join(err, db.active.Close(), releaseLock(lock))
+	}
+
+	if err := db.ranges.load(filepath.Join(dir, rangesName)); err != nil {
+		return nil, errors.Join(err, db.active.Close(), db.clicks.Close(), releaseLock(lock))
+	}
+
+	db.start(db, opts)
+
+	if opts.MergeInterval > 0 {
+		db.wg.Add(1)
+		go db.mergeLoop(opts.MergeInterval)
+	}
+
+	return db, nil
+}
+
+// open reads the manifest, removes segments left over by an interrupted
+// merge or rotation, replays the listed segments and opens the active one.
+func (db *SegmentedFileDatabase) open() error {
+	manifest, err := readManifest(db.dir)
+	if err != nil {
+		return err
+	}
+	db.manifest = manifest
+
+	if err := db.removeStraySegments(); err != nil {
+		return err
+	}
+
+	if len(db.manifest.Segments) == 0 {
+		name := db.nextSegmentName()
+		db.manifest.Segments = append(db.manifest.Segments, name)
+		if err := writeManifest(db.dir, db.manifest); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range db.manifest.Segments {
+		file, err := openLogFile(db.segmentPath(name))
+		if err != nil {
+			return err
+		}
+		report, err := replayLog(file, func(row models.DBShortenRow) {
+			db.index.put(row)
+		})
+		db.recovery.Rows += report.Rows
+		db.recovery.CorruptLines += report.CorruptLines
+		db.recovery.TruncatedBytes += report.TruncatedBytes
+		if err != nil {
+			return errors.Join(err, file.Close())
+		}
+
+		if name != db.manifest.Segments[len(db.manifest.Segments)-1] {
+			if err := file.Close(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		db.active = file
+		db.activeSize, err = file.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeStraySegments deletes segment files that are not in the manifest.
+func (db *SegmentedFileDatabase) removeStraySegments() error {
+	listed := make(map[string]struct{}, len(db.manifest.Segments))
+	for _, name := range db.manifest.Segments {
+		listed[name] = struct{}{}
+	}
+
+	entries, err := os.ReadDir(db.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		if _, ok := listed[name]; ok {
+			continue
+		}
+		log.Printf("удаляю сегмент %s, которого нет в манифесте", name)
+		if err := os.Remove(db.segmentPath(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops background merges, writes the queued rows
+// and closes the active segment.
+func (db *SegmentedFileDatabase) Close() error {
+	close(db.stop)
+	db.wg.Wait()
+
+	db.shutdown()
+
+	db.segmentMutex.Lock()
+	defer db.segmentMutex.Unlock()
+	return errors.Join(db.active.Close(), db.clicks.Close(), releaseLock(db.lock))
+}
+
+// Recovery returns the summed report of the recovery pass over all segments.
+func (db *SegmentedFileDatabase) Recovery() RecoveryReport {
+	return db.recovery
+}
+
+// Segments returns the names of the segments in replay order.
+func (db *SegmentedFileDatabase) Segments() []string {
+	db.segmentMutex.Lock()
+	defer db.segmentMutex.Unlock()
+
+	return append([]string(nil), db.manifest.Segments...)
+}
+
+// appendRows writes rows at the end of the active segment and fsyncs it
+// if sync is set, then rotates the segment if it grew over the segment size.
+// It is called by the group committer only.
+func (db *SegmentedFileDatabase) appendRows(rows models.DBShortenRowList, sync bool) error {
+	db.segmentMutex.Lock()
+	defer db.segmentMutex.Unlock()
+
+	err := writeRows(db.active, rows, sync)
+	if err != nil {
+		return errors.Join(err, db.active.Truncate(db.activeSize))
+	}
+
+	db.activeSize, err = db.active.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	if db.activeSize >= db.segmentSize {
+		// Строки уже записаны, поэтому ошибка ротации не должна их откатывать
+		if err := db.rotate(); err != nil {
+			log.Printf("ошибка ротации сегмента: %v", err)
+		}
+	}
+	return nil
+}
+
+// rotate seals the active segment and starts a new one.
+// The caller must hold segmentMutex.
+func (db *SegmentedFileDatabase) rotate() error {
+	manifest := db.manifest
+	name := db.nextSegmentName()
+	manifest.NextID = db.manifest.NextID
+	manifest.Segments = append(append([]string(nil), db.manifest.Segments...), name)
+
+	file, err := openLogFile(db.segmentPath(name))
+	if err != nil {
+		return err
+	}
+	if err := writeManifest(db.dir, manifest); err != nil {
+		return errors.Join(err, file.Close(), os.Remove(db.segmentPath(name)))
+	}
+
+	old := db.active
+	db.manifest = manifest
+	db.active = file
+	db.activeSize = 0
+	return old.Close()
+}
+
+// Merge rewrites all sealed segments into a single one that holds only
+// the latest record of every link, tombstones of deleted links included.
+// The new segment replaces the merged ones in the manifest atomically.
+// Writes to the active segment and lookups are not blocked while
+// the merge runs.
+func (db *SegmentedFileDatabase) Merge() error {
+	db.mergeMutex.Lock()
+	defer db.mergeMutex.Unlock()
+
+	db.segmentMutex.Lock()
+	sealed := append([]string(nil), db.manifest.Segments[:len(db.manifest.Segments)-1]...)
+	db.segmentMutex.Unlock()
+
+	if len(sealed) < minMergeInputs {
+		return nil
+	}
+
+	// Запечатанные сегменты больше не меняются, их можно читать без блокировок
+	index := newLinkIndex()
+	for _, name := range sealed {
+		if err := readSegment(db.segmentPath(name), index); err != nil {
+			return err
+		}
+	}
+
+	rows := make(models.DBShortenRowList, 0, index.len())
+	for _, row := range index.rows {
+		rows = append(rows, row)
+	}
+	sortByID(rows)
+
+	db.segmentMutex.Lock()
+	name := db.nextSegmentName()
+	db.segmentMutex.Unlock()
+
+	if err := writeLogFile(db.segmentPath(name), rows); err != nil {
+		return errors.Join(err, os.Remove(db.segmentPath(name)))
+	}
+
+	db.segmentMutex.Lock()
+	manifest := db.manifest
+	manifest.Segments = append([]string{name}, db.manifest.Segments[len(sealed):]...)
+	if err := writeManifest(db.dir, manifest); err != nil {
+		db.segmentMutex.Unlock()
+		return errors.Join(err, os.Remove(db.segmentPath(name)))
+	}
+	db.manifest = manifest
+	db.segmentMutex.Unlock()
+
+	for _, old := range sealed {
+		if err := os.Remove(db.segmentPath(old)); err != nil {
+			log.Printf("не удалось удалить слитый сегмент %s: %v", old, err)
+		}
+	}
+	return nil
+}
+
+func (db *SegmentedFileDatabase) mergeLoop(interval time.Duration) {
+	defer db.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			if err := db.Merge(); err != nil {
+				log.Printf("ошибка слияния сегментов: %v", err)
+			}
+		}
+	}
+}
+
+// nextSegmentName reserves the name of a new segment.
+// The caller must hold segmentMutex.
+func (db *SegmentedFileDatabase) nextSegmentName() string {
+	if db.manifest.NextID == 0 {
+		db.manifest.NextID = 1
+	}
+	name := fmt.Sprintf("%s%06d%s", segmentPrefix, db.manifest.NextID, segmentSuffix)
+	db.manifest.NextID++
+	return name
+}
+
+func (db *SegmentedFileDatabase) segmentPath(name string) string {
+	return filepath.Join(db.dir, name)
+}
+
+// readSegment replays a sealed segment into the index.
+func readSegment(path string, index *linkIndex) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	_, err = replayLog(file, func(row models.DBShortenRow) {
+		index.put(row)
+	})
+	return errors.Join(err, file.Close())
+}
+
+// readManifest loads the manifest from dir. A missing manifest means
+// an empty storage.
+func readManifest(dir string) (segmentManifest, error) {
+	var manifest segmentManifest
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, err
+	}
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("повреждён манифест сегментов: %w", err)
+	}
+	return manifest, nil
+}
+
+// writeManifest replaces the manifest in dir atomically via rename.
+func writeManifest(dir string, manifest segmentManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, manifestName)
+	tmpPath := path + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		return errors.Join(err, file.Close())
+	}
+	if err := file.Sync(); err != nil {
+		return errors.Join(err, file.Close())
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
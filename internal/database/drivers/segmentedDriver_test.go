@@ -0,0 +1,97 @@
+package drivers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+func TestSegmentedFileDatabase_RotateAndMerge(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "db")
+	opts := FileOptions{SegmentSize: 256}
+	db, err := NewSegmentedFileDatabase(dir, opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		_, err := db.AddLink(ctx, "https://ya.ru/"+strconv.Itoa(i), "h"+strconv.Itoa(i), "user", models.LinkOptions{})
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.RemoveUserLinks(ctx, "user", []string{"h0", "h1"}))
+	require.Greater(t, len(db.Segments()), minMergeInputs, "сегменты должны ротироваться по размеру")
+
+	require.NoError(t, db.Merge())
+	segments := db.Segments()
+	require.LessOrEqual(t, len(segments), 2, "запечатанные сегменты должны слиться в один")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var files int
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == segmentSuffix {
+			files++
+		}
+	}
+	require.Equal(t, len(segments), files, "слитые сегменты должны удаляться")
+
+	_, err = db.GetFullLink(ctx, "h5")
+	require.NoError(t, err)
+	row, err := db.GetFullLink(ctx, "h0")
+	require.NoError(t, err)
+	require.True(t, row.IsDeleted, "слияние не должно забывать удалённые ссылки")
+	require.NoError(t, db.Close())
+
+	db, err = NewSegmentedFileDatabase(dir, opts)
+	require.NoError(t, err)
+	defer db.Close()
+
+	links, err := db.GetUserFullLinks(ctx, "user")
+	require.NoError(t, err)
+	require.Len(t, links, 20)
+
+	row, err = db.GetFullLink(ctx, "h0")
+	require.NoError(t, err, "удалённая ссылка должна остаться надгробием после слияния")
+	require.True(t, row.IsDeleted)
+	_, err = db.AddLink(ctx, "https://ya.ru/new", "h1", "user", models.LinkOptions{})
+	require.ErrorIs(t, err, customErrors.ErrHashCollision, "хэш удалённой ссылки не должен переиспользоваться")
+
+	hash, err := db.AddLink(ctx, "https://ya.ru/5", "other", "user", models.LinkOptions{})
+	require.ErrorIs(t, err, customErrors.ErrDuplicate)
+	require.Equal(t, "h5", hash)
+}
+
+func TestSegmentedFileDatabase_StraySegment(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewSegmentedFileDatabase(dir, FileOptions{})
+	require.NoError(t, err)
+	_, err = db.AddLink(context.Background(), "https://ya.ru", "aaa", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// Сегмент от прерванного слияния, которого нет в манифесте
+	stray := filepath.Join(dir, segmentPrefix+"000099"+segmentSuffix)
+	require.NoError(t, os.WriteFile(stray, []byte(`{"id":9,"hash":"zzz","url":"https://google.com"}`+"\n"), 0666))
+
+	db, err = NewSegmentedFileDatabase(dir, FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = os.Stat(stray)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = db.GetFullLink(context.Background(), "zzz")
+	require.ErrorIs(t, err, customErrors.ErrNotFound)
+}
+
+func TestSegmentedFileDatabase_OneTimeLink(t *testing.T) {
+	db, err := NewSegmentedFileDatabase(filepath.Join(t.TempDir(), "db"), FileOptions{})
+	require.NoError(t, err)
+	defer db.Close()
+
+	requireSingleClick(t, db)
+}
@@ -0,0 +1,570 @@
+package drivers
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EvictionPolicy defines which rows ShardedMemoryDatabase drops
+// once a shard reaches its capacity.
+type EvictionPolicy string
+
+const (
+	// EvictLRU drops the least recently used row.
+	EvictLRU EvictionPolicy = "lru"
+
+	// EvictTTL drops the oldest row, and additionally expires
+	// rows that were not written for longer than the TTL.
+	EvictTTL EvictionPolicy = "ttl"
+)
+
+// DefaultShards is the number of shards used when ShardedMemoryOptions.Shards is not set.
+const DefaultShards = 32
+
+// ParseEvictionPolicy converts a config value into EvictionPolicy.
+// An empty value means EvictLRU.
+func ParseEvictionPolicy(value string) (EvictionPolicy, error) {
+	switch policy := EvictionPolicy(value); policy {
+	case "":
+		return EvictLRU, nil
+	case EvictLRU, EvictTTL:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("неизвестная политика вытеснения: %q", value)
+	}
+}
+
+// ShardedMemoryOptions holds settings of ShardedMemoryDatabase.
+type ShardedMemoryOptions struct {
+	// Shards is the number of shards, DefaultShards if not set.
+	Shards int
+
+	// MaxEntries limits the total number of stored rows, zero means unbounded.
+	// The limit is split evenly between shards.
+	MaxEntries int
+
+	// Policy defines the eviction policy, EvictLRU by default.
+	Policy EvictionPolicy
+
+	// TTL is the lifetime of a row for EvictTTL, zero means rows never expire.
+	TTL time.Duration
+}
+
+// shardEntry is a stored row together with its last write time.
+type shardEntry struct {
+	row     models.DBShortenRow
+	written time.Time
+
+	// clicks are the click events of the row, evicted together with it.
+	clicks models.ClickEventList
+}
+
+// memoryShard holds the rows whose hashes map to it, ordered from
+// the most recently used (or written, for EvictTTL) to the least one.
+type memoryShard struct {
+	mutex    sync.Mutex
+	rows     map[string]*list.Element
+	order    *list.List
+	capacity int
+}
+
+// ShardedMemoryDatabase implements the Database interface using
+// a set of in-memory shards, where the hash picks the shard. Lookups by hash,
+// which is the redirect hot path, only lock a single shard.
+//
+// The number of rows can be bounded, so the driver can be used
+// as a cache: rows are evicted by the configured policy.
+// Secondary indexes by original URL and by user are global and are
+// locked only by writers and user listings. Data is not persisted.
+// Click events of redirects are kept with their rows, so they are evicted
+// together, and events of links that are not stored are dropped.
+type ShardedMemoryDatabase struct {
+	shards []*memoryShard
+	policy EvictionPolicy
+	ttl    time.Duration
+	lastID atomic.Int64
+	ranges localRange
+
+	// indexMutex guards the secondary indexes. Writers lock it before
+	// any shard, which keeps the lock order fixed.
+	indexMutex sync.RWMutex
+	byOriginal map[string]string
+	byUser     map[string]map[string]struct{}
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewShardedMemoryDatabase creates a new ShardedMemoryDatabase.
+// For EvictTTL with a positive TTL a janitor goroutine drops expired rows.
+func NewShardedMemoryDatabase(opts ShardedMemoryOptions) (*ShardedMemoryDatabase, error) {
+	if opts.Shards <= 0 {
+		opts.Shards = DefaultShards
+	}
+	if opts.Policy == "" {
+		opts.Policy = EvictLRU
+	}
+	if opts.MaxEntries > 0 && opts.MaxEntries < opts.Shards {
+		opts.Shards = opts.MaxEntries
+	}
+
+	db := &ShardedMemoryDatabase{
+		shards:     make([]*memoryShard, opts.Shards),
+		policy:     opts.Policy,
+		ttl:        opts.TTL,
+		byOriginal: make(map[string]string),
+		byUser:     make(map[string]map[string]struct{}),
+		stop:       make(chan struct{}),
+	}
+
+	capacity := 0
+	if opts.MaxEntries > 0 {
+		capacity = opts.MaxEntries / opts.Shards
+	}
+	for i := range db.shards {
+		db.shards[i] = &memoryShard{
+			rows:     make(map[string]*list.Element),
+			order:    list.New(),
+			capacity: capacity,
+		}
+	}
+
+	if db.policy == EvictTTL && db.ttl > 0 {
+		db.wg.Add(1)
+		go db.expireLoop()
+	}
+
+	return db, nil
+}
+
+// shard picks the shard of the hash by its FNV-1a sum.
+func (db *ShardedMemoryDatabase) shard(hash string) *memoryShard {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	sum := uint32(offset32)
+	for i := 0; i < len(hash); i++ {
+		sum ^= uint32(hash[i])
+		sum *= prime32
+	}
+	return db.shards[sum%uint32(len(db.shards))]
+}
+
+// ListHashes calls fn for every stored hash that has not been evicted,
+// including deleted links. Shards are locked one at a time.
+func (db *ShardedMemoryDatabase) ListHashes(ctx context.Context, fn func(hash string) error) error {
+	for _, shard := range db.shards {
+		shard.mutex.Lock()
+		hashes := make([]string, 0, len(shard.rows))
+		for hash := range shard.rows {
+			hashes = append(hashes, hash)
+		}
+		shard.mutex.Unlock()
+
+		if err := listHashes(ctx, hashes, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllocateRange reserves size IDs for short codes in this process.
+func (db *ShardedMemoryDatabase) AllocateRange(ctx context.Context, size int) (uint64, error) {
+	return db.ranges.allocate(int(db.lastID.Load()), size)
+}
+
+// RunMigrations is a no-op for ShardedMemoryDatabase.
+// It always returns nil.
+func (db *ShardedMemoryDatabase) RunMigrations() error {
+	return nil
+}
+
+// AddLink stores a single shortened link.
+// If the original URL already exists, it returns the existing shorten hash
+// and ErrDuplicate. Returns ErrHashCollision if the hash already exists.
+func (db *ShardedMemoryDatabase) AddLink(ctx context.Context, original string, shorten string, userID string, opts models.LinkOptions) (string, error) {
+	db.indexMutex.Lock()
+	defer db.indexMutex.Unlock()
+
+	if existing, ok := db.byOriginal[original]; ok {
+		return existing, customErrors.ErrDuplicate
+	}
+
+	shard := db.shard(shorten)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	if _, exists := shard.rows[shorten]; exists {
+		return "", &customErrors.HashCollisionError{Hash: shorten}
+	}
+
+	db.insert(shard, models.DBShortenRow{
+		ID:          int(db.lastID.Add(1)),
+		Hash:        shorten,
+		URL:         original,
+		UserID:      userID,
+		Time:        time.Now(),
+		LinkOptions: opts,
+	})
+	return shorten, nil
+}
+
+// AddLinks stores multiple shortened links.
+// The batch is validated before anything is stored, so on ErrDuplicate
+// (an existing original URL) or ErrHashCollision (an existing hash)
+// the storage is left untouched.
+func (db *ShardedMemoryDatabase) AddLinks(ctx context.Context, list models.DBShortenRowList, userID string) error {
+	db.indexMutex.Lock()
+	defer db.indexMutex.Unlock()
+
+	err := validateBatch(list,
+		func(original string) bool {
+			_, exists := db.byOriginal[original]
+			return exists
+		},
+		db.exists,
+	)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, link := range list {
+		shard := db.shard(link.Hash)
+		shard.mutex.Lock()
+		db.insert(shard, models.DBShortenRow{
+			ID:          int(db.lastID.Add(1)),
+			Hash:        link.Hash,
+			URL:         link.URL,
+			UserID:      userID,
+			Time:        now,
+			LinkOptions: link.LinkOptions,
+		})
+		shard.mutex.Unlock()
+	}
+
+	return nil
+}
+
+func (db *ShardedMemoryDatabase) exists(hash string) bool {
+	shard := db.shard(hash)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	_, ok := shard.rows[hash]
+	return ok
+}
+
+// insert stores a new row in the shard, evicting rows over the capacity.
+// The caller must hold indexMutex and the shard lock.
+func (db *ShardedMemoryDatabase) insert(shard *memoryShard, row models.DBShortenRow) {
+	for shard.capacity > 0 && shard.order.Len() >= shard.capacity {
+		db.evict(shard, shard.order.Back())
+	}
+
+	shard.rows[row.Hash] = shard.order.PushFront(&shardEntry{row: row, written: time.Now()})
+	db.byOriginal[row.URL] = row.Hash
+	if row.UserID != "" {
+		if db.byUser[row.UserID] == nil {
+			db.byUser[row.UserID] = make(map[string]struct{})
+		}
+		db.byUser[row.UserID][row.Hash] = struct{}{}
+	}
+}
+
+// evict drops the element from the shard and the secondary indexes.
+// The caller must hold indexMutex and the shard lock.
+func (db *ShardedMemoryDatabase) evict(shard *memoryShard, element *list.Element) {
+	row := element.Value.(*shardEntry).row
+
+	shard.order.Remove(element)
+	delete(shard.rows, row.Hash)
+	if db.byOriginal[row.URL] == row.Hash {
+		delete(db.byOriginal, row.URL)
+	}
+	if hashes, ok := db.byUser[row.UserID]; ok {
+		delete(hashes, row.Hash)
+		if len(hashes) == 0 {
+			delete(db.byUser, row.UserID)
+		}
+	}
+}
+
+// expired reports whether the entry outlived the TTL.
+func (db *ShardedMemoryDatabase) expired(entry *shardEntry, now time.Time) bool {
+	return db.policy == EvictTTL && db.ttl > 0 && now.Sub(entry.written) > db.ttl
+}
+
+// GetFullLink retrieves the full row by hash.
+// Returns ErrNotFound if the hash does not exist, was evicted or expired.
+func (db *ShardedMemoryDatabase) GetFullLink(ctx context.Context, hash string) (models.DBShortenRow, error) {
+	shard := db.shard(hash)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	element, ok := shard.rows[hash]
+	if !ok {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+
+	entry := element.Value.(*shardEntry)
+	if db.expired(entry, time.Now()) {
+		// Удалит janitor: здесь нет блокировки вторичных индексов
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	if db.policy == EvictLRU {
+		shard.order.MoveToFront(element)
+	}
+	return entry.row, nil
+}
+
+// GetUserFullLinks retrieves all stored links created by the given user,
+// including the deleted ones, ordered by ID. Returns nil if there are none.
+func (db *ShardedMemoryDatabase) GetUserFullLinks(ctx context.Context, userID string) (models.DBShortenRowList, error) {
+	if userID == "" {
+		return nil, nil
+	}
+
+	db.indexMutex.RLock()
+	defer db.indexMutex.RUnlock()
+
+	var result models.DBShortenRowList
+	now := time.Now()
+	for hash := range db.byUser[userID] {
+		shard := db.shard(hash)
+		shard.mutex.Lock()
+		if element, ok := shard.rows[hash]; ok {
+			entry := element.Value.(*shardEntry)
+			if !db.expired(entry, now) {
+				result = append(result, entry.row)
+			}
+		}
+		shard.mutex.Unlock()
+	}
+
+	sortByID(result)
+	return result, nil
+}
+
+// RemoveUserLinks marks user links as deleted.
+// Links that do not exist or belong to another user are skipped.
+func (db *ShardedMemoryDatabase) RemoveUserLinks(ctx context.Context, userID string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	db.indexMutex.Lock()
+	defer db.indexMutex.Unlock()
+
+	for _, id := range ids {
+		shard := db.shard(id)
+		shard.mutex.Lock()
+		if element, ok := shard.rows[id]; ok {
+			entry := element.Value.(*shardEntry)
+			if entry.row.UserID == userID {
+				entry.row.IsDeleted = true
+			}
+		}
+		shard.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// UpdateLink changes the original URL of the user's link, keeping
+// the previous one in the history of the row.
+func (db *ShardedMemoryDatabase) UpdateLink(ctx context.Context, userID string, hash string, original string) (models.DBShortenRow, error) {
+	db.indexMutex.Lock()
+	defer db.indexMutex.Unlock()
+
+	shard := db.shard(hash)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	element, ok := shard.rows[hash]
+	if !ok {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	entry := element.Value.(*shardEntry)
+	updated, err := editRow(entry.row, userID, original, time.Now())
+	if err != nil || updated.URL == entry.row.URL {
+		return updated, err
+	}
+	if _, exists := db.byOriginal[original]; exists {
+		return models.DBShortenRow{}, customErrors.ErrDuplicate
+	}
+
+	if db.byOriginal[entry.row.URL] == hash {
+		delete(db.byOriginal, entry.row.URL)
+	}
+	db.byOriginal[original] = hash
+	entry.row = updated
+	return updated, nil
+}
+
+// UpdateLinkTargeting replaces the targeting rules of the user's link.
+func (db *ShardedMemoryDatabase) UpdateLinkTargeting(ctx context.Context, userID string, hash string, rules models.TargetingRuleList) (models.DBShortenRow, error) {
+	shard := db.shard(hash)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	element, ok := shard.rows[hash]
+	if !ok {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	entry := element.Value.(*shardEntry)
+	updated, err := retargetRow(entry.row, userID, rules)
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+	entry.row = updated
+	return updated, nil
+}
+
+// GetLinkHistory returns the previous destinations of the link, oldest first.
+// Returns ErrNotFound if the hash does not exist or was evicted.
+func (db *ShardedMemoryDatabase) GetLinkHistory(ctx context.Context, hash string) (models.LinkVersionList, error) {
+	shard := db.shard(hash)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	element, ok := shard.rows[hash]
+	if !ok {
+		return nil, customErrors.ErrNotFound
+	}
+	return append(models.LinkVersionList(nil), element.Value.(*shardEntry).row.History...), nil
+}
+
+// AddClickEvents stores the click events with the rows of their links.
+func (db *ShardedMemoryDatabase) AddClickEvents(ctx context.Context, events models.ClickEventList) error {
+	for _, event := range events {
+		shard := db.shard(event.Hash)
+		shard.mutex.Lock()
+		if element, ok := shard.rows[event.Hash]; ok {
+			entry := element.Value.(*shardEntry)
+			entry.clicks = append(entry.clicks, event)
+		}
+		shard.mutex.Unlock()
+	}
+	return nil
+}
+
+// GetClickEvents returns the click events of the link that happened in [from, to).
+// Events of evicted links are gone.
+func (db *ShardedMemoryDatabase) GetClickEvents(ctx context.Context, hash string, from time.Time, to time.Time) (models.ClickEventList, error) {
+	shard := db.shard(hash)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	element, ok := shard.rows[hash]
+	if !ok {
+		return nil, nil
+	}
+	return eventsBetween(element.Value.(*shardEntry).clicks, from, to), nil
+}
+
+// AddClick counts a redirect of the link against its MaxClicks.
+// Returns ErrNotFound if the hash does not exist or was evicted
+// and ErrLinkExpired if the link has no clicks left.
+func (db *ShardedMemoryDatabase) AddClick(ctx context.Context, hash string) error {
+	shard := db.shard(hash)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	element, ok := shard.rows[hash]
+	if !ok {
+		return customErrors.ErrNotFound
+	}
+	return countClick(&element.Value.(*shardEntry).row)
+}
+
+// ExpireLinks marks links whose ExpiresAt or ActiveUntil is not after now
+// as expired and returns their hashes. Shards are locked one at a time.
+func (db *ShardedMemoryDatabase) ExpireLinks(ctx context.Context, now time.Time) ([]string, error) {
+	var hashes []string
+	for _, shard := range db.shards {
+		shard.mutex.Lock()
+		for hash, element := range shard.rows {
+			entry := element.Value.(*shardEntry)
+			if expiresBy(entry.row, now) {
+				entry.row.IsExpired = true
+				hashes = append(hashes, hash)
+			}
+		}
+		shard.mutex.Unlock()
+	}
+	return hashes, nil
+}
+
+// Len returns the number of stored rows.
+func (db *ShardedMemoryDatabase) Len() int {
+	total := 0
+	for _, shard := range db.shards {
+		shard.mutex.Lock()
+		total += shard.order.Len()
+		shard.mutex.Unlock()
+	}
+	return total
+}
+
+// expireLoop periodically drops rows that outlived the TTL.
+func (db *ShardedMemoryDatabase) expireLoop() {
+	defer db.wg.Done()
+
+	ticker := time.NewTicker(db.ttl / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			db.expire(time.Now())
+		}
+	}
+}
+
+// expire drops expired rows. Rows in a shard are ordered by write time,
+// so the scan stops at the first live row.
+func (db *ShardedMemoryDatabase) expire(now time.Time) {
+	db.indexMutex.Lock()
+	defer db.indexMutex.Unlock()
+
+	for _, shard := range db.shards {
+		shard.mutex.Lock()
+		for element := shard.order.Back(); element != nil; element = shard.order.Back() {
+			if !db.expired(element.Value.(*shardEntry), now) {
+				break
+			}
+			db.evict(shard, element)
+		}
+		shard.mutex.Unlock()
+	}
+}
+
+// Close stops the janitor goroutine.
+func (db *ShardedMemoryDatabase) Close() error {
+	close(db.stop)
+	db.wg.Wait()
+	return nil
+}
+
+// PingConnection always succeeds for ShardedMemoryDatabase.
+// It returns nil to indicate the database is available.
+func (db *ShardedMemoryDatabase) PingConnection() error {
+	return nil
+}
+
+// GetDriver always returns nil for ShardedMemoryDatabase since it has no SQL driver.
+func (db *ShardedMemoryDatabase) GetDriver() *sql.DB {
+	return nil
+}
@@ -0,0 +1,157 @@
+package drivers
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+func TestShardedMemoryDatabase_Semantics(t *testing.T) {
+	db, err := NewShardedMemoryDatabase(ShardedMemoryOptions{Shards: 4})
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+
+	hash, err := db.AddLink(ctx, "https://ya.ru", "bbb", "owner", models.LinkOptions{})
+	require.ErrorIs(t, err, customErrors.ErrDuplicate)
+	require.Equal(t, "aaa", hash)
+
+	err = db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "ccc", URL: "https://google.com"},
+		{Hash: "aaa", URL: "https://test.ru"},
+	}, "owner")
+	require.ErrorIs(t, err, customErrors.ErrHashCollision)
+	require.Equal(t, 1, db.Len())
+
+	require.NoError(t, db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "ccc", URL: "https://google.com"},
+	}, "owner"))
+	require.NoError(t, db.RemoveUserLinks(ctx, "owner", []string{"ccc"}))
+
+	links, err := db.GetUserFullLinks(ctx, "owner")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	require.Equal(t, "aaa", links[0].Hash)
+	require.True(t, links[1].IsDeleted)
+}
+
+func TestShardedMemoryDatabase_EvictLRU(t *testing.T) {
+	db, err := NewShardedMemoryDatabase(ShardedMemoryOptions{Shards: 1, MaxEntries: 2, Policy: EvictLRU})
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	_, err = db.AddLink(ctx, "https://ya.ru/1", "h1", "", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = db.AddLink(ctx, "https://ya.ru/2", "h2", "", models.LinkOptions{})
+	require.NoError(t, err)
+
+	// h1 становится самой свежей, поэтому вытесняется h2
+	_, err = db.GetFullLink(ctx, "h1")
+	require.NoError(t, err)
+	_, err = db.AddLink(ctx, "https://ya.ru/3", "h3", "", models.LinkOptions{})
+	require.NoError(t, err)
+
+	require.Equal(t, 2, db.Len())
+	_, err = db.GetFullLink(ctx, "h2")
+	require.ErrorIs(t, err, customErrors.ErrNotFound)
+
+	// Вытесненный оригинал можно сократить заново
+	_, err = db.AddLink(ctx, "https://ya.ru/2", "h4", "", models.LinkOptions{})
+	require.NoError(t, err)
+}
+
+func TestShardedMemoryDatabase_EvictTTL(t *testing.T) {
+	db, err := NewShardedMemoryDatabase(ShardedMemoryOptions{Shards: 2, Policy: EvictTTL, TTL: time.Hour})
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		_, err := db.AddLink(ctx, "https://ya.ru/"+strconv.Itoa(i), "h"+strconv.Itoa(i), "user", models.LinkOptions{})
+		require.NoError(t, err)
+	}
+
+	db.expire(time.Now().Add(2 * time.Hour))
+	require.Zero(t, db.Len())
+
+	links, err := db.GetUserFullLinks(ctx, "user")
+	require.NoError(t, err)
+	require.Empty(t, links)
+}
+
+func TestShardedMemoryDatabase_LinkLimits(t *testing.T) {
+	db, err := NewShardedMemoryDatabase(ShardedMemoryOptions{Shards: 4})
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Minute)
+	err = db.AddLinks(ctx, models.DBShortenRowList{
+		{Hash: "aaa", URL: "https://ya.ru", LinkOptions: models.LinkOptions{MaxClicks: 1}},
+		{Hash: "bbb", URL: "https://google.com", LinkOptions: models.LinkOptions{ExpiresAt: &expiresAt}},
+	}, "user")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddClick(ctx, "aaa"))
+	require.ErrorIs(t, db.AddClick(ctx, "aaa"), customErrors.ErrLinkExpired)
+
+	hashes, err := db.ExpireLinks(ctx, expiresAt)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bbb"}, hashes)
+
+	row, err := db.GetFullLink(ctx, "bbb")
+	require.NoError(t, err)
+	require.True(t, row.IsExpired)
+}
+
+func TestShardedMemoryDatabase_OneTimeLink(t *testing.T) {
+	db, err := NewShardedMemoryDatabase(ShardedMemoryOptions{Shards: 4})
+	require.NoError(t, err)
+	defer db.Close()
+
+	requireSingleClick(t, db)
+}
+
+func TestShardedMemoryDatabase_UpdateLink(t *testing.T) {
+	db, err := NewShardedMemoryDatabase(ShardedMemoryOptions{Shards: 4})
+	require.NoError(t, err)
+	defer db.Close()
+
+	requireEditableLink(t, db)
+}
+
+func TestShardedMemoryDatabase_UpdateLinkTargeting(t *testing.T) {
+	db, err := NewShardedMemoryDatabase(ShardedMemoryOptions{Shards: 4})
+	require.NoError(t, err)
+	defer db.Close()
+
+	requireTargetableLink(t, db)
+}
+
+func TestShardedMemoryDatabase_ClickEvents(t *testing.T) {
+	db, err := NewShardedMemoryDatabase(ShardedMemoryOptions{Shards: 1, MaxEntries: 1})
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	_, err = db.AddLink(ctx, "https://ya.ru", "aaa", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, db.AddClickEvents(ctx, models.ClickEventList{{Hash: "aaa"}, {Hash: "zzz"}, {Hash: "aaa"}}))
+
+	element := db.shard("aaa").rows["aaa"]
+	require.Len(t, element.Value.(*shardEntry).clicks, 2, "события неизвестных ссылок отбрасываются")
+
+	_, err = db.AddLink(ctx, "https://google.com", "bbb", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	_, ok := db.shard("aaa").rows["aaa"]
+	require.False(t, ok, "события вытесняются вместе со ссылкой")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"github.com/thxhix/shortener/internal/models"
+	"time"
 )
 
 // Database defines the contract for all storage backends.
@@ -11,10 +12,10 @@ type Database interface {
 	// RunMigrations runs initial schema migrations for the database.
 	RunMigrations() error
 
-	// AddLink stores a single shortened link and returns its hash.
-	AddLink(ctx context.Context, original string, shorten string, userID string) (string, error)
+	// AddLink stores a single shortened link with the given limits and returns its hash.
+	AddLink(ctx context.Context, original string, shorten string, userID string, opts models.LinkOptions) (string, error)
 
-	// AddLinks stores a batch of shortened links.
+	// AddLinks stores a batch of shortened links together with their limits.
 	AddLinks(ctx context.Context, list models.DBShortenRowList, userID string) error
 
 	// GetFullLink retrieves the original link by its short hash.
@@ -26,6 +27,29 @@ type Database interface {
 	// RemoveUserLinks deletes links by their IDs for the given user.
 	RemoveUserLinks(ctx context.Context, userID string, ids []string) error
 
+	// UpdateLink changes the original URL of the user's link, keeping the previous
+	// one in the history, and returns the updated row. Returns ErrNotFound if the link
+	// does not exist or is deleted, ErrNotOwner if it belongs to another user and
+	// ErrDuplicate if another link already shortens the URL.
+	UpdateLink(ctx context.Context, userID string, hash string, original string) (models.DBShortenRow, error)
+
+	// GetLinkHistory returns the previous destinations of the link, oldest first.
+	GetLinkHistory(ctx context.Context, hash string) (models.LinkVersionList, error)
+
+	// UpdateLinkTargeting replaces the targeting rules of the user's link and
+	// returns the updated row. Returns ErrNotFound if the link does not exist
+	// or is deleted and ErrNotOwner if it belongs to another user.
+	UpdateLinkTargeting(ctx context.Context, userID string, hash string, rules models.TargetingRuleList) (models.DBShortenRow, error)
+
+	// AddClick counts a redirect of the link against its MaxClicks and marks
+	// the link as expired once the limit is reached. Returns ErrLinkExpired
+	// if the link has already expired or has no clicks left.
+	AddClick(ctx context.Context, hash string) error
+
+	// ExpireLinks marks links whose ExpiresAt or ActiveUntil is not after now
+	// as expired and returns their hashes. Deleted links are skipped.
+	ExpireLinks(ctx context.Context, now time.Time) ([]string, error)
+
 	// Close releases resources and closes the database connection.
 	Close() error
 
@@ -35,3 +59,95 @@ type Database interface {
 	// GetDriver returns the underlying sql.DB object for advanced use.
 	GetDriver() *sql.DB
 }
+
+// HashLister is implemented by storages that can enumerate their hashes,
+// e.g. to warm up a filter of known links at startup. Only storages used
+// by a single process implement it, storages shared by several replicas
+// implement HashTailer instead.
+type HashLister interface {
+	// ListHashes calls fn for every stored hash, including deleted links,
+	// and stops at the first error returned by fn.
+	ListHashes(ctx context.Context, fn func(hash string) error) error
+}
+
+// HashTailer is implemented by storages shared by several replicas that can
+// list the hashes added after a cursor, so that a filter of known links
+// catches up with the links created by the other replicas.
+type HashTailer interface {
+	// ListHashesAfter calls fn for every hash with an ID greater than after,
+	// including deleted links, in the order of IDs, and stops at the first
+	// error returned by fn. Returns the greatest ID listed, or after if none.
+	ListHashesAfter(ctx context.Context, after int, fn func(hash string) error) (int, error)
+}
+
+// RangeAllocator is implemented by storages that can reserve blocks
+// of numeric IDs for short codes. Blocks never overlap, even when
+// several instances of the service share the storage.
+type RangeAllocator interface {
+	// AllocateRange reserves size consecutive IDs and returns the first one.
+	AllocateRange(ctx context.Context, size int) (uint64, error)
+}
+
+// ClickStore is implemented by storages that keep click events of redirects.
+type ClickStore interface {
+	// AddClickEvents stores a batch of click events. The slice is reused
+	// by the caller, so it must not be retained.
+	AddClickEvents(ctx context.Context, events models.ClickEventList) error
+}
+
+// ClickReader is implemented by storages that can read back the click events
+// stored through ClickStore.
+type ClickReader interface {
+	// GetClickEvents returns the click events of the link that happened
+	// in [from, to), oldest first.
+	GetClickEvents(ctx context.Context, hash string, from time.Time, to time.Time) (models.ClickEventList, error)
+}
+
+// ClickRoller is implemented by storages that summarize click events
+// into hourly and daily rollups, so that raw events can be pruned.
+//
+// Events are rolled up in the order of their time: everything before
+// the watermark is in the rollups, events stored later with an earlier
+// time are kept raw only.
+type ClickRoller interface {
+	// RollupClicks adds the events in [watermark, until) to the rollups and moves
+	// the watermark to until in one atomic step, so that a crash never counts
+	// an event twice. Returns the number of rolled up events, until not after
+	// the watermark changes nothing.
+	RollupClicks(ctx context.Context, until time.Time) (int, error)
+
+	// PruneClicks deletes raw events before the given moment that are already
+	// rolled up and returns their number.
+	PruneClicks(ctx context.Context, before time.Time) (int, error)
+
+	// ClickWatermark returns the moment events are rolled up to, zero if never.
+	ClickWatermark(ctx context.Context) (time.Time, error)
+
+	// GetClickRollups returns the rollups of the link with starts in [from, to),
+	// oldest first. Empty buckets are omitted.
+	GetClickRollups(ctx context.Context, hash string, granularity models.RollupGranularity, from time.Time, to time.Time) (models.StatsBucketList, error)
+}
+
+// Unwrapper is implemented by decorators to expose the wrapped database.
+type Unwrapper interface {
+	// Unwrap returns the wrapped database.
+	Unwrap() Database
+}
+
+// As looks for the first database in the chain of decorators, starting
+// with db itself, that implements T.
+func As[T any](db Database) (T, bool) {
+	for db != nil {
+		if found, ok := db.(T); ok {
+			return found, true
+		}
+		wrapper, ok := db.(Unwrapper)
+		if !ok {
+			break
+		}
+		db = wrapper.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"github.com/thxhix/shortener/internal/database/interfaces"
+	"log"
+	"sync"
+	"time"
+)
+
+// Sweeper periodically marks links that have passed their expiration time
+// as expired, so that the state is kept in the storage and not only
+// derived on every lookup.
+type Sweeper struct {
+	db       interfaces.Database
+	interval time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewSweeper creates a Sweeper of the database running every interval.
+func NewSweeper(db interfaces.Database, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		db:       db,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start launches the sweeper goroutine.
+func (s *Sweeper) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop stops the sweeper goroutine and waits until it exits.
+func (s *Sweeper) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// Sweep marks links expired by now and returns their number.
+func (s *Sweeper) Sweep(ctx context.Context, now time.Time) (int, error) {
+	hashes, err := s.db.ExpireLinks(ctx, now)
+	return len(hashes), err
+}
+
+func (s *Sweeper) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+			expired, err := s.Sweep(ctx, now)
+			cancel()
+			if err != nil {
+				log.Printf("ошибка пометки истёкших ссылок: %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("помечено истёкших ссылок: %d", expired)
+			}
+		}
+	}
+}
@@ -4,3 +4,33 @@ import "errors"
 
 // ErrDuplicate is returned when hash exist for the given link.
 var ErrDuplicate = errors.New("такая ссылка уже сжата")
+
+// ErrHashCollision is returned when the short hash is already taken by another link.
+// Unlike ErrDuplicate, it can be resolved by generating another hash.
+var ErrHashCollision = errors.New("такой короткий код уже занят")
+
+// HashCollisionError reports which hash is already taken.
+// It matches ErrHashCollision with errors.Is.
+type HashCollisionError struct {
+	Hash string
+}
+
+// Error returns the message of ErrHashCollision with the taken hash.
+func (e *HashCollisionError) Error() string {
+	return ErrHashCollision.Error() + ": " + e.Hash
+}
+
+// Unwrap returns ErrHashCollision.
+func (e *HashCollisionError) Unwrap() error {
+	return ErrHashCollision
+}
+
+// ErrNotFound is returned when no link exists for the given hash.
+var ErrNotFound = errors.New("нет такой записи в БД")
+
+// ErrLinkExpired is returned when a link has passed its expiration time
+// or has run out of clicks.
+var ErrLinkExpired = errors.New("срок действия ссылки истёк")
+
+// ErrNotOwner is returned when a user changes a link created by another user.
+var ErrNotOwner = errors.New("ссылка принадлежит другому пользователю")
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/thxhix/shortener/internal/models"
+	"github.com/thxhix/shortener/internal/useragent"
+)
+
+// ClickTracker records redirects of short links. Track must not block.
+type ClickTracker interface {
+	// Track enqueues the event and reports whether it was accepted.
+	Track(event models.ClickEvent) bool
+}
+
+// Option configures optional dependencies of Handler.
+type Option func(*Handler)
+
+// WithClickTracker makes Redirect report every successful redirect to the tracker.
+func WithClickTracker(tracker ClickTracker) Option {
+	return func(h *Handler) {
+		h.clicks = tracker
+	}
+}
+
+// WithUserAgentParser makes Handler classify user agents with the parser.
+func WithUserAgentParser(parser *useragent.Parser) Option {
+	return func(h *Handler) {
+		h.agents = parser
+	}
+}
+
+// trackClick reports the redirect of the link to the click tracker, if any.
+// Redirects of bots, such as link-preview fetchers, are not tracked.
+func (h *Handler) trackClick(r *http.Request, hash string, agent useragent.Agent) {
+	if h.clicks == nil {
+		return
+	}
+	if agent.Bot {
+		return
+	}
+	h.clicks.Track(models.ClickEvent{
+		Hash:      hash,
+		Time:      time.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+		Device:    agent.Device,
+		OS:        agent.OS,
+		Browser:   agent.Browser,
+	})
+}
+
+// clientIP returns the address of the client without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -15,9 +15,9 @@ import (
 )
 
 func ExampleHandler_StoreLink() {
-	db, _ := drivers.NewMemoryDatabase()
+	db, _ := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
 	cfg := config.Config{BaseURL: "http://localhost:8080"}
-	useCase := urlUseCase.NewURLUseCase(db, cfg)
+	useCase := urlUseCase.NewURLUseCase(db, cfg, urlUseCase.NewSeededGenerator(1, 8))
 	h := NewHandler(&cfg, useCase)
 
 	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com"))
@@ -30,13 +30,13 @@ func ExampleHandler_StoreLink() {
 
 	// Output:
 	// Status: 201
-	// Body: http://localhost:8080/testHash
+	// Body: http://localhost:8080/RFbD56TI
 }
 
 func ExampleHandler_Redirect() {
-	db, _ := drivers.NewMemoryDatabase()
+	db, _ := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
 	cfg := config.Config{BaseURL: "http://localhost:8080"}
-	useCase := urlUseCase.NewURLUseCase(db, cfg)
+	useCase := urlUseCase.NewURLUseCase(db, cfg, urlUseCase.NewSeededGenerator(1, 8))
 	h := NewHandler(&cfg, useCase)
 
 	insertBDReq := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com"))
@@ -44,7 +44,7 @@ func ExampleHandler_Redirect() {
 
 	h.StoreLink(insertBDw, insertBDReq)
 
-	req := httptest.NewRequest(http.MethodGet, "/testHash", nil)
+	req := httptest.NewRequest(http.MethodGet, "/RFbD56TI", nil)
 	w := httptest.NewRecorder()
 
 	router := chi.NewRouter()
@@ -61,12 +61,12 @@ func ExampleHandler_Redirect() {
 }
 
 func ExampleHandler_APIStoreLink() {
-	db, _ := drivers.NewMemoryDatabase()
+	db, _ := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
 	cfg := config.Config{
 		BaseURL: "http://localhost:8080",
 	}
 
-	useCase := urlUseCase.NewURLUseCase(db, cfg)
+	useCase := urlUseCase.NewURLUseCase(db, cfg, urlUseCase.NewSeededGenerator(1, 8))
 	handler := NewHandler(&cfg, useCase)
 
 	body := models.FullURL{URL: "https://example.com"}
@@ -81,13 +81,13 @@ func ExampleHandler_APIStoreLink() {
 
 	// Output:
 	// Status: 201
-	// Body: {"result":"http://localhost:8080/testHash"}
+	// Body: {"result":"http://localhost:8080/RFbD56TI"}
 }
 
 func ExampleHandler_BatchStoreLink() {
-	db, _ := drivers.NewMemoryDatabase()
+	db, _ := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
 	cfg := config.Config{BaseURL: "http://localhost:8080"}
-	useCase := urlUseCase.NewURLUseCase(db, cfg)
+	useCase := urlUseCase.NewURLUseCase(db, cfg, urlUseCase.NewSeededGenerator(1, 8))
 	h := NewHandler(&cfg, useCase)
 
 	body := `[
@@ -105,13 +105,13 @@ func ExampleHandler_BatchStoreLink() {
 
 	// Output:
 	// Status: 201
-	// Body: [{"correlation_id":"1","short_url":"http://localhost:8080/testHash"}]
+	// Body: [{"correlation_id":"1","short_url":"http://localhost:8080/RFbD56TI"}]
 }
 
 func ExampleHandler_PingDatabase() {
-	db, _ := drivers.NewMemoryDatabase()
+	db, _ := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
 	cfg := config.Config{BaseURL: "http://localhost:8080"}
-	useCase := urlUseCase.NewURLUseCase(db, cfg)
+	useCase := urlUseCase.NewURLUseCase(db, cfg, urlUseCase.NewSeededGenerator(1, 8))
 	h := NewHandler(&cfg, useCase)
 
 	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
@@ -129,12 +129,12 @@ func ExampleHandler_PingDatabase() {
 func ExampleHandler_UserList() {
 	fmt.Println("Request: GET /api/user/urls")
 	fmt.Println("Response: 200 OK")
-	fmt.Println(`Body: [{"short_url":"http://localhost:8080/testHash","original_url":"https://example.com"}]`)
+	fmt.Println(`Body: [{"short_url":"http://localhost:8080/RFbD56TI","original_url":"https://example.com"}]`)
 
 	// Output:
 	// Request: GET /api/user/urls
 	// Response: 200 OK
-	// Body: [{"short_url":"http://localhost:8080/testHash","original_url":"https://example.com"}]
+	// Body: [{"short_url":"http://localhost:8080/RFbD56TI","original_url":"https://example.com"}]
 }
 
 // ExampleHandler_UserDeleteRows demonstrates how to call the "/api/user/urls" DELETE endpoint.
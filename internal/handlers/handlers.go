@@ -8,6 +8,7 @@ import (
 	"github.com/thxhix/shortener/internal/middleware"
 	"github.com/thxhix/shortener/internal/models"
 	urlUseCase "github.com/thxhix/shortener/internal/url"
+	"github.com/thxhix/shortener/internal/useragent"
 	"io"
 	"log"
 	"net/http"
@@ -22,20 +23,31 @@ import (
 type Handler struct {
 	config     config.Config
 	URLUsecase urlUseCase.URLUseCaseInterface
+	clicks     ClickTracker
+	agents     *useragent.Parser
 }
 
 // NewHandler creates a new Handler instance with the given configuration
-// and URL use case implementation.
-func NewHandler(cfg *config.Config, useCase urlUseCase.URLUseCaseInterface) *Handler {
-	return &Handler{
+// and URL use case implementation. Redirects are not tracked unless
+// a tracker is set with WithClickTracker. User agents are classified
+// with the bundled rules unless WithUserAgentParser sets others.
+func NewHandler(cfg *config.Config, useCase urlUseCase.URLUseCaseInterface, opts ...Option) *Handler {
+	h := &Handler{
 		config:     *cfg,
 		URLUsecase: useCase,
+		agents:     useragent.Default(),
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // StoreLink It reads the raw URL from the request body, validates it,
 // and returns a shortened link as plain text
 // Responds with 201 Created on success or 409 Conflict if the URL already exists.
+// If the existing link has limits or a password, the 409 Conflict carries
+// a plain text error instead of the link.
 func (h *Handler) StoreLink(w http.ResponseWriter, r *http.Request) {
 	targetURL, err := io.ReadAll(r.Body)
 	defer func() {
@@ -55,12 +67,12 @@ func (h *Handler) StoreLink(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var isConflict = false
-	link, err := h.URLUsecase.Shorten(r.Context(), parsedURL.String())
+	link, err := h.URLUsecase.Shorten(r.Context(), models.FullURL{URL: parsedURL.String()})
 	if err != nil {
 		if errors.Is(err, custorErrors.ErrDuplicate) {
 			isConflict = true
 		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeStoreError(w, err)
 			return
 		}
 	}
@@ -79,29 +91,80 @@ func (h *Handler) StoreLink(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Redirect It looks up the full URL by the short hash and issues a 307 redirect.
-// If the link was deleted, responds with 410 Gone.
+// Redirect It looks up the full URL by the short hash and issues a redirect
+// with the status code of the link, 307 by default, and its Cache-Control header.
+// Successful redirects are reported to the click tracker without waiting for it.
+// If the link was deleted, responds with 410 Gone and an empty body.
+// If the link has expired or run out of clicks, responds with 410 Gone
+// and an explanation in the body. Bots requesting a link with a click limit
+// get 403 Forbidden, so that link previews do not use up its clicks.
+// If the link is requested before its active window, redirects to
+// InactiveLinkFallbackURL if it is configured, or responds with 404 Not Found.
 // If the link does not exist, responds with 400 Bad Request.
+//
+// A password-protected link redirects only once the password, passed
+// in PasswordHeader, the "password" query parameter or the posted form,
+// matches. Without it, or with a wrong one, responds with 401 Unauthorized
+// and the password form, or a plain text error if the password was passed
+// in the header. Links with too many failed attempts get 429 Too Many Requests.
+//
+// Links with targeting rules redirect according to the device and OS of the
+// client, its Accept-Language header and the query parameters of the request.
 func (h *Handler) Redirect(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	agent := h.agents.Parse(r.UserAgent())
+	attrs := requestAttributes(r, agent)
 
-	link, err := h.URLUsecase.GetFullURL(r.Context(), id)
+	var link models.Redirect
+	var err error
+	if password, ok := linkPassword(r); ok {
+		link, err = h.URLUsecase.UnlockURL(r.Context(), id, password, attrs)
+	} else {
+		link, err = h.URLUsecase.GetFullURL(r.Context(), id, attrs)
+	}
 	if err != nil {
-		if errors.Is(err, urlUseCase.ErrLinkDeleted) {
+		switch {
+		case errors.Is(err, urlUseCase.ErrLinkDeleted):
 			w.WriteHeader(http.StatusGone)
-			return
+		case errors.Is(err, urlUseCase.ErrLinkExpired):
+			http.Error(w, err.Error(), http.StatusGone)
+		case errors.Is(err, urlUseCase.ErrLimitedForBots):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, urlUseCase.ErrLinkNotActive):
+			h.writeNotActive(w, err)
+		case errors.Is(err, urlUseCase.ErrPasswordRequired):
+			writePasswordError(w, r, "")
+		case errors.Is(err, urlUseCase.ErrWrongPassword):
+			writePasswordError(w, r, err.Error())
+		case errors.Is(err, urlUseCase.ErrTooManyAttempts):
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		default:
+			http.Error(w, "такой страницы нет", http.StatusBadRequest)
 		}
-		http.Error(w, "такой страницы нет", http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Add("Location", link)
-	w.WriteHeader(http.StatusTemporaryRedirect)
+	h.trackClick(r, id, agent)
+
+	w.Header().Add("Location", link.URL)
+	if link.CacheControl != "" {
+		w.Header().Set("Cache-Control", link.CacheControl)
+	}
+	if link.Vary != "" {
+		w.Header().Set("Vary", link.Vary)
+	}
+	w.WriteHeader(link.StatusCode)
 }
 
-// APIStoreLink It reads a JSON payload with the original URL, validates it,
+// APIStoreLink It reads a JSON payload with the original URL, an optional alias,
+// optional expires_at, max_clicks, active_from and active_until limits,
+// an optional one_time flag, an optional password, optional redirect_code
+// and cache_max_age of the redirect and optional targeting rules, validates it,
 // and returns a JSON response containing the shortened URL.
 // Responds with 201 Created on success or 409 Conflict if the URL already exists.
+// An invalid alias or invalid limits get 400 Bad Request, and a taken alias or an existing
+// link with other limits or password gets 409 Conflict with a plain text error instead
+// of the existing link.
 func (h *Handler) APIStoreLink(w http.ResponseWriter, r *http.Request) {
 	json, err := io.ReadAll(r.Body)
 	defer func() {
@@ -124,12 +187,12 @@ func (h *Handler) APIStoreLink(w http.ResponseWriter, r *http.Request) {
 
 	var isConflict = false
 
-	link, err := h.URLUsecase.Shorten(r.Context(), fullURL.URL)
+	link, err := h.URLUsecase.Shorten(r.Context(), *fullURL)
 	if err != nil {
 		if errors.Is(err, custorErrors.ErrDuplicate) {
 			isConflict = true
 		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeStoreError(w, err)
 			return
 		}
 	}
@@ -159,6 +222,9 @@ func (h *Handler) APIStoreLink(w http.ResponseWriter, r *http.Request) {
 
 // BatchStoreLink It reads a JSON array of objects with correlation_id and original_url,
 // validates the input, and returns a JSON array of shortened links.
+// Items may carry an alias, limits, the one_time flag and a password,
+// handled the same way as in APIStoreLink.
+// Responds with 503 Service Unavailable if no free short codes were found.
 func (h *Handler) BatchStoreLink(w http.ResponseWriter, r *http.Request) {
 	json, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -186,7 +252,7 @@ func (h *Handler) BatchStoreLink(w http.ResponseWriter, r *http.Request) {
 
 	data, err := h.URLUsecase.BatchShorten(r.Context(), batch)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeStoreError(w, err)
 		return
 	}
 
@@ -292,3 +358,32 @@ func (h *Handler) UserDeleteRows(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// writeNotActive responds to a request of a link before its active window
+// with a redirect to the configured fallback URL or 404 Not Found.
+// The response must not be cached, since the link becomes active later.
+func (h *Handler) writeNotActive(w http.ResponseWriter, err error) {
+	w.Header().Set("Cache-Control", "no-store")
+	if h.config.InactiveLinkFallbackURL == "" {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Location", h.config.InactiveLinkFallbackURL)
+	w.WriteHeader(http.StatusTemporaryRedirect)
+}
+
+// writeStoreError responds to a failed attempt to shorten links.
+// Exhausted short codes are reported as 503 Service Unavailable,
+// since retrying the same request later may succeed.
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, urlUseCase.ErrInvalidAlias), errors.Is(err, urlUseCase.ErrInvalidLinkOptions):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, urlUseCase.ErrAliasTaken), errors.Is(err, urlUseCase.ErrDuplicateOptions):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, urlUseCase.ErrCodeSpaceExhausted):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
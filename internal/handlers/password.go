@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// PasswordHeader is the request header API clients pass the password
+// of a protected link in. Browsers submit it with the form served by Redirect.
+const PasswordHeader = "X-Link-Password"
+
+// passwordParam is the query parameter and the form field of the password.
+const passwordParam = "password"
+
+// passwordForm is the page asking for the password of a protected link.
+// The form is posted back to the short link itself.
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Ссылка защищена паролем</title>
+</head>
+<body>
+<form method="post">
+<p>Ссылка защищена паролем.</p>
+{{if .}}<p>{{.}}</p>
+{{end}}<input type="password" name="` + passwordParam + `" autofocus required>
+<button type="submit">Открыть</button>
+</form>
+</body>
+</html>
+`))
+
+// linkPassword returns the password sent with the request in PasswordHeader,
+// the query or the posted form, and whether it was sent at all.
+func linkPassword(r *http.Request) (string, bool) {
+	if password := r.Header.Get(PasswordHeader); password != "" {
+		return password, true
+	}
+	if err := r.ParseForm(); err != nil {
+		return "", false
+	}
+	if values, ok := r.Form[passwordParam]; ok && len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+// writePasswordError responds to a request of a protected link without
+// the right password. API clients, which pass the password in PasswordHeader,
+// get a plain text error, others get the password form with the message.
+func writePasswordError(w http.ResponseWriter, r *http.Request, message string) {
+	if r.Header.Get(PasswordHeader) != "" {
+		http.Error(w, message, http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusUnauthorized)
+	if err := passwordForm.Execute(w, message); err != nil {
+		log.Printf("ошибка при записи формы пароля: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thxhix/shortener/internal/middleware"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+// LinkStats returns the click statistics of the user's link.
+//
+// The range is set by the "from" and "to" query parameters in RFC 3339,
+// the last week by default, and the size of the buckets by the "bucket"
+// parameter: "hour", "day" (the default) or "week":
+//
+//	GET /api/user/urls/{id}/stats?from=2024-05-01T00:00:00Z&bucket=hour
+//
+// Responds with 200 OK and the statistics on success and with 400 Bad Request
+// if the range or the bucket is invalid. Other errors are reported the same
+// way as in UpdateLink.
+func (h *Handler) LinkStats(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "неверный данные авторизации..", http.StatusUnauthorized)
+		return
+	}
+
+	params := r.URL.Query()
+	query := models.StatsQuery{Bucket: params.Get("bucket")}
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+	}{
+		{"from", &query.From},
+		{"to", &query.To},
+	} {
+		value := params.Get(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "некорректный параметр "+param.name+": ожидается время в формате RFC 3339", http.StatusBadRequest)
+			return
+		}
+		*param.target = &parsed
+	}
+
+	stats, err := h.URLUsecase.LinkStats(r.Context(), userID, chi.URLParam(r, "id"), query)
+	if err != nil {
+		writeOwnedLinkError(w, err)
+		return
+	}
+	writeJSON(w, stats)
+}
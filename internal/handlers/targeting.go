@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mailru/easyjson"
+	"github.com/thxhix/shortener/internal/middleware"
+	"github.com/thxhix/shortener/internal/models"
+	"github.com/thxhix/shortener/internal/useragent"
+)
+
+// LinkTargeting returns the targeting rules of the user's link in the order
+// they are checked. Errors are reported the same way as in UpdateLink.
+func (h *Handler) LinkTargeting(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "неверный данные авторизации..", http.StatusUnauthorized)
+		return
+	}
+
+	rules, err := h.URLUsecase.LinkTargeting(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeOwnedLinkError(w, err)
+		return
+	}
+	writeJSON(w, rules)
+}
+
+// UpdateLinkTargeting replaces the targeting rules of the user's link.
+//
+// It expects the request body to contain the rules in the order they are checked:
+//
+//	[
+//	  {"os": "iOS", "url": "https://apps.apple.com/app/id1"},
+//	  {"os": "Android", "url": "https://play.google.com/store/apps/details?id=app"}
+//	]
+//
+// An empty list removes targeting. Responds with 200 OK and the stored rules
+// on success and with 400 Bad Request if the rules are invalid. Other errors
+// are reported the same way as in UpdateLink.
+func (h *Handler) UpdateLinkTargeting(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "неверный данные авторизации..", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "не удалось прочитать тело запроса", http.StatusBadRequest)
+		return
+	}
+	var request models.TargetingRuleList
+	if err := easyjson.Unmarshal(body, &request); err != nil {
+		http.Error(w, "невалидный JSON", http.StatusBadRequest)
+		return
+	}
+
+	rules, err := h.URLUsecase.UpdateTargeting(r.Context(), userID, chi.URLParam(r, "id"), request)
+	if err != nil {
+		writeOwnedLinkError(w, err)
+		return
+	}
+	writeJSON(w, rules)
+}
+
+// requestAttributes collects the attributes of the request that targeting
+// rules are matched against and that tell bots apart.
+func requestAttributes(r *http.Request, agent useragent.Agent) models.RequestAttributes {
+	return models.RequestAttributes{
+		Device:         agent.Device,
+		OS:             agent.OS,
+		Bot:            agent.Bot,
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		Query:          r.URL.Query(),
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mailru/easyjson"
+	custorErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/middleware"
+	"github.com/thxhix/shortener/internal/models"
+	urlUseCase "github.com/thxhix/shortener/internal/url"
+)
+
+// UpdateLink changes the destination of the user's link.
+//
+// It expects the request body to contain the new original URL:
+//
+//	{"url": "https://example.com/fixed"}
+//
+// Responds with 200 OK and the new current version on success,
+// 401 Unauthorized for an unauthenticated user, 403 Forbidden if the link
+// belongs to another user, 404 Not Found if it does not exist and
+// 409 Conflict if another link already shortens the URL.
+func (h *Handler) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "неверный данные авторизации..", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "не удалось прочитать тело запроса", http.StatusBadRequest)
+		return
+	}
+	request := models.UpdateURLRequest{}
+	if err := easyjson.Unmarshal(body, &request); err != nil {
+		http.Error(w, "невалидный JSON", http.StatusBadRequest)
+		return
+	}
+
+	version, err := h.URLUsecase.UpdateURL(r.Context(), userID, chi.URLParam(r, "id"), request.URL)
+	if err != nil {
+		writeOwnedLinkError(w, err)
+		return
+	}
+	writeJSON(w, version)
+}
+
+// LinkVersions returns all destinations of the user's link, oldest first,
+// with the current one marked. Errors are reported the same way as in UpdateLink.
+func (h *Handler) LinkVersions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "неверный данные авторизации..", http.StatusUnauthorized)
+		return
+	}
+
+	versions, err := h.URLUsecase.URLVersions(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeOwnedLinkError(w, err)
+		return
+	}
+	writeJSON(w, versions)
+}
+
+// RollbackLink makes the user's link redirect to an earlier destination.
+//
+// It expects the request body to contain the number of the version:
+//
+//	{"version": 1}
+//
+// Responds with 200 OK and the new current version on success and with
+// 404 Not Found if the link has no such version. Other errors are reported
+// the same way as in UpdateLink.
+func (h *Handler) RollbackLink(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "неверный данные авторизации..", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "не удалось прочитать тело запроса", http.StatusBadRequest)
+		return
+	}
+	request := models.RollbackRequest{}
+	if err := easyjson.Unmarshal(body, &request); err != nil {
+		http.Error(w, "невалидный JSON", http.StatusBadRequest)
+		return
+	}
+
+	version, err := h.URLUsecase.RollbackURL(r.Context(), userID, chi.URLParam(r, "id"), request.Version)
+	if err != nil {
+		writeOwnedLinkError(w, err)
+		return
+	}
+	writeJSON(w, version)
+}
+
+// writeOwnedLinkError responds to a failed request of the user's own link,
+// such as an edit, a read of its versions, statistics or targeting rules.
+func writeOwnedLinkError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, urlUseCase.ErrInvalidURL), errors.Is(err, urlUseCase.ErrInvalidStatsQuery),
+		errors.Is(err, urlUseCase.ErrInvalidLinkOptions):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, custorErrors.ErrNotOwner):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, custorErrors.ErrNotFound), errors.Is(err, urlUseCase.ErrVersionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, custorErrors.ErrDuplicate):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, urlUseCase.ErrStatsUnavailable):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeJSON responds with 200 OK and the value encoded as JSON.
+func writeJSON(w http.ResponseWriter, v easyjson.Marshaler) {
+	result, err := easyjson.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(result); err != nil {
+		log.Printf("ошибка при записи ответа: %v", err)
+	}
+}
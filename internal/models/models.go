@@ -9,6 +9,17 @@ import (
 //easyjson:json
 type FullURL struct {
 	URL string `json:"url"`
+
+	// Alias is an optional custom short code chosen by the user.
+	Alias string `json:"alias,omitempty"`
+
+	// Password optionally protects the link, it is stored only as a hash.
+	Password string `json:"password,omitempty"`
+
+	// OneTime makes the link work for a single redirect, same as MaxClicks of one.
+	OneTime bool `json:"one_time,omitempty"`
+
+	LinkOptions
 }
 
 //easyjson:json
@@ -19,6 +30,89 @@ type ShortURL struct {
 //easyjson:json
 type DBShortenRowList []DBShortenRow
 
+// LinkOptions holds optional limits of a link, set when it is created.
+type LinkOptions struct {
+	// ExpiresAt is the moment the link stops redirecting, nil means never.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// MaxClicks is the number of redirects after which the link expires,
+	// zero means unlimited.
+	MaxClicks int `json:"max_clicks,omitempty"`
+
+	// ActiveFrom is the moment the link starts redirecting, nil means right away.
+	ActiveFrom *time.Time `json:"active_from,omitempty"`
+
+	// ActiveUntil is the moment the link stops redirecting, nil means never.
+	// Unlike ExpiresAt, it is meant to close a scheduled window, but once
+	// it passes the link is expired all the same.
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+
+	// PasswordHash is the salted hash of the link password, empty if the link
+	// is not protected. It is computed by the service, values sent by clients
+	// are ignored.
+	PasswordHash string `json:"password_hash,omitempty"`
+
+	// RedirectCode is the HTTP status of the redirect: 301, 302, 307 or 308.
+	// Zero means 307 Temporary Redirect.
+	RedirectCode int `json:"redirect_code,omitempty"`
+
+	// CacheMaxAge is the number of seconds browsers and CDNs may cache
+	// the redirect for, zero means the redirect is sent without cache headers.
+	CacheMaxAge int `json:"cache_max_age,omitempty"`
+
+	// Targeting sends matching requests to other destinations. The rules are
+	// checked in order, the first matching one wins, requests matching none
+	// are sent to the URL of the link.
+	Targeting TargetingRuleList `json:"targeting,omitempty"`
+}
+
+// TargetingRule sends requests matching all of its conditions to its URL.
+// Empty conditions match any request, at least one must be set.
+type TargetingRule struct {
+	// Device is the device class of the client: desktop, mobile, tablet,
+	// bot or other.
+	Device string `json:"device,omitempty"`
+
+	// OS is the family of the operating system of the client, e.g. iOS
+	// or Android, compared case-insensitively.
+	OS string `json:"os,omitempty"`
+
+	// Language is the language the client prefers most according to
+	// Accept-Language, e.g. "ru". A language without a region also matches
+	// its regional variants.
+	Language string `json:"language,omitempty"`
+
+	// QueryParam is the name of a query parameter the request must have.
+	QueryParam string `json:"query_param,omitempty"`
+
+	// QueryValue is the value QueryParam must have, empty means any.
+	QueryValue string `json:"query_value,omitempty"`
+
+	// URL is the destination of the matching requests.
+	URL string `json:"url"`
+}
+
+//easyjson:json
+type TargetingRuleList []TargetingRule
+
+// RequestAttributes describe the request following a short link,
+// targeting rules are matched against them.
+type RequestAttributes struct {
+	// Device and OS classify the user agent of the client.
+	Device string
+	OS     string
+
+	// Bot is set for crawlers and link-preview fetchers, they are not
+	// redirected by links with MaxClicks.
+	Bot bool
+
+	// AcceptLanguage is the Accept-Language header of the request.
+	AcceptLanguage string
+
+	// Query holds the query parameters of the request.
+	Query map[string][]string
+}
+
 //easyjson:json
 type DBShortenRow struct {
 	ID        int       `json:"id"`
@@ -27,6 +121,44 @@ type DBShortenRow struct {
 	Time      time.Time `json:"time"`
 	UserID    string    `json:"user_id"`
 	IsDeleted bool      `json:"is_deleted"`
+
+	LinkOptions
+
+	// Clicks is the number of redirects counted against MaxClicks.
+	Clicks int `json:"clicks,omitempty"`
+
+	// IsExpired is set once the link runs out of clicks or is marked
+	// by the sweeper after ExpiresAt.
+	IsExpired bool `json:"is_expired,omitempty"`
+
+	// Version is the number of the current destination, zero for a link
+	// that has never been edited, which is the same as the first version.
+	Version int `json:"version,omitempty"`
+
+	// History holds the previous destinations in storages that keep them
+	// within the row, such as the memory and file ones. PostgreSQL keeps them
+	// in a separate table, so use Database.GetLinkHistory to read them.
+	History LinkVersionList `json:"history,omitempty"`
+}
+
+// CurrentVersion returns the number of the current destination of the link.
+func (r DBShortenRow) CurrentVersion() int {
+	return max(r.Version, 1)
+}
+
+// ExpiredAt reports whether the link no longer redirects at the given moment,
+// either because it has been marked as expired or because ExpiresAt
+// or ActiveUntil has passed.
+func (r DBShortenRow) ExpiredAt(now time.Time) bool {
+	return r.IsExpired ||
+		r.ExpiresAt != nil && !now.Before(*r.ExpiresAt) ||
+		r.ActiveUntil != nil && !now.Before(*r.ActiveUntil)
+}
+
+// PendingAt reports whether the link has not started redirecting yet
+// at the given moment because ActiveFrom is still ahead.
+func (r DBShortenRow) PendingAt(now time.Time) bool {
+	return r.ActiveFrom != nil && now.Before(*r.ActiveFrom)
 }
 
 //easyjson:json
@@ -36,6 +168,17 @@ type BatchShortenRequestList []BatchShortenRequest
 type BatchShortenRequest struct {
 	ID  string `json:"correlation_id"`
 	URL string `json:"original_url"`
+
+	// Alias is an optional custom short code chosen by the user.
+	Alias string `json:"alias,omitempty"`
+
+	// Password optionally protects the link, it is stored only as a hash.
+	Password string `json:"password,omitempty"`
+
+	// OneTime makes the link work for a single redirect, same as MaxClicks of one.
+	OneTime bool `json:"one_time,omitempty"`
+
+	LinkOptions
 }
 
 //easyjson:json
@@ -64,6 +207,171 @@ type UserLinksResponse struct {
 
 	// Original is the original, unmodified URL provided by the user.
 	Original string `json:"original_url"`
+
+	// RedirectCode is the HTTP status of the redirect, omitted for the default one.
+	RedirectCode int `json:"redirect_code,omitempty"`
+
+	// CacheMaxAge is the number of seconds the redirect may be cached for.
+	CacheMaxAge int `json:"cache_max_age,omitempty"`
+
+	// Targeting are the targeting rules of the link.
+	Targeting TargetingRuleList `json:"targeting,omitempty"`
+}
+
+// Redirect is the response to a request of a short link.
+type Redirect struct {
+	// URL is the original URL to redirect to.
+	URL string
+
+	// StatusCode is the HTTP status of the redirect.
+	StatusCode int
+
+	// CacheControl is the value of the Cache-Control header,
+	// empty if the header is not sent.
+	CacheControl string
+
+	// Vary is the value of the Vary header, set when the destination
+	// depends on the headers of the request.
+	Vary string
+}
+
+//easyjson:json
+type LinkVersionList []LinkVersion
+
+// LinkVersion is one of the destinations a short link has had.
+type LinkVersion struct {
+	// Version is the sequential number of the destination, starting with 1.
+	Version int `json:"version"`
+
+	// URL is the original URL the link redirected to in this version.
+	URL string `json:"original_url"`
+
+	// ReplacedAt is when the version was replaced by the next one,
+	// nil for the current version.
+	ReplacedAt *time.Time `json:"replaced_at,omitempty"`
+
+	// Current is set for the version the link redirects to now.
+	Current bool `json:"current,omitempty"`
+}
+
+//easyjson:json
+type UpdateURLRequest struct {
+	// URL is the new original URL of the link.
+	URL string `json:"url"`
+}
+
+//easyjson:json
+type RollbackRequest struct {
+	// Version is the number of the destination to return to.
+	Version int `json:"version"`
+}
+
+//easyjson:json
+type ClickEventList []ClickEvent
+
+// ClickEvent is a single redirect of a short link.
+type ClickEvent struct {
+	// Hash is the short code of the followed link.
+	Hash string `json:"hash"`
+
+	// Time is the moment of the redirect.
+	Time time.Time `json:"time"`
+
+	// Referrer is the Referer header of the request, if any.
+	Referrer string `json:"referrer,omitempty"`
+
+	// UserAgent is the User-Agent header of the request, if any.
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// IP is the address of the client.
+	IP string `json:"ip,omitempty"`
+
+	// Device, OS and Browser classify the user agent of the client.
+	// They are empty for events tracked before user agents were classified.
+	Device  string `json:"device,omitempty"`
+	OS      string `json:"os,omitempty"`
+	Browser string `json:"browser,omitempty"`
+}
+
+//easyjson:json
+type LinkStats struct {
+	// Hash is the short code of the link.
+	Hash string `json:"hash"`
+
+	// From and To bound the requested range, From is included and To is not.
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// Bucket is the size of the buckets of Series: "hour", "day" or "week".
+	Bucket string `json:"bucket"`
+
+	// TotalClicks is the number of redirects in the range.
+	TotalClicks int `json:"total_clicks"`
+
+	// UniqueVisitors is the number of distinct clients in the range,
+	// a client is identified by its IP and user agent.
+	UniqueVisitors int `json:"unique_visitors"`
+
+	// Series holds the number of redirects in every bucket of the range,
+	// including the empty ones.
+	Series StatsBucketList `json:"series"`
+
+	// TopReferrers are the referring hosts with the most redirects.
+	TopReferrers StatsCountList `json:"top_referrers"`
+
+	// UserAgents are the browser families with the most redirects.
+	UserAgents StatsCountList `json:"user_agents"`
+
+	// Devices are the device classes with the most redirects.
+	Devices StatsCountList `json:"devices"`
+
+	// RolledUpUntil is set when redirects before it are counted from rollups.
+	// Such redirects are counted at the resolution of the rollup, and only
+	// the retained raw clicks add to UniqueVisitors, TopReferrers, UserAgents
+	// and Devices.
+	RolledUpUntil *time.Time `json:"rolled_up_until,omitempty"`
+}
+
+//easyjson:json
+type StatsBucketList []StatsBucket
+
+// StatsBucket is the number of redirects in a time bucket.
+type StatsBucket struct {
+	// Start is the beginning of the bucket.
+	Start time.Time `json:"start"`
+
+	// Clicks is the number of redirects in the bucket.
+	Clicks int `json:"clicks"`
+}
+
+//easyjson:json
+type StatsCountList []StatsCount
+
+// StatsCount is the number of redirects with the given value, e.g. a referrer.
+type StatsCount struct {
+	Name   string `json:"name"`
+	Clicks int    `json:"clicks"`
+}
+
+// RollupGranularity is the size of the buckets of click rollups.
+type RollupGranularity string
+
+// Granularities of click rollups.
+const (
+	RollupHour RollupGranularity = "hour"
+	RollupDay  RollupGranularity = "day"
+)
+
+// StatsQuery selects the range and the bucket size of link statistics.
+type StatsQuery struct {
+	// From is the beginning of the range, a week before To if nil.
+	From *time.Time
+
+	// To is the end of the range, now if nil.
+	To *time.Time
+
+	// Bucket is "hour", "day" or "week", "day" if empty.
+	Bucket string
 }
 
 //easyjson:json
@@ -7,6 +7,7 @@ import (
 	easyjson "github.com/mailru/easyjson"
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
+	time "time"
 )
 
 // suppress unused package warning
@@ -26,7 +27,7 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels(in *jlexer.Lex
 		in.Delim('[')
 		if *out == nil {
 			if !in.IsDelim(']') {
-				*out = make(UserLinksResponseList, 0, 2)
+				*out = make(UserLinksResponseList, 0, 0)
 			} else {
 				*out = UserLinksResponseList{}
 			}
@@ -106,6 +107,12 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels1(in *jlexer.Le
 			out.Short = string(in.String())
 		case "original_url":
 			out.Original = string(in.String())
+		case "redirect_code":
+			out.RedirectCode = int(in.Int())
+		case "cache_max_age":
+			out.CacheMaxAge = int(in.Int())
+		case "targeting":
+			(out.Targeting).UnmarshalEasyJSON(in)
 		default:
 			in.SkipRecursive()
 		}
@@ -130,33 +137,1383 @@ func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels1(out *jwriter.
 		out.RawString(prefix)
 		out.String(string(in.Original))
 	}
+	if in.RedirectCode != 0 {
+		const prefix string = ",\"redirect_code\":"
+		out.RawString(prefix)
+		out.Int(int(in.RedirectCode))
+	}
+	if in.CacheMaxAge != 0 {
+		const prefix string = ",\"cache_max_age\":"
+		out.RawString(prefix)
+		out.Int(int(in.CacheMaxAge))
+	}
+	if len(in.Targeting) != 0 {
+		const prefix string = ",\"targeting\":"
+		out.RawString(prefix)
+		(in.Targeting).MarshalEasyJSON(out)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v UserLinksResponse) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v UserLinksResponse) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *UserLinksResponse) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *UserLinksResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels1(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels2(in *jlexer.Lexer, out *UpdateURLRequest) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "url":
+			out.URL = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels2(out *jwriter.Writer, in UpdateURLRequest) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"url\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.URL))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v UpdateURLRequest) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v UpdateURLRequest) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *UpdateURLRequest) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *UpdateURLRequest) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels2(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels3(in *jlexer.Lexer, out *TargetingRuleList) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(TargetingRuleList, 0, 0)
+			} else {
+				*out = TargetingRuleList{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v4 TargetingRule
+			(v4).UnmarshalEasyJSON(in)
+			*out = append(*out, v4)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels3(out *jwriter.Writer, in TargetingRuleList) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v5, v6 := range in {
+			if v5 > 0 {
+				out.RawByte(',')
+			}
+			(v6).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v TargetingRuleList) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels3(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v TargetingRuleList) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels3(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *TargetingRuleList) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels3(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *TargetingRuleList) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels3(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels4(in *jlexer.Lexer, out *TargetingRule) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "device":
+			out.Device = string(in.String())
+		case "os":
+			out.OS = string(in.String())
+		case "language":
+			out.Language = string(in.String())
+		case "query_param":
+			out.QueryParam = string(in.String())
+		case "query_value":
+			out.QueryValue = string(in.String())
+		case "url":
+			out.URL = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels4(out *jwriter.Writer, in TargetingRule) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if in.Device != "" {
+		const prefix string = ",\"device\":"
+		first = false
+		out.RawString(prefix[1:])
+		out.String(string(in.Device))
+	}
+	if in.OS != "" {
+		const prefix string = ",\"os\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.OS))
+	}
+	if in.Language != "" {
+		const prefix string = ",\"language\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Language))
+	}
+	if in.QueryParam != "" {
+		const prefix string = ",\"query_param\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.QueryParam))
+	}
+	if in.QueryValue != "" {
+		const prefix string = ",\"query_value\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.QueryValue))
+	}
+	{
+		const prefix string = ",\"url\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.URL))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v TargetingRule) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels4(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v TargetingRule) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels4(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *TargetingRule) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels4(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *TargetingRule) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels4(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels5(in *jlexer.Lexer, out *StatsQuery) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "From":
+			if in.IsNull() {
+				in.Skip()
+				out.From = nil
+			} else {
+				if out.From == nil {
+					out.From = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.From).UnmarshalJSON(data))
+				}
+			}
+		case "To":
+			if in.IsNull() {
+				in.Skip()
+				out.To = nil
+			} else {
+				if out.To == nil {
+					out.To = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.To).UnmarshalJSON(data))
+				}
+			}
+		case "Bucket":
+			out.Bucket = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels5(out *jwriter.Writer, in StatsQuery) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"From\":"
+		out.RawString(prefix[1:])
+		if in.From == nil {
+			out.RawString("null")
+		} else {
+			out.Raw((*in.From).MarshalJSON())
+		}
+	}
+	{
+		const prefix string = ",\"To\":"
+		out.RawString(prefix)
+		if in.To == nil {
+			out.RawString("null")
+		} else {
+			out.Raw((*in.To).MarshalJSON())
+		}
+	}
+	{
+		const prefix string = ",\"Bucket\":"
+		out.RawString(prefix)
+		out.String(string(in.Bucket))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v StatsQuery) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels5(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v StatsQuery) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels5(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *StatsQuery) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels5(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *StatsQuery) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels5(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels6(in *jlexer.Lexer, out *StatsCountList) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(StatsCountList, 0, 2)
+			} else {
+				*out = StatsCountList{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v7 StatsCount
+			(v7).UnmarshalEasyJSON(in)
+			*out = append(*out, v7)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels6(out *jwriter.Writer, in StatsCountList) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v8, v9 := range in {
+			if v8 > 0 {
+				out.RawByte(',')
+			}
+			(v9).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v StatsCountList) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels6(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v StatsCountList) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels6(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *StatsCountList) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels6(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *StatsCountList) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels6(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels7(in *jlexer.Lexer, out *StatsCount) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "name":
+			out.Name = string(in.String())
+		case "clicks":
+			out.Clicks = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels7(out *jwriter.Writer, in StatsCount) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"name\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Name))
+	}
+	{
+		const prefix string = ",\"clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.Clicks))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v StatsCount) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels7(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v StatsCount) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels7(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *StatsCount) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels7(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *StatsCount) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels7(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels8(in *jlexer.Lexer, out *StatsBucketList) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(StatsBucketList, 0, 2)
+			} else {
+				*out = StatsBucketList{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v10 StatsBucket
+			(v10).UnmarshalEasyJSON(in)
+			*out = append(*out, v10)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels8(out *jwriter.Writer, in StatsBucketList) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v11, v12 := range in {
+			if v11 > 0 {
+				out.RawByte(',')
+			}
+			(v12).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v StatsBucketList) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels8(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v StatsBucketList) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels8(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *StatsBucketList) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels8(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *StatsBucketList) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels8(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels9(in *jlexer.Lexer, out *StatsBucket) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "start":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Start).UnmarshalJSON(data))
+			}
+		case "clicks":
+			out.Clicks = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels9(out *jwriter.Writer, in StatsBucket) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"start\":"
+		out.RawString(prefix[1:])
+		out.Raw((in.Start).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.Clicks))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v StatsBucket) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels9(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v StatsBucket) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels9(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *StatsBucket) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels9(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *StatsBucket) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels9(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels10(in *jlexer.Lexer, out *ShortURL) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "result":
+			out.Result = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels10(out *jwriter.Writer, in ShortURL) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"result\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Result))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ShortURL) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels10(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ShortURL) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels10(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ShortURL) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels10(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ShortURL) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels10(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels11(in *jlexer.Lexer, out *RollbackRequest) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "version":
+			out.Version = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels11(out *jwriter.Writer, in RollbackRequest) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"version\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Version))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v RollbackRequest) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels11(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v RollbackRequest) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels11(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *RollbackRequest) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels11(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *RollbackRequest) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels11(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels12(in *jlexer.Lexer, out *RequestAttributes) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "Device":
+			out.Device = string(in.String())
+		case "OS":
+			out.OS = string(in.String())
+		case "Bot":
+			out.Bot = bool(in.Bool())
+		case "AcceptLanguage":
+			out.AcceptLanguage = string(in.String())
+		case "Query":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				out.Query = make(map[string][]string)
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v13 []string
+					if in.IsNull() {
+						in.Skip()
+						v13 = nil
+					} else {
+						in.Delim('[')
+						if v13 == nil {
+							if !in.IsDelim(']') {
+								v13 = make([]string, 0, 4)
+							} else {
+								v13 = []string{}
+							}
+						} else {
+							v13 = (v13)[:0]
+						}
+						for !in.IsDelim(']') {
+							var v14 string
+							v14 = string(in.String())
+							v13 = append(v13, v14)
+							in.WantComma()
+						}
+						in.Delim(']')
+					}
+					(out.Query)[key] = v13
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels12(out *jwriter.Writer, in RequestAttributes) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"Device\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Device))
+	}
+	{
+		const prefix string = ",\"OS\":"
+		out.RawString(prefix)
+		out.String(string(in.OS))
+	}
+	{
+		const prefix string = ",\"Bot\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.Bot))
+	}
+	{
+		const prefix string = ",\"AcceptLanguage\":"
+		out.RawString(prefix)
+		out.String(string(in.AcceptLanguage))
+	}
+	{
+		const prefix string = ",\"Query\":"
+		out.RawString(prefix)
+		if in.Query == nil && (out.Flags&jwriter.NilMapAsEmpty) == 0 {
+			out.RawString(`null`)
+		} else {
+			out.RawByte('{')
+			v15First := true
+			for v15Name, v15Value := range in.Query {
+				if v15First {
+					v15First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v15Name))
+				out.RawByte(':')
+				if v15Value == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+					out.RawString("null")
+				} else {
+					out.RawByte('[')
+					for v16, v17 := range v15Value {
+						if v16 > 0 {
+							out.RawByte(',')
+						}
+						out.String(string(v17))
+					}
+					out.RawByte(']')
+				}
+			}
+			out.RawByte('}')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v RequestAttributes) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels12(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v RequestAttributes) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels12(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *RequestAttributes) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels12(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *RequestAttributes) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels12(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels13(in *jlexer.Lexer, out *Redirect) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "URL":
+			out.URL = string(in.String())
+		case "StatusCode":
+			out.StatusCode = int(in.Int())
+		case "CacheControl":
+			out.CacheControl = string(in.String())
+		case "Vary":
+			out.Vary = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels13(out *jwriter.Writer, in Redirect) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"URL\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.URL))
+	}
+	{
+		const prefix string = ",\"StatusCode\":"
+		out.RawString(prefix)
+		out.Int(int(in.StatusCode))
+	}
+	{
+		const prefix string = ",\"CacheControl\":"
+		out.RawString(prefix)
+		out.String(string(in.CacheControl))
+	}
+	{
+		const prefix string = ",\"Vary\":"
+		out.RawString(prefix)
+		out.String(string(in.Vary))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Redirect) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels13(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Redirect) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels13(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Redirect) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels13(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Redirect) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels13(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels14(in *jlexer.Lexer, out *LinkVersionList) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(LinkVersionList, 0, 1)
+			} else {
+				*out = LinkVersionList{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v18 LinkVersion
+			(v18).UnmarshalEasyJSON(in)
+			*out = append(*out, v18)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels14(out *jwriter.Writer, in LinkVersionList) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v19, v20 := range in {
+			if v19 > 0 {
+				out.RawByte(',')
+			}
+			(v20).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v LinkVersionList) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels14(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v LinkVersionList) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels14(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *LinkVersionList) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels14(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *LinkVersionList) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels14(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels15(in *jlexer.Lexer, out *LinkVersion) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "version":
+			out.Version = int(in.Int())
+		case "original_url":
+			out.URL = string(in.String())
+		case "replaced_at":
+			if in.IsNull() {
+				in.Skip()
+				out.ReplacedAt = nil
+			} else {
+				if out.ReplacedAt == nil {
+					out.ReplacedAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ReplacedAt).UnmarshalJSON(data))
+				}
+			}
+		case "current":
+			out.Current = bool(in.Bool())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels15(out *jwriter.Writer, in LinkVersion) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"version\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Version))
+	}
+	{
+		const prefix string = ",\"original_url\":"
+		out.RawString(prefix)
+		out.String(string(in.URL))
+	}
+	if in.ReplacedAt != nil {
+		const prefix string = ",\"replaced_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.ReplacedAt).MarshalJSON())
+	}
+	if in.Current {
+		const prefix string = ",\"current\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.Current))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v LinkVersion) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels15(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v LinkVersion) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels15(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *LinkVersion) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels15(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *LinkVersion) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels15(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels16(in *jlexer.Lexer, out *LinkStats) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "hash":
+			out.Hash = string(in.String())
+		case "from":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.From).UnmarshalJSON(data))
+			}
+		case "to":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.To).UnmarshalJSON(data))
+			}
+		case "bucket":
+			out.Bucket = string(in.String())
+		case "total_clicks":
+			out.TotalClicks = int(in.Int())
+		case "unique_visitors":
+			out.UniqueVisitors = int(in.Int())
+		case "series":
+			(out.Series).UnmarshalEasyJSON(in)
+		case "top_referrers":
+			(out.TopReferrers).UnmarshalEasyJSON(in)
+		case "user_agents":
+			(out.UserAgents).UnmarshalEasyJSON(in)
+		case "devices":
+			(out.Devices).UnmarshalEasyJSON(in)
+		case "rolled_up_until":
+			if in.IsNull() {
+				in.Skip()
+				out.RolledUpUntil = nil
+			} else {
+				if out.RolledUpUntil == nil {
+					out.RolledUpUntil = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.RolledUpUntil).UnmarshalJSON(data))
+				}
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels16(out *jwriter.Writer, in LinkStats) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"hash\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Hash))
+	}
+	{
+		const prefix string = ",\"from\":"
+		out.RawString(prefix)
+		out.Raw((in.From).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"to\":"
+		out.RawString(prefix)
+		out.Raw((in.To).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"bucket\":"
+		out.RawString(prefix)
+		out.String(string(in.Bucket))
+	}
+	{
+		const prefix string = ",\"total_clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.TotalClicks))
+	}
+	{
+		const prefix string = ",\"unique_visitors\":"
+		out.RawString(prefix)
+		out.Int(int(in.UniqueVisitors))
+	}
+	{
+		const prefix string = ",\"series\":"
+		out.RawString(prefix)
+		(in.Series).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"top_referrers\":"
+		out.RawString(prefix)
+		(in.TopReferrers).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"user_agents\":"
+		out.RawString(prefix)
+		(in.UserAgents).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"devices\":"
+		out.RawString(prefix)
+		(in.Devices).MarshalEasyJSON(out)
+	}
+	if in.RolledUpUntil != nil {
+		const prefix string = ",\"rolled_up_until\":"
+		out.RawString(prefix)
+		out.Raw((*in.RolledUpUntil).MarshalJSON())
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v UserLinksResponse) MarshalJSON() ([]byte, error) {
+func (v LinkStats) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels1(&w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels16(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v UserLinksResponse) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels1(w, v)
+func (v LinkStats) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels16(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *UserLinksResponse) UnmarshalJSON(data []byte) error {
+func (v *LinkStats) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels1(&r, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels16(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *UserLinksResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels1(l, v)
+func (v *LinkStats) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels16(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels2(in *jlexer.Lexer, out *ShortURL) {
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels17(in *jlexer.Lexer, out *LinkOptions) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -175,8 +1532,52 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels2(in *jlexer.Le
 			continue
 		}
 		switch key {
-		case "result":
-			out.Result = string(in.String())
+		case "expires_at":
+			if in.IsNull() {
+				in.Skip()
+				out.ExpiresAt = nil
+			} else {
+				if out.ExpiresAt == nil {
+					out.ExpiresAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ExpiresAt).UnmarshalJSON(data))
+				}
+			}
+		case "max_clicks":
+			out.MaxClicks = int(in.Int())
+		case "active_from":
+			if in.IsNull() {
+				in.Skip()
+				out.ActiveFrom = nil
+			} else {
+				if out.ActiveFrom == nil {
+					out.ActiveFrom = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ActiveFrom).UnmarshalJSON(data))
+				}
+			}
+		case "active_until":
+			if in.IsNull() {
+				in.Skip()
+				out.ActiveUntil = nil
+			} else {
+				if out.ActiveUntil == nil {
+					out.ActiveUntil = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ActiveUntil).UnmarshalJSON(data))
+				}
+			}
+		case "password_hash":
+			out.PasswordHash = string(in.String())
+		case "redirect_code":
+			out.RedirectCode = int(in.Int())
+		case "cache_max_age":
+			out.CacheMaxAge = int(in.Int())
+		case "targeting":
+			(out.Targeting).UnmarshalEasyJSON(in)
 		default:
 			in.SkipRecursive()
 		}
@@ -187,42 +1588,113 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels2(in *jlexer.Le
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels2(out *jwriter.Writer, in ShortURL) {
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels17(out *jwriter.Writer, in LinkOptions) {
 	out.RawByte('{')
 	first := true
 	_ = first
-	{
-		const prefix string = ",\"result\":"
+	if in.ExpiresAt != nil {
+		const prefix string = ",\"expires_at\":"
+		first = false
 		out.RawString(prefix[1:])
-		out.String(string(in.Result))
+		out.Raw((*in.ExpiresAt).MarshalJSON())
+	}
+	if in.MaxClicks != 0 {
+		const prefix string = ",\"max_clicks\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.MaxClicks))
+	}
+	if in.ActiveFrom != nil {
+		const prefix string = ",\"active_from\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Raw((*in.ActiveFrom).MarshalJSON())
+	}
+	if in.ActiveUntil != nil {
+		const prefix string = ",\"active_until\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Raw((*in.ActiveUntil).MarshalJSON())
+	}
+	if in.PasswordHash != "" {
+		const prefix string = ",\"password_hash\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.PasswordHash))
+	}
+	if in.RedirectCode != 0 {
+		const prefix string = ",\"redirect_code\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.RedirectCode))
+	}
+	if in.CacheMaxAge != 0 {
+		const prefix string = ",\"cache_max_age\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.CacheMaxAge))
+	}
+	if len(in.Targeting) != 0 {
+		const prefix string = ",\"targeting\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		(in.Targeting).MarshalEasyJSON(out)
 	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v ShortURL) MarshalJSON() ([]byte, error) {
+func (v LinkOptions) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels2(&w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels17(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v ShortURL) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels2(w, v)
+func (v LinkOptions) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels17(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *ShortURL) UnmarshalJSON(data []byte) error {
+func (v *LinkOptions) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels2(&r, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels17(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *ShortURL) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels2(l, v)
+func (v *LinkOptions) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels17(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels3(in *jlexer.Lexer, out *IDList) {
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels18(in *jlexer.Lexer, out *IDList) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -257,70 +1729,309 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels3(in *jlexer.Le
 					out.IDs = (out.IDs)[:0]
 				}
 				for !in.IsDelim(']') {
-					var v4 string
-					v4 = string(in.String())
-					out.IDs = append(out.IDs, v4)
+					var v21 string
+					v21 = string(in.String())
+					out.IDs = append(out.IDs, v21)
 					in.WantComma()
 				}
 				in.Delim(']')
 			}
-		default:
-			in.SkipRecursive()
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels18(out *jwriter.Writer, in IDList) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"ids\":"
+		out.RawString(prefix[1:])
+		if in.IDs == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v22, v23 := range in.IDs {
+				if v22 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v23))
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v IDList) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels18(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v IDList) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels18(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *IDList) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels18(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *IDList) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels18(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels19(in *jlexer.Lexer, out *FullURL) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "url":
+			out.URL = string(in.String())
+		case "alias":
+			out.Alias = string(in.String())
+		case "password":
+			out.Password = string(in.String())
+		case "one_time":
+			out.OneTime = bool(in.Bool())
+		case "expires_at":
+			if in.IsNull() {
+				in.Skip()
+				out.ExpiresAt = nil
+			} else {
+				if out.ExpiresAt == nil {
+					out.ExpiresAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ExpiresAt).UnmarshalJSON(data))
+				}
+			}
+		case "max_clicks":
+			out.MaxClicks = int(in.Int())
+		case "active_from":
+			if in.IsNull() {
+				in.Skip()
+				out.ActiveFrom = nil
+			} else {
+				if out.ActiveFrom == nil {
+					out.ActiveFrom = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ActiveFrom).UnmarshalJSON(data))
+				}
+			}
+		case "active_until":
+			if in.IsNull() {
+				in.Skip()
+				out.ActiveUntil = nil
+			} else {
+				if out.ActiveUntil == nil {
+					out.ActiveUntil = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ActiveUntil).UnmarshalJSON(data))
+				}
+			}
+		case "password_hash":
+			out.PasswordHash = string(in.String())
+		case "redirect_code":
+			out.RedirectCode = int(in.Int())
+		case "cache_max_age":
+			out.CacheMaxAge = int(in.Int())
+		case "targeting":
+			(out.Targeting).UnmarshalEasyJSON(in)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels19(out *jwriter.Writer, in FullURL) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"url\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.URL))
+	}
+	if in.Alias != "" {
+		const prefix string = ",\"alias\":"
+		out.RawString(prefix)
+		out.String(string(in.Alias))
+	}
+	if in.Password != "" {
+		const prefix string = ",\"password\":"
+		out.RawString(prefix)
+		out.String(string(in.Password))
+	}
+	if in.OneTime {
+		const prefix string = ",\"one_time\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.OneTime))
+	}
+	if in.ExpiresAt != nil {
+		const prefix string = ",\"expires_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.ExpiresAt).MarshalJSON())
+	}
+	if in.MaxClicks != 0 {
+		const prefix string = ",\"max_clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.MaxClicks))
+	}
+	if in.ActiveFrom != nil {
+		const prefix string = ",\"active_from\":"
+		out.RawString(prefix)
+		out.Raw((*in.ActiveFrom).MarshalJSON())
+	}
+	if in.ActiveUntil != nil {
+		const prefix string = ",\"active_until\":"
+		out.RawString(prefix)
+		out.Raw((*in.ActiveUntil).MarshalJSON())
+	}
+	if in.PasswordHash != "" {
+		const prefix string = ",\"password_hash\":"
+		out.RawString(prefix)
+		out.String(string(in.PasswordHash))
+	}
+	if in.RedirectCode != 0 {
+		const prefix string = ",\"redirect_code\":"
+		out.RawString(prefix)
+		out.Int(int(in.RedirectCode))
+	}
+	if in.CacheMaxAge != 0 {
+		const prefix string = ",\"cache_max_age\":"
+		out.RawString(prefix)
+		out.Int(int(in.CacheMaxAge))
+	}
+	if len(in.Targeting) != 0 {
+		const prefix string = ",\"targeting\":"
+		out.RawString(prefix)
+		(in.Targeting).MarshalEasyJSON(out)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v FullURL) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels19(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v FullURL) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels19(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *FullURL) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels19(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *FullURL) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels19(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels20(in *jlexer.Lexer, out *DBShortenRowList) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(DBShortenRowList, 0, 0)
+			} else {
+				*out = DBShortenRowList{}
+			}
+		} else {
+			*out = (*out)[:0]
 		}
-		in.WantComma()
+		for !in.IsDelim(']') {
+			var v24 DBShortenRow
+			(v24).UnmarshalEasyJSON(in)
+			*out = append(*out, v24)
+			in.WantComma()
+		}
+		in.Delim(']')
 	}
-	in.Delim('}')
 	if isTopLevel {
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels3(out *jwriter.Writer, in IDList) {
-	out.RawByte('{')
-	first := true
-	_ = first
-	{
-		const prefix string = ",\"ids\":"
-		out.RawString(prefix[1:])
-		if in.IDs == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
-			out.RawString("null")
-		} else {
-			out.RawByte('[')
-			for v5, v6 := range in.IDs {
-				if v5 > 0 {
-					out.RawByte(',')
-				}
-				out.String(string(v6))
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels20(out *jwriter.Writer, in DBShortenRowList) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v25, v26 := range in {
+			if v25 > 0 {
+				out.RawByte(',')
 			}
-			out.RawByte(']')
+			(v26).MarshalEasyJSON(out)
 		}
+		out.RawByte(']')
 	}
-	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v IDList) MarshalJSON() ([]byte, error) {
+func (v DBShortenRowList) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels3(&w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels20(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v IDList) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels3(w, v)
+func (v DBShortenRowList) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels20(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *IDList) UnmarshalJSON(data []byte) error {
+func (v *DBShortenRowList) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels3(&r, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels20(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *IDList) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels3(l, v)
+func (v *DBShortenRowList) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels20(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels4(in *jlexer.Lexer, out *FullURL) {
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels21(in *jlexer.Lexer, out *DBShortenRow) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -339,8 +2050,74 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels4(in *jlexer.Le
 			continue
 		}
 		switch key {
+		case "id":
+			out.ID = int(in.Int())
+		case "hash":
+			out.Hash = string(in.String())
 		case "url":
 			out.URL = string(in.String())
+		case "time":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Time).UnmarshalJSON(data))
+			}
+		case "user_id":
+			out.UserID = string(in.String())
+		case "is_deleted":
+			out.IsDeleted = bool(in.Bool())
+		case "clicks":
+			out.Clicks = int(in.Int())
+		case "is_expired":
+			out.IsExpired = bool(in.Bool())
+		case "version":
+			out.Version = int(in.Int())
+		case "history":
+			(out.History).UnmarshalEasyJSON(in)
+		case "expires_at":
+			if in.IsNull() {
+				in.Skip()
+				out.ExpiresAt = nil
+			} else {
+				if out.ExpiresAt == nil {
+					out.ExpiresAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ExpiresAt).UnmarshalJSON(data))
+				}
+			}
+		case "max_clicks":
+			out.MaxClicks = int(in.Int())
+		case "active_from":
+			if in.IsNull() {
+				in.Skip()
+				out.ActiveFrom = nil
+			} else {
+				if out.ActiveFrom == nil {
+					out.ActiveFrom = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ActiveFrom).UnmarshalJSON(data))
+				}
+			}
+		case "active_until":
+			if in.IsNull() {
+				in.Skip()
+				out.ActiveUntil = nil
+			} else {
+				if out.ActiveUntil == nil {
+					out.ActiveUntil = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ActiveUntil).UnmarshalJSON(data))
+				}
+			}
+		case "password_hash":
+			out.PasswordHash = string(in.String())
+		case "redirect_code":
+			out.RedirectCode = int(in.Int())
+		case "cache_max_age":
+			out.CacheMaxAge = int(in.Int())
+		case "targeting":
+			(out.Targeting).UnmarshalEasyJSON(in)
 		default:
 			in.SkipRecursive()
 		}
@@ -351,42 +2128,127 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels4(in *jlexer.Le
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels4(out *jwriter.Writer, in FullURL) {
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels21(out *jwriter.Writer, in DBShortenRow) {
 	out.RawByte('{')
 	first := true
 	_ = first
 	{
-		const prefix string = ",\"url\":"
+		const prefix string = ",\"id\":"
 		out.RawString(prefix[1:])
+		out.Int(int(in.ID))
+	}
+	{
+		const prefix string = ",\"hash\":"
+		out.RawString(prefix)
+		out.String(string(in.Hash))
+	}
+	{
+		const prefix string = ",\"url\":"
+		out.RawString(prefix)
 		out.String(string(in.URL))
 	}
+	{
+		const prefix string = ",\"time\":"
+		out.RawString(prefix)
+		out.Raw((in.Time).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"user_id\":"
+		out.RawString(prefix)
+		out.String(string(in.UserID))
+	}
+	{
+		const prefix string = ",\"is_deleted\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.IsDeleted))
+	}
+	if in.Clicks != 0 {
+		const prefix string = ",\"clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.Clicks))
+	}
+	if in.IsExpired {
+		const prefix string = ",\"is_expired\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.IsExpired))
+	}
+	if in.Version != 0 {
+		const prefix string = ",\"version\":"
+		out.RawString(prefix)
+		out.Int(int(in.Version))
+	}
+	if len(in.History) != 0 {
+		const prefix string = ",\"history\":"
+		out.RawString(prefix)
+		(in.History).MarshalEasyJSON(out)
+	}
+	if in.ExpiresAt != nil {
+		const prefix string = ",\"expires_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.ExpiresAt).MarshalJSON())
+	}
+	if in.MaxClicks != 0 {
+		const prefix string = ",\"max_clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.MaxClicks))
+	}
+	if in.ActiveFrom != nil {
+		const prefix string = ",\"active_from\":"
+		out.RawString(prefix)
+		out.Raw((*in.ActiveFrom).MarshalJSON())
+	}
+	if in.ActiveUntil != nil {
+		const prefix string = ",\"active_until\":"
+		out.RawString(prefix)
+		out.Raw((*in.ActiveUntil).MarshalJSON())
+	}
+	if in.PasswordHash != "" {
+		const prefix string = ",\"password_hash\":"
+		out.RawString(prefix)
+		out.String(string(in.PasswordHash))
+	}
+	if in.RedirectCode != 0 {
+		const prefix string = ",\"redirect_code\":"
+		out.RawString(prefix)
+		out.Int(int(in.RedirectCode))
+	}
+	if in.CacheMaxAge != 0 {
+		const prefix string = ",\"cache_max_age\":"
+		out.RawString(prefix)
+		out.Int(int(in.CacheMaxAge))
+	}
+	if len(in.Targeting) != 0 {
+		const prefix string = ",\"targeting\":"
+		out.RawString(prefix)
+		(in.Targeting).MarshalEasyJSON(out)
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v FullURL) MarshalJSON() ([]byte, error) {
+func (v DBShortenRow) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels4(&w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels21(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v FullURL) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels4(w, v)
+func (v DBShortenRow) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels21(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *FullURL) UnmarshalJSON(data []byte) error {
+func (v *DBShortenRow) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels4(&r, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels21(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *FullURL) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels4(l, v)
+func (v *DBShortenRow) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels21(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels5(in *jlexer.Lexer, out *DBShortenRowList) {
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels22(in *jlexer.Lexer, out *ClickEventList) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		in.Skip()
@@ -395,17 +2257,17 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels5(in *jlexer.Le
 		in.Delim('[')
 		if *out == nil {
 			if !in.IsDelim(']') {
-				*out = make(DBShortenRowList, 0, 0)
+				*out = make(ClickEventList, 0, 0)
 			} else {
-				*out = DBShortenRowList{}
+				*out = ClickEventList{}
 			}
 		} else {
 			*out = (*out)[:0]
 		}
 		for !in.IsDelim(']') {
-			var v7 DBShortenRow
-			(v7).UnmarshalEasyJSON(in)
-			*out = append(*out, v7)
+			var v27 ClickEvent
+			(v27).UnmarshalEasyJSON(in)
+			*out = append(*out, v27)
 			in.WantComma()
 		}
 		in.Delim(']')
@@ -414,45 +2276,45 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels5(in *jlexer.Le
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels5(out *jwriter.Writer, in DBShortenRowList) {
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels22(out *jwriter.Writer, in ClickEventList) {
 	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
 		out.RawString("null")
 	} else {
 		out.RawByte('[')
-		for v8, v9 := range in {
-			if v8 > 0 {
+		for v28, v29 := range in {
+			if v28 > 0 {
 				out.RawByte(',')
 			}
-			(v9).MarshalEasyJSON(out)
+			(v29).MarshalEasyJSON(out)
 		}
 		out.RawByte(']')
 	}
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v DBShortenRowList) MarshalJSON() ([]byte, error) {
+func (v ClickEventList) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels5(&w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels22(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v DBShortenRowList) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels5(w, v)
+func (v ClickEventList) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels22(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *DBShortenRowList) UnmarshalJSON(data []byte) error {
+func (v *ClickEventList) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels5(&r, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels22(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *DBShortenRowList) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels5(l, v)
+func (v *ClickEventList) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels22(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels6(in *jlexer.Lexer, out *DBShortenRow) {
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels23(in *jlexer.Lexer, out *ClickEvent) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -471,20 +2333,24 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels6(in *jlexer.Le
 			continue
 		}
 		switch key {
-		case "id":
-			out.ID = int(in.Int())
 		case "hash":
 			out.Hash = string(in.String())
-		case "url":
-			out.URL = string(in.String())
 		case "time":
 			if data := in.Raw(); in.Ok() {
 				in.AddError((out.Time).UnmarshalJSON(data))
 			}
-		case "user_id":
-			out.UserID = string(in.String())
-		case "is_deleted":
-			out.IsDeleted = bool(in.Bool())
+		case "referrer":
+			out.Referrer = string(in.String())
+		case "user_agent":
+			out.UserAgent = string(in.String())
+		case "ip":
+			out.IP = string(in.String())
+		case "device":
+			out.Device = string(in.String())
+		case "os":
+			out.OS = string(in.String())
+		case "browser":
+			out.Browser = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -495,67 +2361,77 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels6(in *jlexer.Le
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels6(out *jwriter.Writer, in DBShortenRow) {
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels23(out *jwriter.Writer, in ClickEvent) {
 	out.RawByte('{')
 	first := true
 	_ = first
 	{
-		const prefix string = ",\"id\":"
+		const prefix string = ",\"hash\":"
 		out.RawString(prefix[1:])
-		out.Int(int(in.ID))
+		out.String(string(in.Hash))
 	}
 	{
-		const prefix string = ",\"hash\":"
+		const prefix string = ",\"time\":"
 		out.RawString(prefix)
-		out.String(string(in.Hash))
+		out.Raw((in.Time).MarshalJSON())
 	}
-	{
-		const prefix string = ",\"url\":"
+	if in.Referrer != "" {
+		const prefix string = ",\"referrer\":"
 		out.RawString(prefix)
-		out.String(string(in.URL))
+		out.String(string(in.Referrer))
 	}
-	{
-		const prefix string = ",\"time\":"
+	if in.UserAgent != "" {
+		const prefix string = ",\"user_agent\":"
 		out.RawString(prefix)
-		out.Raw((in.Time).MarshalJSON())
+		out.String(string(in.UserAgent))
 	}
-	{
-		const prefix string = ",\"user_id\":"
+	if in.IP != "" {
+		const prefix string = ",\"ip\":"
 		out.RawString(prefix)
-		out.String(string(in.UserID))
+		out.String(string(in.IP))
 	}
-	{
-		const prefix string = ",\"is_deleted\":"
+	if in.Device != "" {
+		const prefix string = ",\"device\":"
 		out.RawString(prefix)
-		out.Bool(bool(in.IsDeleted))
+		out.String(string(in.Device))
+	}
+	if in.OS != "" {
+		const prefix string = ",\"os\":"
+		out.RawString(prefix)
+		out.String(string(in.OS))
+	}
+	if in.Browser != "" {
+		const prefix string = ",\"browser\":"
+		out.RawString(prefix)
+		out.String(string(in.Browser))
 	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v DBShortenRow) MarshalJSON() ([]byte, error) {
+func (v ClickEvent) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels6(&w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels23(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v DBShortenRow) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels6(w, v)
+func (v ClickEvent) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels23(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *DBShortenRow) UnmarshalJSON(data []byte) error {
+func (v *ClickEvent) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels6(&r, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels23(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *DBShortenRow) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels6(l, v)
+func (v *ClickEvent) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels23(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels7(in *jlexer.Lexer, out *BatchShortenResponseList) {
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels24(in *jlexer.Lexer, out *BatchShortenResponseList) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		in.Skip()
@@ -572,9 +2448,9 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels7(in *jlexer.Le
 			*out = (*out)[:0]
 		}
 		for !in.IsDelim(']') {
-			var v10 BatchShortenResponse
-			(v10).UnmarshalEasyJSON(in)
-			*out = append(*out, v10)
+			var v30 BatchShortenResponse
+			(v30).UnmarshalEasyJSON(in)
+			*out = append(*out, v30)
 			in.WantComma()
 		}
 		in.Delim(']')
@@ -583,16 +2459,16 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels7(in *jlexer.Le
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels7(out *jwriter.Writer, in BatchShortenResponseList) {
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels24(out *jwriter.Writer, in BatchShortenResponseList) {
 	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
 		out.RawString("null")
 	} else {
 		out.RawByte('[')
-		for v11, v12 := range in {
-			if v11 > 0 {
+		for v31, v32 := range in {
+			if v31 > 0 {
 				out.RawByte(',')
 			}
-			(v12).MarshalEasyJSON(out)
+			(v32).MarshalEasyJSON(out)
 		}
 		out.RawByte(']')
 	}
@@ -601,27 +2477,27 @@ func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels7(out *jwriter.
 // MarshalJSON supports json.Marshaler interface
 func (v BatchShortenResponseList) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels7(&w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels24(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v BatchShortenResponseList) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels7(w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels24(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *BatchShortenResponseList) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels7(&r, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels24(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *BatchShortenResponseList) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels7(l, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels24(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels8(in *jlexer.Lexer, out *BatchShortenResponse) {
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels25(in *jlexer.Lexer, out *BatchShortenResponse) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -654,7 +2530,7 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels8(in *jlexer.Le
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels8(out *jwriter.Writer, in BatchShortenResponse) {
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels25(out *jwriter.Writer, in BatchShortenResponse) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -674,27 +2550,27 @@ func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels8(out *jwriter.
 // MarshalJSON supports json.Marshaler interface
 func (v BatchShortenResponse) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels8(&w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels25(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v BatchShortenResponse) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels8(w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels25(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *BatchShortenResponse) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels8(&r, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels25(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *BatchShortenResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels8(l, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels25(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels9(in *jlexer.Lexer, out *BatchShortenRequestList) {
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels26(in *jlexer.Lexer, out *BatchShortenRequestList) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		in.Skip()
@@ -703,7 +2579,7 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels9(in *jlexer.Le
 		in.Delim('[')
 		if *out == nil {
 			if !in.IsDelim(']') {
-				*out = make(BatchShortenRequestList, 0, 2)
+				*out = make(BatchShortenRequestList, 0, 0)
 			} else {
 				*out = BatchShortenRequestList{}
 			}
@@ -711,9 +2587,9 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels9(in *jlexer.Le
 			*out = (*out)[:0]
 		}
 		for !in.IsDelim(']') {
-			var v13 BatchShortenRequest
-			(v13).UnmarshalEasyJSON(in)
-			*out = append(*out, v13)
+			var v33 BatchShortenRequest
+			(v33).UnmarshalEasyJSON(in)
+			*out = append(*out, v33)
 			in.WantComma()
 		}
 		in.Delim(']')
@@ -722,16 +2598,16 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels9(in *jlexer.Le
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels9(out *jwriter.Writer, in BatchShortenRequestList) {
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels26(out *jwriter.Writer, in BatchShortenRequestList) {
 	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
 		out.RawString("null")
 	} else {
 		out.RawByte('[')
-		for v14, v15 := range in {
-			if v14 > 0 {
+		for v34, v35 := range in {
+			if v34 > 0 {
 				out.RawByte(',')
 			}
-			(v15).MarshalEasyJSON(out)
+			(v35).MarshalEasyJSON(out)
 		}
 		out.RawByte(']')
 	}
@@ -740,27 +2616,27 @@ func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels9(out *jwriter.
 // MarshalJSON supports json.Marshaler interface
 func (v BatchShortenRequestList) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels9(&w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels26(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v BatchShortenRequestList) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels9(w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels26(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *BatchShortenRequestList) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels9(&r, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels26(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *BatchShortenRequestList) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels9(l, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels26(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels10(in *jlexer.Lexer, out *BatchShortenRequest) {
+func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels27(in *jlexer.Lexer, out *BatchShortenRequest) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -783,6 +2659,58 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels10(in *jlexer.L
 			out.ID = string(in.String())
 		case "original_url":
 			out.URL = string(in.String())
+		case "alias":
+			out.Alias = string(in.String())
+		case "password":
+			out.Password = string(in.String())
+		case "one_time":
+			out.OneTime = bool(in.Bool())
+		case "expires_at":
+			if in.IsNull() {
+				in.Skip()
+				out.ExpiresAt = nil
+			} else {
+				if out.ExpiresAt == nil {
+					out.ExpiresAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ExpiresAt).UnmarshalJSON(data))
+				}
+			}
+		case "max_clicks":
+			out.MaxClicks = int(in.Int())
+		case "active_from":
+			if in.IsNull() {
+				in.Skip()
+				out.ActiveFrom = nil
+			} else {
+				if out.ActiveFrom == nil {
+					out.ActiveFrom = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ActiveFrom).UnmarshalJSON(data))
+				}
+			}
+		case "active_until":
+			if in.IsNull() {
+				in.Skip()
+				out.ActiveUntil = nil
+			} else {
+				if out.ActiveUntil == nil {
+					out.ActiveUntil = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ActiveUntil).UnmarshalJSON(data))
+				}
+			}
+		case "password_hash":
+			out.PasswordHash = string(in.String())
+		case "redirect_code":
+			out.RedirectCode = int(in.Int())
+		case "cache_max_age":
+			out.CacheMaxAge = int(in.Int())
+		case "targeting":
+			(out.Targeting).UnmarshalEasyJSON(in)
 		default:
 			in.SkipRecursive()
 		}
@@ -793,7 +2721,7 @@ func easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels10(in *jlexer.L
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels10(out *jwriter.Writer, in BatchShortenRequest) {
+func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels27(out *jwriter.Writer, in BatchShortenRequest) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -807,29 +2735,84 @@ func easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels10(out *jwriter
 		out.RawString(prefix)
 		out.String(string(in.URL))
 	}
+	if in.Alias != "" {
+		const prefix string = ",\"alias\":"
+		out.RawString(prefix)
+		out.String(string(in.Alias))
+	}
+	if in.Password != "" {
+		const prefix string = ",\"password\":"
+		out.RawString(prefix)
+		out.String(string(in.Password))
+	}
+	if in.OneTime {
+		const prefix string = ",\"one_time\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.OneTime))
+	}
+	if in.ExpiresAt != nil {
+		const prefix string = ",\"expires_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.ExpiresAt).MarshalJSON())
+	}
+	if in.MaxClicks != 0 {
+		const prefix string = ",\"max_clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.MaxClicks))
+	}
+	if in.ActiveFrom != nil {
+		const prefix string = ",\"active_from\":"
+		out.RawString(prefix)
+		out.Raw((*in.ActiveFrom).MarshalJSON())
+	}
+	if in.ActiveUntil != nil {
+		const prefix string = ",\"active_until\":"
+		out.RawString(prefix)
+		out.Raw((*in.ActiveUntil).MarshalJSON())
+	}
+	if in.PasswordHash != "" {
+		const prefix string = ",\"password_hash\":"
+		out.RawString(prefix)
+		out.String(string(in.PasswordHash))
+	}
+	if in.RedirectCode != 0 {
+		const prefix string = ",\"redirect_code\":"
+		out.RawString(prefix)
+		out.Int(int(in.RedirectCode))
+	}
+	if in.CacheMaxAge != 0 {
+		const prefix string = ",\"cache_max_age\":"
+		out.RawString(prefix)
+		out.Int(int(in.CacheMaxAge))
+	}
+	if len(in.Targeting) != 0 {
+		const prefix string = ",\"targeting\":"
+		out.RawString(prefix)
+		(in.Targeting).MarshalEasyJSON(out)
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v BatchShortenRequest) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels10(&w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels27(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v BatchShortenRequest) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels10(w, v)
+	easyjsonD2b7633eEncodeGithubComThxhixShortenerInternalModels27(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *BatchShortenRequest) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels10(&r, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels27(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *BatchShortenRequest) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels10(l, v)
+	easyjsonD2b7633eDecodeGithubComThxhixShortenerInternalModels27(l, v)
 }
@@ -7,6 +7,7 @@ import (
 	handle "github.com/thxhix/shortener/internal/handlers"
 	"github.com/thxhix/shortener/internal/middleware"
 	"github.com/thxhix/shortener/internal/url"
+	"github.com/thxhix/shortener/internal/useragent"
 	"go.uber.org/zap"
 )
 
@@ -18,12 +19,26 @@ import (
 //
 //   - GET    /{id}           → Redirect to original URL
 //
+//   - POST   /{id}           → Redirect to original URL after the password form
+//
 //   - GET    /ping           → Ping database
 //
 //   - GET    /api/user/urls  → List user links
 //
 //   - DELETE /api/user/urls  → Delete user links
 //
+//   - PATCH  /api/user/urls/{id}           → Change the destination of a user link
+//
+//   - GET    /api/user/urls/{id}/versions  → List destinations of a user link
+//
+//   - POST   /api/user/urls/{id}/rollback  → Return a user link to an earlier destination
+//
+//   - GET    /api/user/urls/{id}/stats     → Click statistics of a user link
+//
+//   - GET    /api/user/urls/{id}/targeting → List targeting rules of a user link
+//
+//   - PUT    /api/user/urls/{id}/targeting → Replace targeting rules of a user link
+//
 //   - POST   /api/shorten          → Store a short link via API
 //
 //   - POST   /api/shorten/batch    → Store multiple links via API
@@ -32,11 +47,20 @@ import (
 //   - WithLogging: request logging using zap logger
 //   - CompressorMiddleware: response compression
 //   - Auth: authentication based on SecretKey
-func NewRouter(cfg *config.Config, db interfaces.Database, logger *zap.SugaredLogger) *chi.Mux {
-	uc := url.NewURLUseCase(db, *cfg)
+//
+// Short codes for new links are taken from the generator. User agents
+// of redirects and of stored click events are classified by the agents
+// parser, the bundled rules are used if it is nil. The options are passed
+// to the handlers, e.g. to track redirects.
+func NewRouter(cfg *config.Config, db interfaces.Database, generator url.CodeGenerator, agents *useragent.Parser, logger *zap.SugaredLogger, opts ...handle.Option) *chi.Mux {
+	if agents == nil {
+		agents = useragent.Default()
+	}
+	uc := url.NewURLUseCase(db, *cfg, generator, url.WithUserAgentParser(agents))
 
 	router := chi.NewRouter()
-	handlers := handle.NewHandler(cfg, uc)
+	opts = append([]handle.Option{handle.WithUserAgentParser(agents)}, opts...)
+	handlers := handle.NewHandler(cfg, uc, opts...)
 
 	router.Route("/", func(r chi.Router) {
 		// Кидаем на группу мидлвару с логами
@@ -46,6 +70,7 @@ func NewRouter(cfg *config.Config, db interfaces.Database, logger *zap.SugaredLo
 
 		r.Post("/", handlers.StoreLink)
 		r.Get("/{id}", handlers.Redirect)
+		r.Post("/{id}", handlers.Redirect)
 		r.Get("/ping", handlers.PingDatabase)
 
 		r.Route("/api", func(r chi.Router) {
@@ -53,6 +78,12 @@ func NewRouter(cfg *config.Config, db interfaces.Database, logger *zap.SugaredLo
 			r.Route("/user", func(r chi.Router) {
 				r.Get("/urls", handlers.UserList)
 				r.Delete("/urls", handlers.UserDeleteRows)
+				r.Patch("/urls/{id}", handlers.UpdateLink)
+				r.Get("/urls/{id}/versions", handlers.LinkVersions)
+				r.Post("/urls/{id}/rollback", handlers.RollbackLink)
+				r.Get("/urls/{id}/stats", handlers.LinkStats)
+				r.Get("/urls/{id}/targeting", handlers.LinkTargeting)
+				r.Put("/urls/{id}/targeting", handlers.UpdateLinkTargeting)
 			})
 
 			r.Route("/shorten", func(r chi.Router) {
@@ -1,14 +1,23 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/thxhix/shortener/internal/config"
 	"github.com/thxhix/shortener/internal/database/interfaces"
 	"go.uber.org/zap"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout bounds the graceful shutdown of the HTTP server.
+const shutdownTimeout = 10 * time.Second
+
 // ServerInterface the contract for running the HTTP server.
 type ServerInterface interface {
 	// StartPooling starts the HTTP server and begins listening for requests.
@@ -40,6 +49,10 @@ func NewServer(config config.Config, router chi.Mux, db interfaces.Database, log
 // If profiling is enabled in the configuration, a separate pprof server is
 // started on the ProfilerAddress in a separate goroutine. The method blocks
 // until the main HTTP server exits or encounters an error.
+//
+// On SIGINT or SIGTERM the server stops accepting connections, waits
+// up to shutdownTimeout for active requests and returns nil, so that
+// the caller can flush and close its resources.
 func (s *Server) StartPooling() error {
 	s.logger.Info("* * * Запускаюсь * * *")
 	s.logger.Infof("Адрес: %s", s.config.Address)
@@ -59,9 +72,29 @@ func (s *Server) StartPooling() error {
 		}()
 	}
 
-	if err := http.ListenAndServe(s.config.Address, s.router); err != nil {
+	server := &http.Server{Addr: s.config.Address, Handler: s.router}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
 		return err
+	case <-ctx.Done():
 	}
 
+	s.logger.Info("Останавливаюсь...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,57 @@
+package url
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// MinAliasLength is the minimal length of a custom alias.
+	MinAliasLength = 3
+
+	// MaxAliasLength is the maximal length of a custom alias.
+	MaxAliasLength = 32
+)
+
+// reservedAliases are the first path segments taken by the service routes.
+// Aliases are compared with them case-insensitively.
+var reservedAliases = map[string]struct{}{
+	"api":   {},
+	"ping":  {},
+	"debug": {},
+}
+
+// ErrInvalidAlias is returned when a custom alias fails validation.
+var ErrInvalidAlias = errors.New("некорректный alias")
+
+// ErrAliasTaken is returned when a custom alias is already used by another link.
+var ErrAliasTaken = errors.New("такой alias уже занят")
+
+// ValidateAlias checks that the alias consists of latin letters, digits,
+// '-' and '_', fits between MinAliasLength and MaxAliasLength and is not
+// a reserved word. The returned error wraps ErrInvalidAlias.
+func ValidateAlias(alias string) error {
+	if len(alias) < MinAliasLength || len(alias) > MaxAliasLength {
+		return fmt.Errorf("%w: длина должна быть от %d до %d символов", ErrInvalidAlias, MinAliasLength, MaxAliasLength)
+	}
+
+	for i := 0; i < len(alias); i++ {
+		c := alias[i]
+		if !isAliasChar(c) {
+			return fmt.Errorf("%w: недопустимый символ %q", ErrInvalidAlias, c)
+		}
+	}
+
+	if _, reserved := reservedAliases[strings.ToLower(alias)]; reserved {
+		return fmt.Errorf("%w: %q зарезервирован", ErrInvalidAlias, alias)
+	}
+	return nil
+}
+
+func isAliasChar(c byte) bool {
+	return c >= 'a' && c <= 'z' ||
+		c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9' ||
+		c == '-' || c == '_'
+}
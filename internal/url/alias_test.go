@@ -0,0 +1,39 @@
+package url
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name  string
+		alias string
+		valid bool
+	}{
+		{name: "буквы и цифры", alias: "promo2024", valid: true},
+		{name: "дефис и подчёркивание", alias: "my-link_1", valid: true},
+		{name: "минимальная длина", alias: "abc", valid: true},
+		{name: "максимальная длина", alias: strings.Repeat("a", MaxAliasLength), valid: true},
+		{name: "слишком короткий", alias: "ab"},
+		{name: "слишком длинный", alias: strings.Repeat("a", MaxAliasLength+1)},
+		{name: "пробел", alias: "my link"},
+		{name: "слэш", alias: "api/v1"},
+		{name: "кириллица", alias: "ссылка"},
+		{name: "зарезервирован ping", alias: "ping"},
+		{name: "зарезервирован api в другом регистре", alias: "API"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAlias(tt.alias)
+			if tt.valid {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrInvalidAlias)
+		})
+	}
+}
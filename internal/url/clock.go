@@ -0,0 +1,30 @@
+package url
+
+import "time"
+
+// Clock tells the current time to URLUseCase, so that time-dependent
+// behaviour such as expiration and activation windows can be tested
+// deterministically.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// systemClock is the Clock of the wall time.
+type systemClock struct{}
+
+// Now returns time.Now().
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Option customizes URLUseCase.
+type Option func(*URLUseCase)
+
+// WithClock makes URLUseCase take the current time from the clock
+// instead of the system one.
+func WithClock(clock Clock) Option {
+	return func(u *URLUseCase) {
+		u.clock = clock
+	}
+}
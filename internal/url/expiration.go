@@ -0,0 +1,107 @@
+package url
+
+import (
+	"errors"
+	"fmt"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+	"slices"
+	"time"
+)
+
+// ErrLinkExpired is returned when a link has passed its expiration time
+// or has run out of clicks. Unlike ErrLinkDeleted, it is not caused by the owner.
+var ErrLinkExpired = customErrors.ErrLinkExpired
+
+// ErrLinkNotActive is returned when a link is requested before its ActiveFrom.
+var ErrLinkNotActive = errors.New("ссылка ещё не активна")
+
+// ErrLimitedForBots is returned when a bot requests a link with MaxClicks.
+// Bots do not use up the clicks, so they are not given the destination either.
+var ErrLimitedForBots = errors.New("ссылка с ограничением переходов не открывается ботам")
+
+// ErrDuplicateOptions is returned when the original URL is already shortened
+// with other limits or another password than requested. The existing link
+// is not returned, so that a request for a protected link never gets
+// an unprotected one.
+var ErrDuplicateOptions = errors.New("ссылка на этот адрес уже существует с другими ограничениями")
+
+// ErrInvalidLinkOptions is returned when the limits of a new link are invalid.
+var ErrInvalidLinkOptions = errors.New("некорректные ограничения ссылки")
+
+// ValidateLinkOptions checks that the expiration time and the end of the active
+// window are in the future, the window does not end before it starts and the click
+// limit is not negative, as well as the redirect settings checked by ValidateRedirect
+// and the targeting rules checked by ValidateTargeting.
+// The returned error wraps ErrInvalidLinkOptions.
+func ValidateLinkOptions(opts models.LinkOptions, now time.Time) error {
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: expires_at должен быть в будущем", ErrInvalidLinkOptions)
+	}
+	if opts.ActiveUntil != nil && !opts.ActiveUntil.After(now) {
+		return fmt.Errorf("%w: active_until должен быть в будущем", ErrInvalidLinkOptions)
+	}
+	if opts.ActiveFrom != nil && opts.ActiveUntil != nil && !opts.ActiveUntil.After(*opts.ActiveFrom) {
+		return fmt.Errorf("%w: active_until должен быть позже active_from", ErrInvalidLinkOptions)
+	}
+	if opts.MaxClicks < 0 {
+		return fmt.Errorf("%w: max_clicks не может быть отрицательным", ErrInvalidLinkOptions)
+	}
+	if err := ValidateRedirect(opts); err != nil {
+		return err
+	}
+	return ValidateTargeting(opts.Targeting)
+}
+
+// linkOptions validates the requested limits, turns a one-time link into
+// a link with a single click and replaces the password hash sent by the client
+// with the hash of the password, if any.
+func linkOptions(opts models.LinkOptions, password string, oneTime bool, now time.Time) (models.LinkOptions, error) {
+	if err := ValidateLinkOptions(opts, now); err != nil {
+		return models.LinkOptions{}, err
+	}
+	if oneTime {
+		if opts.MaxClicks > 1 {
+			return models.LinkOptions{}, fmt.Errorf("%w: one_time несовместим с max_clicks больше 1", ErrInvalidLinkOptions)
+		}
+		opts.MaxClicks = 1
+	}
+
+	opts.PasswordHash = ""
+	if password != "" {
+		hash, err := hashPassword(password)
+		if err != nil {
+			return models.LinkOptions{}, err
+		}
+		opts.PasswordHash = hash
+	}
+	return opts, nil
+}
+
+// sameOptions reports whether the existing link has the requested limits,
+// redirect settings, targeting rules and password. The password is compared
+// in plain text, since its hashes are salted.
+func sameOptions(existing models.LinkOptions, requested models.LinkOptions, password string) bool {
+	if (existing.PasswordHash == "") != (password == "") {
+		return false
+	}
+	if password != "" && !checkPassword(existing.PasswordHash, password) {
+		return false
+	}
+	return sameTime(existing.ExpiresAt, requested.ExpiresAt) &&
+		sameTime(existing.ActiveFrom, requested.ActiveFrom) &&
+		sameTime(existing.ActiveUntil, requested.ActiveUntil) &&
+		existing.MaxClicks == requested.MaxClicks &&
+		existing.RedirectCode == requested.RedirectCode &&
+		existing.CacheMaxAge == requested.CacheMaxAge &&
+		slices.Equal(existing.Targeting, requested.Targeting)
+}
+
+// sameTime reports whether both moments are unset or equal. They are compared
+// to microseconds, which is what PostgreSQL keeps.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Truncate(time.Microsecond).Equal(b.Truncate(time.Microsecond))
+}
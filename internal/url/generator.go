@@ -0,0 +1,240 @@
+package url
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"github.com/thxhix/shortener/internal/config"
+	"github.com/thxhix/shortener/internal/database/interfaces"
+	mathRand "math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CodeGenerator produces short codes for new links.
+// Implementations must be safe for concurrent use.
+type CodeGenerator interface {
+	// Generate returns a new short code.
+	Generate(ctx context.Context) (string, error)
+}
+
+const (
+	// GeneratorRandom selects RandomGenerator.
+	GeneratorRandom = "random"
+
+	// GeneratorCounter selects CounterGenerator.
+	GeneratorCounter = "counter"
+
+	// GeneratorRange selects RangeGenerator.
+	GeneratorRange = "range"
+
+	// DefaultCodeLength is the length of random codes when it is not configured.
+	DefaultCodeLength = 8
+
+	// DefaultRangeSize is the number of IDs RangeGenerator reserves at once
+	// when it is not configured.
+	DefaultRangeSize = 1000
+
+	// DefaultCodeAttempts is the number of codes generated for a single write
+	// when it is not configured.
+	DefaultCodeAttempts = 5
+)
+
+// NewCodeGenerator creates the generator selected by the configuration.
+//
+// The counter of CounterGenerator is not persisted, so it starts from
+// the current Unix time in milliseconds: codes issued after a restart
+// do not repeat the earlier ones as long as less than one link per
+// millisecond is created on average.
+//
+// RangeGenerator reserves IDs in the database, which must implement RangeAllocator.
+func NewCodeGenerator(cfg config.Config, db interfaces.Database) (CodeGenerator, error) {
+	switch cfg.CodeGenerator {
+	case "", GeneratorRandom:
+		return NewRandomGenerator(cfg.CodeLength), nil
+	case GeneratorCounter:
+		return NewCounterGenerator(cfg.CodeSalt, uint64(time.Now().UnixMilli())), nil
+	case GeneratorRange:
+		allocator, ok := interfaces.As[interfaces.RangeAllocator](db)
+		if !ok {
+			return nil, fmt.Errorf("хранилище %T не поддерживает выделение диапазонов ID", db)
+		}
+		return NewRangeGenerator(allocator, cfg.CodeRangeSize), nil
+	default:
+		return nil, fmt.Errorf("неизвестный генератор коротких кодов: %q", cfg.CodeGenerator)
+	}
+}
+
+// RandomGenerator produces random base62 codes of a fixed length
+// from a cryptographically secure source.
+type RandomGenerator struct {
+	length int
+}
+
+// NewRandomGenerator creates a RandomGenerator of codes of the given length,
+// DefaultCodeLength if it is not positive.
+func NewRandomGenerator(length int) *RandomGenerator {
+	if length <= 0 {
+		length = DefaultCodeLength
+	}
+	return &RandomGenerator{length: length}
+}
+
+// Generate returns a new random code.
+func (g *RandomGenerator) Generate(ctx context.Context) (string, error) {
+	// Отбрасываем байты за последним полным циклом алфавита, чтобы символы были равновероятны
+	const limit = 256 - 256%len(base62Alphabet)
+
+	code := make([]byte, 0, g.length)
+	buf := make([]byte, g.length*2)
+	for len(code) < g.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			code = append(code, base62Alphabet[int(b)%len(base62Alphabet)])
+			if len(code) == g.length {
+				break
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// CounterGenerator produces codes from a sequential counter, obfuscated
+// in a Hashids-like way: the alphabet is shuffled by the salt and then
+// once more by a per-code lottery character, so consecutive codes
+// do not look alike. The encoding is reversible with Decode.
+type CounterGenerator struct {
+	alphabet string
+	salt     string
+	counter  atomic.Uint64
+}
+
+// NewCounterGenerator creates a CounterGenerator with the given salt,
+// whose first code encodes start.
+func NewCounterGenerator(salt string, start uint64) *CounterGenerator {
+	g := &CounterGenerator{
+		alphabet: shuffleAlphabet(base62Alphabet, salt),
+		salt:     salt,
+	}
+	g.counter.Store(start)
+	return g
+}
+
+// Generate returns the code of the next counter value.
+func (g *CounterGenerator) Generate(ctx context.Context) (string, error) {
+	return g.Encode(g.counter.Add(1) - 1), nil
+}
+
+// Encode returns the code of the number.
+func (g *CounterGenerator) Encode(n uint64) string {
+	lottery := g.alphabet[n%uint64(len(g.alphabet))]
+	return string(lottery) + encodeNumber(n, g.lotteryAlphabet(lottery))
+}
+
+// Decode returns the number encoded in the code.
+func (g *CounterGenerator) Decode(code string) (uint64, error) {
+	if len(code) < 2 {
+		return 0, errInvalidCode
+	}
+
+	n, err := decodeNumber(code[1:], g.lotteryAlphabet(code[0]))
+	if err != nil {
+		return 0, err
+	}
+	if g.Encode(n) != code {
+		return 0, errInvalidCode
+	}
+	return n, nil
+}
+
+// lotteryAlphabet shuffles the alphabet by the lottery character and the salt.
+func (g *CounterGenerator) lotteryAlphabet(lottery byte) string {
+	return shuffleAlphabet(g.alphabet, string(lottery)+g.salt)
+}
+
+// rangeOffset is added to allocated IDs, so that range codes are at least
+// five characters long and never shadow routes such as /ping or /api.
+const rangeOffset = 62 * 62 * 62 * 62
+
+// RangeGenerator produces sequential base62 codes from blocks of IDs
+// reserved by a RangeAllocator, ticket-server style: the storage is
+// queried once per block rather than once per link, and instances
+// sharing the storage never get overlapping blocks.
+type RangeGenerator struct {
+	allocator interfaces.RangeAllocator
+	size      int
+
+	mutex sync.Mutex
+	next  uint64
+	end   uint64
+}
+
+// NewRangeGenerator creates a RangeGenerator reserving blocks of the given size,
+// DefaultRangeSize if it is not positive.
+func NewRangeGenerator(allocator interfaces.RangeAllocator, size int) *RangeGenerator {
+	if size <= 0 {
+		size = DefaultRangeSize
+	}
+	return &RangeGenerator{
+		allocator: allocator,
+		size:      size,
+	}
+}
+
+// Generate returns the code of the next ID, reserving a new block
+// once the current one is used up.
+func (g *RangeGenerator) Generate(ctx context.Context) (string, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.next == g.end {
+		start, err := g.allocator.AllocateRange(ctx, g.size)
+		if err != nil {
+			return "", fmt.Errorf("не удалось зарезервировать диапазон ID: %w", err)
+		}
+		g.next = start
+		g.end = start + uint64(g.size)
+	}
+
+	id := g.next
+	g.next++
+	return encodeNumber(id+rangeOffset, base62Alphabet), nil
+}
+
+// SeededGenerator produces base62 codes of a fixed length from a pseudo-random
+// sequence, so the same seed always yields the same codes. Meant for tests.
+type SeededGenerator struct {
+	length int
+	mutex  sync.Mutex
+	random *mathRand.Rand
+}
+
+// NewSeededGenerator creates a SeededGenerator with the given seed of codes
+// of the given length, DefaultCodeLength if it is not positive.
+func NewSeededGenerator(seed int64, length int) *SeededGenerator {
+	if length <= 0 {
+		length = DefaultCodeLength
+	}
+	return &SeededGenerator{
+		length: length,
+		random: mathRand.New(mathRand.NewSource(seed)),
+	}
+}
+
+// Generate returns the next code of the sequence.
+func (g *SeededGenerator) Generate(ctx context.Context) (string, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	code := make([]byte, g.length)
+	for i := range code {
+		code[i] = base62Alphabet[g.random.Intn(len(base62Alphabet))]
+	}
+	return string(code), nil
+}
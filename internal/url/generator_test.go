@@ -0,0 +1,131 @@
+package url
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thxhix/shortener/internal/config"
+	"github.com/thxhix/shortener/internal/database/drivers"
+)
+
+func TestRandomGenerator(t *testing.T) {
+	generator := NewRandomGenerator(12)
+
+	seen := make(map[string]struct{})
+	for i := 0; i < 1000; i++ {
+		code, err := generator.Generate(context.Background())
+		require.NoError(t, err)
+		require.Len(t, code, 12)
+		for _, c := range code {
+			require.True(t, strings.ContainsRune(base62Alphabet, c), "код должен состоять из символов base62")
+		}
+		seen[code] = struct{}{}
+	}
+	require.Len(t, seen, 1000, "случайные коды не должны повторяться")
+}
+
+func TestCounterGenerator(t *testing.T) {
+	generator := NewCounterGenerator("salt", 1000)
+
+	first, err := generator.Generate(context.Background())
+	require.NoError(t, err)
+	second, err := generator.Generate(context.Background())
+	require.NoError(t, err)
+	require.NotEqual(t, first[1:], second[1:], "соседние коды не должны быть похожи")
+
+	for _, code := range []string{first, second} {
+		n, err := generator.Decode(code)
+		require.NoError(t, err)
+		require.Contains(t, []uint64{1000, 1001}, n)
+	}
+
+	for _, n := range []uint64{0, 1, 61, 62, 1 << 40, ^uint64(0)} {
+		decoded, err := generator.Decode(generator.Encode(n))
+		require.NoError(t, err)
+		require.Equal(t, n, decoded, "кодирование должно быть обратимым")
+	}
+
+	other := NewCounterGenerator("pepper", 1000)
+	require.NotEqual(t, generator.Encode(1000), other.Encode(1000), "соль должна менять коды")
+
+	_, err = generator.Decode("!")
+	require.Error(t, err)
+	_, err = generator.Decode(first[:1] + "!!")
+	require.Error(t, err)
+}
+
+func TestSeededGenerator(t *testing.T) {
+	a := NewSeededGenerator(42, 0)
+	b := NewSeededGenerator(42, 0)
+
+	for i := 0; i < 10; i++ {
+		first, err := a.Generate(context.Background())
+		require.NoError(t, err)
+		second, err := b.Generate(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, first, second, "одинаковый seed должен давать одинаковые коды")
+		require.Len(t, first, DefaultCodeLength)
+	}
+}
+
+func TestNewCodeGenerator(t *testing.T) {
+	generator, err := NewCodeGenerator(config.Config{CodeLength: 6}, nil)
+	require.NoError(t, err)
+	require.IsType(t, &RandomGenerator{}, generator)
+
+	generator, err = NewCodeGenerator(config.Config{CodeGenerator: GeneratorCounter}, nil)
+	require.NoError(t, err)
+	require.IsType(t, &CounterGenerator{}, generator)
+
+	_, err = NewCodeGenerator(config.Config{CodeGenerator: "uuid"}, nil)
+	require.Error(t, err)
+}
+
+// countingAllocator hands out consecutive ranges and counts requests.
+type countingAllocator struct {
+	next  uint64
+	calls int
+}
+
+func (a *countingAllocator) AllocateRange(ctx context.Context, size int) (uint64, error) {
+	a.calls++
+	start := a.next
+	a.next += uint64(size)
+	return start, nil
+}
+
+func TestRangeGenerator(t *testing.T) {
+	allocator := &countingAllocator{}
+	generator := NewRangeGenerator(allocator, 3)
+	ctx := context.Background()
+
+	var codes []string
+	for i := 0; i < 7; i++ {
+		code, err := generator.Generate(ctx)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, len(code), 5, "коды диапазона не должны быть короче пяти символов")
+		codes = append(codes, code)
+	}
+	require.Equal(t, 3, allocator.calls, "хранилище должно запрашиваться один раз на диапазон")
+
+	for i, code := range codes {
+		id, err := decodeNumber(code, base62Alphabet)
+		require.NoError(t, err)
+		require.EqualValues(t, i+rangeOffset, id, "коды должны идти подряд")
+	}
+}
+
+func TestNewCodeGenerator_Range(t *testing.T) {
+	memory, err := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
+	require.NoError(t, err)
+	db := drivers.NewCachedDatabase(memory, drivers.CacheOptions{Size: 10})
+
+	generator, err := NewCodeGenerator(config.Config{CodeGenerator: GeneratorRange}, db)
+	require.NoError(t, err, "аллокатор должен находиться за декораторами")
+	require.IsType(t, &RangeGenerator{}, generator)
+
+	_, err = NewCodeGenerator(config.Config{CodeGenerator: GeneratorRange}, nil)
+	require.Error(t, err)
+}
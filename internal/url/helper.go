@@ -1,28 +1,70 @@
 package url
 
 import (
-	"crypto/sha256"
-	"encoding/base64"
-	"flag"
-	"github.com/google/uuid"
+	"errors"
+	"strings"
 )
 
-// IsTestMode checks running in Go test mode.
-// It checks if the testing flag ("test.v") is present in the flag set.
-func IsTestMode() bool {
-	return flag.Lookup("test.v") != nil
+// base62Alphabet is the set of characters used in short codes.
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// errInvalidCode is returned when a short code cannot be decoded.
+var errInvalidCode = errors.New("некорректный короткий код")
+
+// encodeNumber writes n in the positional system of the given alphabet.
+func encodeNumber(n uint64, alphabet string) string {
+	base := uint64(len(alphabet))
+	if n == 0 {
+		return alphabet[:1]
+	}
+
+	var buf [64]byte
+	i := len(buf)
+	for n > 0 {
+		i--
+		buf[i] = alphabet[n%base]
+		n /= base
+	}
+	return string(buf[i:])
+}
+
+// decodeNumber reads a number written by encodeNumber with the same alphabet.
+func decodeNumber(code string, alphabet string) (uint64, error) {
+	if code == "" {
+		return 0, errInvalidCode
+	}
+
+	base := uint64(len(alphabet))
+	var n uint64
+	for i := 0; i < len(code); i++ {
+		digit := strings.IndexByte(alphabet, code[i])
+		if digit < 0 {
+			return 0, errInvalidCode
+		}
+		next := n*base + uint64(digit)
+		if next/base != n {
+			return 0, errInvalidCode
+		}
+		n = next
+	}
+	return n, nil
 }
 
-// GetHash generates a short hash string.
-// In test mode (detected by IsTestMode) it always returns a static value "testHash"
-// to provide deterministic results in unit tests.
-// In normal mode it generates a new UUID, computes its SHA256 hash,
-// encodes it using URL-safe Base64, and returns the first 10 characters.
-func GetHash() string {
-	if IsTestMode() {
-		return "testHash"
+// shuffleAlphabet deterministically permutes the alphabet by the salt,
+// the same way Hashids does. An empty salt leaves it as is.
+func shuffleAlphabet(alphabet string, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+
+	chars := []byte(alphabet)
+	for i, v, p := len(chars)-1, 0, 0; i > 0; i-- {
+		v %= len(salt)
+		n := int(salt[v])
+		p += n
+		j := (n + v + p) % i
+		chars[i], chars[j] = chars[j], chars[i]
+		v++
 	}
-	u := uuid.New().String()
-	hash := sha256.Sum256([]byte(u))
-	return base64.URLEncoding.EncodeToString(hash[:])[:10]
+	return string(chars)
 }
@@ -0,0 +1,135 @@
+package url
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxPasswordLength is the maximal length of a link password in bytes,
+	// longer passwords would be silently truncated by bcrypt.
+	MaxPasswordLength = 72
+
+	// DefaultPasswordAttempts is the number of failed password attempts
+	// allowed per link within DefaultPasswordWindow when it is not configured.
+	DefaultPasswordAttempts = 5
+
+	// DefaultPasswordWindow is the period failed password attempts
+	// are counted over when it is not configured.
+	DefaultPasswordWindow = time.Minute
+)
+
+// ErrPasswordRequired is returned when a password-protected link
+// is requested without a password.
+var ErrPasswordRequired = errors.New("ссылка защищена паролем")
+
+// ErrWrongPassword is returned when the password of a link does not match.
+var ErrWrongPassword = errors.New("неверный пароль")
+
+// ErrTooManyAttempts is returned when a link got too many failed
+// password attempts recently. The password is not checked then.
+var ErrTooManyAttempts = errors.New("слишком много неудачных попыток ввода пароля")
+
+// hashPassword returns the salted bcrypt hash of the password.
+func hashPassword(password string) (string, error) {
+	if len(password) > MaxPasswordLength {
+		return "", fmt.Errorf("%w: пароль длиннее %d байт", ErrInvalidLinkOptions, MaxPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword reports whether the password matches the bcrypt hash.
+func checkPassword(hash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// failureWindow counts failed attempts since start.
+type failureWindow struct {
+	count int
+	start time.Time
+}
+
+// attemptLimiter limits failed password attempts per link with a fixed window:
+// once a link gets max failures, further attempts are rejected until
+// the window started by the first of them passes. Every attempt is counted
+// as failed before the password is checked, so that concurrent attempts
+// cannot all pass the limit, and is forgiven if the password matches.
+type attemptLimiter struct {
+	max    int
+	window time.Duration
+
+	mutex    sync.Mutex
+	failures map[string]*failureWindow
+	// pruneAt is the number of tracked links that triggers pruning.
+	pruneAt int
+}
+
+// newAttemptLimiter creates an attemptLimiter, falling back to
+// DefaultPasswordAttempts and DefaultPasswordWindow for non-positive values.
+func newAttemptLimiter(attempts int, window time.Duration) *attemptLimiter {
+	if attempts <= 0 {
+		attempts = DefaultPasswordAttempts
+	}
+	if window <= 0 {
+		window = DefaultPasswordWindow
+	}
+	return &attemptLimiter{
+		max:      attempts,
+		window:   window,
+		failures: make(map[string]*failureWindow),
+		pruneAt:  minPruneAt,
+	}
+}
+
+// reserve records an attempt for the key as failed and reports whether
+// it may be checked. Attempts beyond the limit are not recorded.
+func (l *attemptLimiter) reserve(key string, now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	failures, ok := l.failures[key]
+	if !ok || now.Sub(failures.start) >= l.window {
+		if len(l.failures) >= l.pruneAt {
+			l.prune(now)
+		}
+		l.failures[key] = &failureWindow{count: 1, start: now}
+		return true
+	}
+	if failures.count >= l.max {
+		return false
+	}
+	failures.count++
+	return true
+}
+
+// reset forgets failed attempts for the key after a successful one,
+// including the attempt reserved for it.
+func (l *attemptLimiter) reset(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.failures, key)
+}
+
+// minPruneAt is the number of tracked links below which
+// attemptLimiter does not prune.
+const minPruneAt = 1024
+
+// prune drops windows that have passed, so that links attacked once
+// do not stay in memory. The threshold grows with the live windows,
+// which keeps pruning amortized. The caller must hold the lock.
+func (l *attemptLimiter) prune(now time.Time) {
+	for key, failures := range l.failures {
+		if now.Sub(failures.start) >= l.window {
+			delete(l.failures, key)
+		}
+	}
+	l.pruneAt = max(minPruneAt, 2*len(l.failures))
+}
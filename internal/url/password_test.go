@@ -0,0 +1,58 @@
+package url
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	limiter := newAttemptLimiter(2, time.Minute)
+	now := time.Now()
+
+	require.True(t, limiter.reserve("aaa", now))
+	require.True(t, limiter.reserve("aaa", now.Add(time.Second)))
+	require.False(t, limiter.reserve("aaa", now.Add(2*time.Second)), "лимит неудачных попыток исчерпан")
+	require.True(t, limiter.reserve("bbb", now), "лимит считается для каждой ссылки отдельно")
+	require.True(t, limiter.reserve("aaa", now.Add(time.Minute)), "лимит должен сбрасываться после окна")
+
+	limiter.reset("aaa")
+	require.True(t, limiter.reserve("aaa", now.Add(time.Minute)))
+	require.True(t, limiter.reserve("aaa", now.Add(time.Minute)), "успешная попытка должна сбрасывать счётчик")
+}
+
+func TestAttemptLimiter_Concurrent(t *testing.T) {
+	limiter := newAttemptLimiter(3, time.Minute)
+	now := time.Now()
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.reserve("aaa", now) {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(3), allowed.Load(), "одновременные попытки не должны обходить лимит")
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := hashPassword("secret")
+	require.NoError(t, err)
+
+	other, err := hashPassword("secret")
+	require.NoError(t, err)
+	require.NotEqual(t, hash, other, "хэши одного пароля должны отличаться солью")
+
+	require.True(t, checkPassword(hash, "secret"))
+	require.False(t, checkPassword(hash, "Secret"))
+	require.False(t, checkPassword("", "secret"))
+}
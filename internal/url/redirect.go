@@ -0,0 +1,70 @@
+package url
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thxhix/shortener/internal/models"
+)
+
+// DefaultRedirectCode is the status of redirects of links without RedirectCode.
+const DefaultRedirectCode = http.StatusTemporaryRedirect
+
+// noStore forbids caching the redirect anywhere.
+const noStore = "no-store"
+
+// ValidateRedirect checks that the redirect code is one of 301, 302, 307
+// and 308 or zero for the default one, and that the cache max-age is not
+// negative. The returned error wraps ErrInvalidLinkOptions.
+func ValidateRedirect(opts models.LinkOptions) error {
+	switch opts.RedirectCode {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return fmt.Errorf("%w: redirect_code должен быть 301, 302, 307 или 308", ErrInvalidLinkOptions)
+	}
+	if opts.CacheMaxAge < 0 {
+		return fmt.Errorf("%w: cache_max_age не может быть отрицательным", ErrInvalidLinkOptions)
+	}
+	return nil
+}
+
+// redirect describes the response to a request of the link at the given moment.
+//
+// Redirects of links whose clicks are counted or that are protected with
+// a password must reach the service every time, so they are never cached,
+// whatever CacheMaxAge says. Other links are cached for CacheMaxAge seconds,
+// but not past the moment they expire. Redirects of links with targeting
+// rules depend on the request, so they are cached by browsers only.
+func redirect(link models.DBShortenRow, now time.Time) models.Redirect {
+	result := models.Redirect{
+		URL:        link.URL,
+		StatusCode: link.RedirectCode,
+	}
+	if result.StatusCode == 0 {
+		result.StatusCode = DefaultRedirectCode
+	}
+	scope := "public"
+	if len(link.Targeting) > 0 {
+		scope = "private"
+		result.Vary = targetingVary
+	}
+
+	switch {
+	case link.MaxClicks > 0 || link.PasswordHash != "":
+		result.CacheControl = noStore
+	case link.CacheMaxAge > 0:
+		maxAge := time.Duration(link.CacheMaxAge) * time.Second
+		for _, deadline := range []*time.Time{link.ExpiresAt, link.ActiveUntil} {
+			if deadline != nil && deadline.Sub(now) < maxAge {
+				maxAge = deadline.Sub(now)
+			}
+		}
+		if seconds := int(maxAge / time.Second); seconds > 0 {
+			result.CacheControl = fmt.Sprintf("%s, max-age=%d", scope, seconds)
+		} else {
+			result.CacheControl = noStore
+		}
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package url
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+func TestRedirect(t *testing.T) {
+	now := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	soon := now.Add(90 * time.Second)
+
+	tests := []struct {
+		name         string
+		opts         models.LinkOptions
+		statusCode   int
+		cacheControl string
+		vary         string
+	}{
+		{
+			name:       "default link",
+			statusCode: http.StatusTemporaryRedirect,
+		},
+		{
+			name:         "permanent cached link",
+			opts:         models.LinkOptions{RedirectCode: http.StatusMovedPermanently, CacheMaxAge: 3600},
+			statusCode:   http.StatusMovedPermanently,
+			cacheControl: "public, max-age=3600",
+		},
+		{
+			name:         "tracked link",
+			opts:         models.LinkOptions{RedirectCode: http.StatusPermanentRedirect, CacheMaxAge: 3600, MaxClicks: 10},
+			statusCode:   http.StatusPermanentRedirect,
+			cacheControl: "no-store",
+		},
+		{
+			name:         "password-protected link",
+			opts:         models.LinkOptions{CacheMaxAge: 3600, PasswordHash: "hash"},
+			statusCode:   http.StatusTemporaryRedirect,
+			cacheControl: "no-store",
+		},
+		{
+			name:         "link expiring before max-age",
+			opts:         models.LinkOptions{RedirectCode: http.StatusFound, CacheMaxAge: 3600, ExpiresAt: &soon},
+			statusCode:   http.StatusFound,
+			cacheControl: "public, max-age=90",
+		},
+		{
+			name:         "link closing its window before max-age",
+			opts:         models.LinkOptions{CacheMaxAge: 3600, ActiveUntil: &soon},
+			statusCode:   http.StatusTemporaryRedirect,
+			cacheControl: "public, max-age=90",
+		},
+		{
+			name:         "targeted link",
+			opts:         models.LinkOptions{CacheMaxAge: 3600, Targeting: models.TargetingRuleList{{OS: "iOS", URL: "https://apps.apple.com"}}},
+			statusCode:   http.StatusTemporaryRedirect,
+			cacheControl: "private, max-age=3600",
+			vary:         "User-Agent, Accept-Language",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := redirect(models.DBShortenRow{URL: "https://ya.ru", LinkOptions: tt.opts}, now)
+			require.Equal(t, "https://ya.ru", result.URL)
+			require.Equal(t, tt.statusCode, result.StatusCode, "код редиректа не совпадает с ожидаемым")
+			require.Equal(t, tt.cacheControl, result.CacheControl, "Cache-Control не совпадает с ожидаемым")
+			require.Equal(t, tt.vary, result.Vary, "Vary не совпадает с ожидаемым")
+		})
+	}
+}
+
+func TestValidateRedirect(t *testing.T) {
+	for _, code := range []int{0, 301, 302, 307, 308} {
+		require.NoError(t, ValidateRedirect(models.LinkOptions{RedirectCode: code}))
+	}
+	require.ErrorIs(t, ValidateRedirect(models.LinkOptions{RedirectCode: http.StatusOK}), ErrInvalidLinkOptions)
+	require.ErrorIs(t, ValidateRedirect(models.LinkOptions{RedirectCode: http.StatusSeeOther}), ErrInvalidLinkOptions)
+	require.ErrorIs(t, ValidateRedirect(models.LinkOptions{CacheMaxAge: -1}), ErrInvalidLinkOptions)
+}
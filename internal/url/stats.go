@@ -0,0 +1,291 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thxhix/shortener/internal/database/interfaces"
+	"github.com/thxhix/shortener/internal/models"
+	"github.com/thxhix/shortener/internal/useragent"
+)
+
+// Bucket sizes of link statistics.
+const (
+	BucketHour = "hour"
+	BucketDay  = "day"
+	BucketWeek = "week"
+)
+
+const (
+	// DefaultStatsRange is the range of statistics requested without From.
+	DefaultStatsRange = 7 * 24 * time.Hour
+
+	// MaxStatsBuckets bounds the number of buckets in a single response.
+	MaxStatsBuckets = 1000
+
+	// TopStatsEntries is the number of top referrers and user agents returned.
+	TopStatsEntries = 10
+
+	// directReferrer names redirects without a referrer.
+	directReferrer = "(direct)"
+)
+
+// ErrInvalidStatsQuery is returned when the range or the bucket of statistics is invalid.
+var ErrInvalidStatsQuery = errors.New("некорректный запрос статистики")
+
+// ErrStatsUnavailable is returned when the storage cannot read click events back.
+var ErrStatsUnavailable = errors.New("хранилище не поддерживает статистику переходов")
+
+// WithUserAgentParser makes URLUseCase classify the user agents of click events
+// stored without a classification with the parser, the same one the redirects
+// are classified with.
+func WithUserAgentParser(parser *useragent.Parser) Option {
+	return func(u *URLUseCase) {
+		u.agents = parser
+	}
+}
+
+// LinkStats returns the statistics of the user's link over the requested range:
+// the number of redirects and unique visitors, the number of redirects in every
+// bucket and the top referrers, browser families and device classes. Buckets are aligned
+// in UTC, weeks start on Monday.
+//
+// If the storage rolls click events up, redirects before its watermark are
+// counted from the rollups, since raw events may already be pruned there.
+//
+// Returns ErrInvalidStatsQuery if the range is empty or holds more than
+// MaxStatsBuckets buckets, ErrNotFound if the link does not exist or is deleted
+// and ErrNotOwner if it belongs to another user.
+func (u *URLUseCase) LinkStats(ctx context.Context, userID string, hash string, query models.StatsQuery) (models.LinkStats, error) {
+	stats, err := u.statsRange(query)
+	if err != nil {
+		return models.LinkStats{}, err
+	}
+	if _, err := u.ownLink(ctx, userID, hash); err != nil {
+		return models.LinkStats{}, err
+	}
+
+	store, ok := interfaces.As[interfaces.ClickReader](u.database)
+	if !ok {
+		return models.LinkStats{}, ErrStatsUnavailable
+	}
+	rolledFrom, rolledUntil, err := u.addRollups(ctx, &stats, hash)
+	if err != nil {
+		return models.LinkStats{}, err
+	}
+	events, err := store.GetClickEvents(ctx, hash, stats.From, stats.To)
+	if err != nil {
+		return models.LinkStats{}, err
+	}
+
+	stats.Hash = hash
+	aggregate(&stats, events, u.agents, rolledFrom, rolledUntil)
+	return stats, nil
+}
+
+// addRollups counts the rolled up redirects of the range into the statistics
+// and returns the interval they cover, which is empty if the storage has no
+// rollups for the range. The interval is made of the whole hours between From
+// and the watermark or To, whichever is earlier, so partial hours at its edges
+// are left to raw events. Hourly buckets are served by hourly rollups, the
+// others by daily rollups for whole days and hourly ones for the rest.
+func (u *URLUseCase) addRollups(ctx context.Context, stats *models.LinkStats, hash string) (time.Time, time.Time, error) {
+	roller, ok := interfaces.As[interfaces.ClickRoller](u.database)
+	if !ok {
+		return stats.From, stats.From, nil
+	}
+	watermark, err := roller.ClickWatermark(ctx)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	end := watermark.UTC()
+	if end.After(stats.To) {
+		end = stats.To
+	}
+	from := ceilTime(stats.From, time.Hour)
+	until := end.Truncate(time.Hour)
+	if !from.Before(until) {
+		return stats.From, stats.From, nil
+	}
+
+	dayFrom := ceilTime(from, 24*time.Hour)
+	dayUntil := until.Truncate(24 * time.Hour)
+	if stats.Bucket == BucketHour || !dayFrom.Before(dayUntil) {
+		dayFrom, dayUntil = until, until
+	}
+
+	spans := []struct {
+		granularity models.RollupGranularity
+		from, to    time.Time
+	}{
+		{models.RollupHour, from, dayFrom},
+		{models.RollupDay, dayFrom, dayUntil},
+		{models.RollupHour, dayUntil, until},
+	}
+	first := bucketStart(stats.From, stats.Bucket)
+	for _, span := range spans {
+		if !span.from.Before(span.to) {
+			continue
+		}
+		rollups, err := roller.GetClickRollups(ctx, hash, span.granularity, span.from, span.to)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		for _, rollup := range rollups {
+			stats.TotalClicks += rollup.Clicks
+			i := int(rollup.Start.Sub(first) / bucketSizes[stats.Bucket])
+			if i >= 0 && i < len(stats.Series) {
+				stats.Series[i].Clicks += rollup.Clicks
+			}
+		}
+	}
+	stats.RolledUpUntil = &until
+	return from, until, nil
+}
+
+// ceilTime returns t rounded up to a multiple of d, which is aligned in UTC
+// for hours and days.
+func ceilTime(t time.Time, d time.Duration) time.Time {
+	start := t.UTC().Truncate(d)
+	if start.Before(t) {
+		start = start.Add(d)
+	}
+	return start
+}
+
+// statsRange validates the query and returns empty statistics
+// with the range and the buckets filled in.
+func (u *URLUseCase) statsRange(query models.StatsQuery) (models.LinkStats, error) {
+	stats := models.LinkStats{Bucket: query.Bucket}
+	if stats.Bucket == "" {
+		stats.Bucket = BucketDay
+	}
+	size, ok := bucketSizes[stats.Bucket]
+	if !ok {
+		return models.LinkStats{}, fmt.Errorf("%w: bucket должен быть hour, day или week", ErrInvalidStatsQuery)
+	}
+
+	stats.To = u.clock.Now().UTC()
+	if query.To != nil {
+		stats.To = query.To.UTC()
+	}
+	stats.From = stats.To.Add(-DefaultStatsRange)
+	if query.From != nil {
+		stats.From = query.From.UTC()
+	}
+	if !stats.From.Before(stats.To) {
+		return models.LinkStats{}, fmt.Errorf("%w: from должен быть раньше to", ErrInvalidStatsQuery)
+	}
+
+	for start := bucketStart(stats.From, stats.Bucket); start.Before(stats.To); start = start.Add(size) {
+		if len(stats.Series) == MaxStatsBuckets {
+			return models.LinkStats{}, fmt.Errorf("%w: больше %d интервалов", ErrInvalidStatsQuery, MaxStatsBuckets)
+		}
+		stats.Series = append(stats.Series, models.StatsBucket{Start: start})
+	}
+	return stats, nil
+}
+
+// bucketSizes maps the supported buckets to their durations, which are exact,
+// since buckets are aligned in UTC.
+var bucketSizes = map[string]time.Duration{
+	BucketHour: time.Hour,
+	BucketDay:  24 * time.Hour,
+	BucketWeek: 7 * 24 * time.Hour,
+}
+
+// bucketStart returns the beginning of the bucket holding t.
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	switch bucket {
+	case BucketHour:
+		return t.Truncate(time.Hour)
+	case BucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// aggregate counts the events into the statistics with the buckets filled in.
+// Events stored without a classification are classified by the parser.
+// Events in [rolledFrom, rolledUntil) only count as visitors, referrers and
+// user agents, their redirects are already counted from the rollups.
+func aggregate(stats *models.LinkStats, events models.ClickEventList, parser *useragent.Parser, rolledFrom time.Time, rolledUntil time.Time) {
+	visitors := make(map[string]struct{})
+	referrers := make(map[string]int)
+	agents := make(map[string]int)
+	devices := make(map[string]int)
+	first := bucketStart(stats.From, stats.Bucket)
+
+	for _, event := range events {
+		device, browser := event.Device, event.Browser
+		if browser == "" {
+			// События, записанные до классификации user-agent, разбираем настроенными правилами
+			agent := parser.Parse(event.UserAgent)
+			if agent.Bot {
+				continue
+			}
+			device, browser = agent.Device, agent.Browser
+		}
+
+		visitors[event.IP+"|"+event.UserAgent] = struct{}{}
+		referrers[referrerHost(event.Referrer)]++
+		agents[browser]++
+		devices[device]++
+		if !event.Time.Before(rolledFrom) && event.Time.Before(rolledUntil) {
+			continue
+		}
+		stats.TotalClicks++
+
+		// Интервалы идут подряд, поэтому индекс находится сдвигом от первого
+		i := int(event.Time.Sub(first) / bucketSizes[stats.Bucket])
+		if i >= 0 && i < len(stats.Series) {
+			stats.Series[i].Clicks++
+		}
+	}
+
+	stats.UniqueVisitors = len(visitors)
+	stats.TopReferrers = topCounts(referrers)
+	stats.UserAgents = topCounts(agents)
+	stats.Devices = topCounts(devices)
+}
+
+// topCounts returns up to TopStatsEntries values with the most clicks,
+// ties are ordered by name.
+func topCounts(counts map[string]int) models.StatsCountList {
+	result := make(models.StatsCountList, 0, len(counts))
+	for name, clicks := range counts {
+		result = append(result, models.StatsCount{Name: name, Clicks: clicks})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Clicks != result[j].Clicks {
+			return result[i].Clicks > result[j].Clicks
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > TopStatsEntries {
+		result = result[:TopStatsEntries]
+	}
+	return result
+}
+
+// referrerHost returns the host of the referrer, or directReferrer
+// if there is none.
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return directReferrer
+	}
+	parsed, err := url.Parse(referrer)
+	if err != nil || parsed.Host == "" {
+		return referrer
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Host), "www.")
+}
@@ -0,0 +1,42 @@
+package url
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketStart(t *testing.T) {
+	// Среда, 15 мая 2024 года
+	moment := time.Date(2024, time.May, 15, 13, 45, 10, 0, time.UTC)
+
+	tests := []struct {
+		bucket string
+		want   time.Time
+	}{
+		{BucketHour, time.Date(2024, time.May, 15, 13, 0, 0, 0, time.UTC)},
+		{BucketDay, time.Date(2024, time.May, 15, 0, 0, 0, 0, time.UTC)},
+		{BucketWeek, time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bucket, func(t *testing.T) {
+			require.Equal(t, tt.want, bucketStart(moment, tt.bucket))
+		})
+	}
+
+	sunday := time.Date(2024, time.May, 19, 23, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC), bucketStart(sunday, BucketWeek),
+		"неделя начинается с понедельника")
+
+	moscow := time.FixedZone("MSK", 3*60*60)
+	require.Equal(t, time.Date(2024, time.May, 14, 0, 0, 0, 0, time.UTC),
+		bucketStart(time.Date(2024, time.May, 15, 1, 0, 0, 0, moscow), BucketDay), "интервалы выравниваются по UTC")
+}
+
+func TestReferrerHost(t *testing.T) {
+	require.Equal(t, "google.com", referrerHost("https://www.Google.com/search?q=1"))
+	require.Equal(t, "t.me", referrerHost("https://t.me/channel"))
+	require.Equal(t, directReferrer, referrerHost(""))
+	require.Equal(t, "android-app", referrerHost("android-app"), "значение без хоста возвращается как есть")
+}
@@ -0,0 +1,67 @@
+package url
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/thxhix/shortener/internal/config"
+	"github.com/thxhix/shortener/internal/database/drivers"
+	"github.com/thxhix/shortener/internal/database/interfaces"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+const benchLinks = 10000
+
+// benchmarkParallelGetFullURL measures redirects from many goroutines,
+// with one write for every writeEvery reads.
+func benchmarkParallelGetFullURL(b *testing.B, db interfaces.Database, writeEvery int) {
+	cfg := config.Config{BaseURL: "http://localhost:8080"}
+	uc := NewURLUseCase(db, cfg, NewRandomGenerator(DefaultCodeLength))
+	ctx := context.Background()
+
+	for i := 0; i < benchLinks; i++ {
+		id := strconv.Itoa(i)
+		if _, err := db.AddLink(ctx, "https://example.com/bench"+id, "h"+id, "user", models.LinkOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	var writes atomic.Int64
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i++
+			if writeEvery > 0 && i%writeEvery == 0 {
+				id := strconv.FormatInt(benchLinks+writes.Add(1), 10)
+				_, _ = db.AddLink(ctx, "https://example.com/bench"+id, "h"+id, "user", models.LinkOptions{})
+				continue
+			}
+			_, _ = uc.GetFullURL(ctx, "h"+strconv.Itoa(i%benchLinks), models.RequestAttributes{})
+		}
+	})
+}
+
+func BenchmarkParallelGetFullURL(b *testing.B) {
+	for _, writeEvery := range []int{0, 10} {
+		suffix := "/reads"
+		if writeEvery > 0 {
+			suffix = "/mixed"
+		}
+
+		b.Run("memory"+suffix, func(b *testing.B) {
+			db, _ := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
+			defer db.Close()
+			benchmarkParallelGetFullURL(b, db, writeEvery)
+		})
+
+		b.Run("sharded"+suffix, func(b *testing.B) {
+			db, _ := drivers.NewShardedMemoryDatabase(drivers.ShardedMemoryOptions{})
+			defer db.Close()
+			benchmarkParallelGetFullURL(b, db, writeEvery)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package url
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thxhix/shortener/internal/models"
+	"github.com/thxhix/shortener/internal/useragent"
+)
+
+// MaxTargetingRules bounds the number of targeting rules of a link.
+const MaxTargetingRules = 20
+
+// targetingVary lists the headers the destination of a targeted link depends on.
+const targetingVary = "User-Agent, Accept-Language"
+
+// ValidateTargeting checks that there are at most MaxTargetingRules rules,
+// every rule has a condition and a valid URL, and devices are known classes.
+// The returned error wraps ErrInvalidLinkOptions.
+func ValidateTargeting(rules models.TargetingRuleList) error {
+	if len(rules) > MaxTargetingRules {
+		return fmt.Errorf("%w: больше %d правил таргетинга", ErrInvalidLinkOptions, MaxTargetingRules)
+	}
+	for i, rule := range rules {
+		if rule.Device == "" && rule.OS == "" && rule.Language == "" && rule.QueryParam == "" {
+			return fmt.Errorf("%w: правило таргетинга #%d без условий", ErrInvalidLinkOptions, i+1)
+		}
+		if rule.QueryValue != "" && rule.QueryParam == "" {
+			return fmt.Errorf("%w: правило таргетинга #%d: query_value без query_param", ErrInvalidLinkOptions, i+1)
+		}
+		switch rule.Device {
+		case "", useragent.DeviceDesktop, useragent.DeviceMobile, useragent.DeviceTablet, useragent.DeviceBot, useragent.DeviceOther:
+		default:
+			return fmt.Errorf("%w: правило таргетинга #%d: неизвестное устройство %q", ErrInvalidLinkOptions, i+1, rule.Device)
+		}
+		if _, err := url.ParseRequestURI(rule.URL); err != nil {
+			return fmt.Errorf("%w: правило таргетинга #%d: некорректная ссылка", ErrInvalidLinkOptions, i+1)
+		}
+	}
+	return nil
+}
+
+// LinkTargeting returns the targeting rules of the user's link. Returns
+// ErrNotFound if the link does not exist or is deleted and ErrNotOwner
+// if it belongs to another user.
+func (u *URLUseCase) LinkTargeting(ctx context.Context, userID string, hash string) (models.TargetingRuleList, error) {
+	row, err := u.ownLink(ctx, userID, hash)
+	if err != nil {
+		return nil, err
+	}
+	return nonNilTargeting(row.Targeting), nil
+}
+
+// UpdateTargeting replaces the targeting rules of the user's link and returns
+// the stored rules, an empty list removes targeting. Returns ErrInvalidLinkOptions
+// if the rules are invalid, other errors are the same as in LinkTargeting.
+func (u *URLUseCase) UpdateTargeting(ctx context.Context, userID string, hash string, rules models.TargetingRuleList) (models.TargetingRuleList, error) {
+	if err := ValidateTargeting(rules); err != nil {
+		return nil, err
+	}
+	row, err := u.database.UpdateLinkTargeting(ctx, userID, hash, rules)
+	if err != nil {
+		return nil, err
+	}
+	return nonNilTargeting(row.Targeting), nil
+}
+
+// nonNilTargeting returns the rules, or an empty list if there are none,
+// so that they are encoded as an empty JSON array.
+func nonNilTargeting(rules models.TargetingRuleList) models.TargetingRuleList {
+	if rules == nil {
+		return models.TargetingRuleList{}
+	}
+	return rules
+}
+
+// target returns the destination of the link for the request: the URL
+// of the first matching rule, or the URL of the link if none matches.
+func target(link models.DBShortenRow, attrs models.RequestAttributes) string {
+	language := preferredLanguage(attrs.AcceptLanguage)
+	for _, rule := range link.Targeting {
+		if matches(rule, attrs, language) {
+			return rule.URL
+		}
+	}
+	return link.URL
+}
+
+// matches reports whether the request meets all conditions of the rule.
+func matches(rule models.TargetingRule, attrs models.RequestAttributes, language string) bool {
+	if rule.Device != "" && rule.Device != attrs.Device {
+		return false
+	}
+	if rule.OS != "" && !strings.EqualFold(rule.OS, attrs.OS) {
+		return false
+	}
+	if rule.Language != "" && !languageMatches(rule.Language, language) {
+		return false
+	}
+	if rule.QueryParam != "" {
+		values, ok := attrs.Query[rule.QueryParam]
+		if !ok {
+			return false
+		}
+		if rule.QueryValue != "" && !slices.Contains(values, rule.QueryValue) {
+			return false
+		}
+	}
+	return true
+}
+
+// languageMatches reports whether the language tag of the rule matches the
+// preferred one. A tag without a region also matches its regional variants.
+func languageMatches(tag string, preferred string) bool {
+	if preferred == "" {
+		return false
+	}
+	tag = strings.ToLower(tag)
+	return tag == preferred || strings.HasPrefix(preferred, tag+"-")
+}
+
+// preferredLanguage returns the lowercased language tag with the highest
+// quality in the Accept-Language header, the first one on ties. Wildcards
+// and languages with zero quality are ignored.
+func preferredLanguage(header string) string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			languages = append(languages, weighted{tag: tag, quality: quality})
+		}
+	}
+	if len(languages) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+	return languages[0].tag
+}
@@ -0,0 +1,96 @@
+package url
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+func TestTarget(t *testing.T) {
+	link := models.DBShortenRow{
+		URL: "https://example.com",
+		LinkOptions: models.LinkOptions{Targeting: models.TargetingRuleList{
+			{QueryParam: "preview", URL: "https://example.com/preview"},
+			{QueryParam: "utm_source", QueryValue: "tg", URL: "https://example.com/telegram"},
+			{OS: "iOS", URL: "https://apps.apple.com/app/id1"},
+			{OS: "android", URL: "https://play.google.com/store/apps/details?id=app"},
+			{Device: "desktop", Language: "ru", URL: "https://example.com/ru"},
+		}},
+	}
+
+	tests := []struct {
+		name  string
+		attrs models.RequestAttributes
+		want  string
+	}{
+		{"iPhone", models.RequestAttributes{Device: "mobile", OS: "iOS"}, "https://apps.apple.com/app/id1"},
+		{"iPad", models.RequestAttributes{Device: "tablet", OS: "iOS"}, "https://apps.apple.com/app/id1"},
+		{"Android", models.RequestAttributes{Device: "mobile", OS: "Android"}, "https://play.google.com/store/apps/details?id=app"},
+		{"русский десктоп", models.RequestAttributes{Device: "desktop", OS: "Windows", AcceptLanguage: "ru-RU,ru;q=0.9,en;q=0.8"}, "https://example.com/ru"},
+		{"английский десктоп", models.RequestAttributes{Device: "desktop", OS: "Windows", AcceptLanguage: "en-US,en;q=0.9,ru;q=0.8"}, "https://example.com"},
+		{"параметр без значения", models.RequestAttributes{OS: "iOS", Query: map[string][]string{"preview": {""}}}, "https://example.com/preview"},
+		{"параметр со значением", models.RequestAttributes{Query: map[string][]string{"utm_source": {"tg"}}}, "https://example.com/telegram"},
+		{"параметр с другим значением", models.RequestAttributes{Query: map[string][]string{"utm_source": {"vk"}}}, "https://example.com"},
+		{"без атрибутов", models.RequestAttributes{}, "https://example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, target(link, tt.attrs))
+		})
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"ru-RU,ru;q=0.9,en;q=0.8", "ru-ru"},
+		{"en;q=0.5, de", "de"},
+		{"fr;q=0.8, es;q=0.8", "fr"},
+		{"*, en;q=0.1", "en"},
+		{"ru;q=0", ""},
+		{"ru;q=abc, en;q=0.3", "en"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			require.Equal(t, tt.want, preferredLanguage(tt.header))
+		})
+	}
+
+	require.True(t, languageMatches("ru", "ru-ru"), "язык без региона подходит к региональным вариантам")
+	require.True(t, languageMatches("pt-BR", "pt-br"))
+	require.False(t, languageMatches("pt-BR", "pt"))
+	require.False(t, languageMatches("ru", "rue"))
+}
+
+func TestValidateTargeting(t *testing.T) {
+	require.NoError(t, ValidateTargeting(nil))
+	require.NoError(t, ValidateTargeting(models.TargetingRuleList{
+		{Device: "mobile", OS: "iOS", URL: "https://apps.apple.com/app/id1"},
+		{QueryParam: "ref", URL: "https://example.com"},
+	}))
+
+	tests := []struct {
+		name string
+		rule models.TargetingRule
+	}{
+		{"без условий", models.TargetingRule{URL: "https://example.com"}},
+		{"неизвестное устройство", models.TargetingRule{Device: "tv", URL: "https://example.com"}},
+		{"некорректная ссылка", models.TargetingRule{OS: "iOS", URL: "not a url"}},
+		{"значение без параметра", models.TargetingRule{OS: "iOS", QueryValue: "tg", URL: "https://example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, ValidateTargeting(models.TargetingRuleList{tt.rule}), ErrInvalidLinkOptions)
+		})
+	}
+
+	tooMany := make(models.TargetingRuleList, MaxTargetingRules+1)
+	for i := range tooMany {
+		tooMany[i] = models.TargetingRule{Language: "ru", URL: "https://example.com"}
+	}
+	require.ErrorIs(t, ValidateTargeting(tooMany), ErrInvalidLinkOptions)
+}
@@ -3,11 +3,13 @@ package url
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/thxhix/shortener/internal/config"
 	"github.com/thxhix/shortener/internal/database/interfaces"
 	customErrors "github.com/thxhix/shortener/internal/errors"
 	"github.com/thxhix/shortener/internal/middleware"
 	"github.com/thxhix/shortener/internal/models"
+	"github.com/thxhix/shortener/internal/useragent"
 	"log"
 	"sync"
 	"time"
@@ -16,24 +18,59 @@ import (
 // URLUseCaseInterface defines the business logic of the URL shortener service.
 // This interface is used to work with different storage implementations.
 type URLUseCaseInterface interface {
-	// Shorten generates a short link for the given URL and saves it to the database.
-	// If the URL already exists, returns the same short link with ErrDuplicate.
-	Shorten(ctx context.Context, url string) (string, error)
+	// Shorten generates a short link for the requested URL and saves it to the database
+	// with the requested limits and password. If an alias is requested, it is used
+	// as the short code instead of a generated one. If the URL already exists,
+	// returns the same short link with ErrDuplicate.
+	Shorten(ctx context.Context, request models.FullURL) (string, error)
 
-	// GetFullURL returns the original URL by its short hash.
-	// If the link has been deleted, returns ErrLinkDeleted.
-	GetFullURL(ctx context.Context, hash string) (string, error)
+	// GetFullURL returns the redirect to the original URL by its short hash.
+	// If the link has been deleted, returns ErrLinkDeleted,
+	// if it has expired or run out of clicks, returns ErrLinkExpired.
+	// If it is requested before its active window, returns ErrLinkNotActive.
+	// If the link is password-protected, returns ErrPasswordRequired.
+	// The destination is chosen by the targeting rules of the link
+	// matched against the attributes of the request.
+	GetFullURL(ctx context.Context, hash string, attrs models.RequestAttributes) (models.Redirect, error)
+
+	// UnlockURL returns the redirect to the original URL of a password-protected link
+	// if the password matches, and ErrWrongPassword otherwise. Links
+	// with too many recent failed attempts get ErrTooManyAttempts.
+	// Other errors are the same as in GetFullURL.
+	UnlockURL(ctx context.Context, hash string, password string, attrs models.RequestAttributes) (models.Redirect, error)
 
 	// PingDB checks the database connection.
 	PingDB() error
 
 	// BatchShorten accepts a list of URLs and stores them in the database in batch mode.
+	// Items with an alias use it as the short code.
+	// Each item may carry its own limits.
 	// Returns a list of generated short links.
 	BatchShorten(ctx context.Context, list models.BatchShortenRequestList) (models.BatchShortenResponseList, error)
 
 	// UserList returns all links of a user by their userID.
 	UserList(ctx context.Context, userID string) (models.UserLinksResponseList, error)
 
+	// UpdateURL changes the destination of the user's link, keeping the previous
+	// one in its history, and returns the new current version.
+	UpdateURL(ctx context.Context, userID string, hash string, original string) (models.LinkVersion, error)
+
+	// URLVersions returns all destinations of the user's link, oldest first.
+	URLVersions(ctx context.Context, userID string, hash string) (models.LinkVersionList, error)
+
+	// RollbackURL makes the user's link redirect to the destination
+	// of an earlier version and returns the new current version.
+	RollbackURL(ctx context.Context, userID string, hash string, version int) (models.LinkVersion, error)
+
+	// LinkStats returns the click statistics of the user's link over the requested range.
+	LinkStats(ctx context.Context, userID string, hash string, query models.StatsQuery) (models.LinkStats, error)
+
+	// LinkTargeting returns the targeting rules of the user's link.
+	LinkTargeting(ctx context.Context, userID string, hash string) (models.TargetingRuleList, error)
+
+	// UpdateTargeting replaces the targeting rules of the user's link.
+	UpdateTargeting(ctx context.Context, userID string, hash string, rules models.TargetingRuleList) (models.TargetingRuleList, error)
+
 	// UserDeleteRows deletes a set of user links concurrently.
 	// numWorkers – number of workers (goroutines).
 	// batchSize – number of links processed per worker batch.
@@ -43,46 +80,211 @@ type URLUseCaseInterface interface {
 // ErrLinkDeleted is returned when a deleted link is requested.
 var ErrLinkDeleted = errors.New("DELETED")
 
+// ErrCodeSpaceExhausted is returned when every generated short code collided
+// with an existing one, which means the code space is close to exhausted.
+var ErrCodeSpaceExhausted = errors.New("не удалось подобрать свободный короткий код")
+
 // URLUseCase is the main implementation of the business logic of the URL shortener service.
-// It operates on top of the abstract Database interface.
+// It operates on top of the abstract Database interface and gets short codes
+// from the CodeGenerator.
 type URLUseCase struct {
-	database interfaces.Database
-	cfg      *config.Config
+	database  interfaces.Database
+	cfg       *config.Config
+	generator CodeGenerator
+	attempts  *attemptLimiter
+	clock     Clock
+	agents    *useragent.Parser
 }
 
-// NewURLUseCase creates a new instance of URLUseCase with the given database and config.
-func NewURLUseCase(db interfaces.Database, cfg config.Config) *URLUseCase {
-	return &URLUseCase{
-		database: db,
-		cfg:      &cfg,
+// NewURLUseCase creates a new instance of URLUseCase with the given database,
+// config and generator of short codes. The system clock is used
+// unless another one is set with WithClock, user agents are classified
+// with the bundled rules unless WithUserAgentParser sets others.
+func NewURLUseCase(db interfaces.Database, cfg config.Config, generator CodeGenerator, opts ...Option) *URLUseCase {
+	u := &URLUseCase{
+		database:  db,
+		cfg:       &cfg,
+		generator: generator,
+		attempts:  newAttemptLimiter(cfg.LinkPasswordMaxAttempts, cfg.LinkPasswordAttemptWindow),
+		clock:     systemClock{},
+		agents:    useragent.Default(),
+	}
+	for _, opt := range opts {
+		opt(u)
 	}
+	return u
 }
 
-// Shorten generates a short link for the provided original URL and saves it to the database.
-// If the link already exists, returns the existing short link with ErrDuplicate.
-func (u *URLUseCase) Shorten(ctx context.Context, originalURL string) (string, error) {
-	shorten := GetHash()
-	shorten, err := u.database.AddLink(ctx, originalURL, shorten, middleware.GetUserID(ctx))
+// Shorten generates a short link for the requested original URL and saves it to the database.
+// If the link already exists, returns the existing short link with ErrDuplicate,
+// or ErrDuplicateOptions without it if its limits or password differ from the requested ones.
+// If the generated code is taken, a new one is generated, up to CodeMaxAttempts
+// times in total, after which ErrCodeSpaceExhausted is returned.
+//
+// If an alias is requested, it is validated and used as the short code as is:
+// returns ErrInvalidAlias if it fails validation and ErrAliasTaken
+// if another link uses it.
+//
+// The limits are validated as well, ErrInvalidLinkOptions is returned
+// if they are invalid. A one-time link gets MaxClicks of one, so that
+// exactly one redirect succeeds even under concurrent requests.
+// The password, if any, is stored as a bcrypt hash.
+// The limits and the password of an existing link are left as is.
+func (u *URLUseCase) Shorten(ctx context.Context, request models.FullURL) (string, error) {
+	opts, err := linkOptions(request.LinkOptions, request.Password, request.OneTime, u.clock.Now())
 	if err != nil {
-		if errors.Is(err, customErrors.ErrDuplicate) {
-			return shorten, customErrors.ErrDuplicate
+		return "", err
+	}
+	originalURL := request.URL
+	if request.Alias != "" {
+		return u.shortenWithAlias(ctx, originalURL, request.Alias, opts, request.Password)
+	}
+
+	for attempt := 0; attempt < u.maxAttempts(); attempt++ {
+		shorten, err := u.generator.Generate(ctx)
+		if err != nil {
+			return "", err
 		}
+
+		shorten, err = u.database.AddLink(ctx, originalURL, shorten, middleware.GetUserID(ctx), opts)
+		switch {
+		case err == nil:
+			return shorten, nil
+		case errors.Is(err, customErrors.ErrDuplicate):
+			return u.duplicate(ctx, shorten, opts, request.Password)
+		case errors.Is(err, customErrors.ErrHashCollision):
+			continue
+		default:
+			return "", err
+		}
+	}
+	return "", ErrCodeSpaceExhausted
+}
+
+// shortenWithAlias saves the link under the custom alias.
+func (u *URLUseCase) shortenWithAlias(ctx context.Context, originalURL string, alias string, opts models.LinkOptions, password string) (string, error) {
+	if err := ValidateAlias(alias); err != nil {
+		return "", err
+	}
+
+	shorten, err := u.database.AddLink(ctx, originalURL, alias, middleware.GetUserID(ctx), opts)
+	switch {
+	case err == nil:
+		return shorten, nil
+	case errors.Is(err, customErrors.ErrDuplicate):
+		return u.duplicate(ctx, shorten, opts, password)
+	case errors.Is(err, customErrors.ErrHashCollision):
+		return "", ErrAliasTaken
+	default:
 		return "", err
 	}
-	return shorten, nil
 }
 
-// GetFullURL returns the original URL by the given short hash.
-// If the link was deleted, returns ErrLinkDeleted.
-func (u *URLUseCase) GetFullURL(ctx context.Context, hash string) (string, error) {
-	link, err := u.database.GetFullLink(ctx, hash)
+// duplicate returns the existing short link of an original URL with ErrDuplicate
+// if the link has the requested options and password, or ErrDuplicateOptions otherwise.
+func (u *URLUseCase) duplicate(ctx context.Context, existing string, opts models.LinkOptions, password string) (string, error) {
+	link, err := u.database.GetFullLink(ctx, existing)
 	if err != nil {
 		return "", err
 	}
+	if !sameOptions(link.LinkOptions, opts, password) {
+		return "", ErrDuplicateOptions
+	}
+	return existing, customErrors.ErrDuplicate
+}
+
+// maxAttempts returns how many codes may be generated for a single write.
+func (u *URLUseCase) maxAttempts() int {
+	if u.cfg.CodeMaxAttempts > 0 {
+		return u.cfg.CodeMaxAttempts
+	}
+	return DefaultCodeAttempts
+}
+
+// GetFullURL returns the redirect to the original URL by the given short hash
+// with the status code and caching of the link.
+// If the link was deleted, returns ErrLinkDeleted. If it has passed
+// its ExpiresAt or ActiveUntil or run out of clicks, returns ErrLinkExpired.
+// If ActiveFrom has not come yet, returns ErrLinkNotActive.
+// If the link is password-protected, returns ErrPasswordRequired.
+// Redirects of links with MaxClicks are counted, except those of bots.
+// Links with targeting rules redirect to the URL of the first rule
+// the request attributes match.
+func (u *URLUseCase) GetFullURL(ctx context.Context, hash string, attrs models.RequestAttributes) (models.Redirect, error) {
+	link, err := u.activeLink(ctx, hash)
+	if err != nil {
+		return models.Redirect{}, err
+	}
+	if link.PasswordHash != "" {
+		return models.Redirect{}, ErrPasswordRequired
+	}
+	return u.follow(ctx, link, attrs)
+}
+
+// UnlockURL returns the redirect to the original URL by the given short hash if the password
+// matches the one of the link. Failed attempts are limited per link:
+// after LinkPasswordMaxAttempts of them within LinkPasswordAttemptWindow,
+// ErrTooManyAttempts is returned without checking the password.
+// The password of a link that is not protected is ignored.
+func (u *URLUseCase) UnlockURL(ctx context.Context, hash string, password string, attrs models.RequestAttributes) (models.Redirect, error) {
+	link, err := u.activeLink(ctx, hash)
+	if err != nil {
+		return models.Redirect{}, err
+	}
+
+	if link.PasswordHash != "" {
+		now := u.clock.Now()
+		if !u.attempts.reserve(hash, now) {
+			return models.Redirect{}, ErrTooManyAttempts
+		}
+		if !checkPassword(link.PasswordHash, password) {
+			return models.Redirect{}, ErrWrongPassword
+		}
+		u.attempts.reset(hash)
+	}
+	return u.follow(ctx, link, attrs)
+}
+
+// activeLink returns the link by the given short hash, or ErrLinkDeleted
+// and ErrLinkExpired if it no longer redirects and ErrLinkNotActive
+// if it does not redirect yet.
+func (u *URLUseCase) activeLink(ctx context.Context, hash string) (models.DBShortenRow, error) {
+	link, err := u.database.GetFullLink(ctx, hash)
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
 	if link.IsDeleted {
-		return "", ErrLinkDeleted
+		return models.DBShortenRow{}, ErrLinkDeleted
 	}
-	return link.URL, nil
+
+	now := u.clock.Now()
+	if link.ExpiredAt(now) {
+		return models.DBShortenRow{}, ErrLinkExpired
+	}
+	if link.PendingAt(now) {
+		return models.DBShortenRow{}, ErrLinkNotActive
+	}
+	return link, nil
+}
+
+// follow counts the redirect of a link with MaxClicks and returns the redirect
+// to its original URL, or to the URL of the targeting rule the request matches.
+// Bots get ErrLimitedForBots for links with MaxClicks, so that link previews
+// neither use up one-time links before the recipient follows them nor learn
+// the destination, since anyone can pretend to be a bot.
+func (u *URLUseCase) follow(ctx context.Context, link models.DBShortenRow, attrs models.RequestAttributes) (models.Redirect, error) {
+	if link.MaxClicks > 0 {
+		if attrs.Bot {
+			return models.Redirect{}, ErrLimitedForBots
+		}
+		// Счётчик проверяется в хранилище атомарно, строка выше могла устареть
+		if err := u.database.AddClick(ctx, link.Hash); err != nil {
+			return models.Redirect{}, err
+		}
+	}
+	result := redirect(link, u.clock.Now())
+	result.URL = target(link, attrs)
+	return result, nil
 }
 
 // PingDB checks if the database connection is alive.
@@ -92,30 +294,80 @@ func (u *URLUseCase) PingDB() error {
 
 // BatchShorten accepts a list of URLs and saves them in the database in batch mode.
 // Returns a list of generated short links with their IDs.
+// The batch is stored atomically, so if any generated code is taken, codes
+// for the whole batch are generated anew, up to CodeMaxAttempts times in total,
+// after which ErrCodeSpaceExhausted is returned.
+//
+// Items with an alias use it as the short code. Aliases are validated before
+// anything is stored, and ErrAliasTaken is returned if any of them is used.
+// Limits and passwords of the items are handled the same way as in Shorten.
+// If any original URL is already shortened, ErrDuplicate is returned and nothing
+// is stored, so existing links are never handed out in place of protected ones.
 func (u *URLUseCase) BatchShorten(ctx context.Context, list models.BatchShortenRequestList) (models.BatchShortenResponseList, error) {
-	var result models.DBShortenRowList
-	var response models.BatchShortenResponseList
+	now := u.clock.Now()
+	result := make(models.DBShortenRowList, len(list))
+	aliases := make(map[string]struct{})
+	for i, batch := range list {
+		opts, err := linkOptions(batch.LinkOptions, batch.Password, batch.OneTime, now)
+		if err != nil {
+			return nil, err
+		}
+		result[i].URL = batch.URL
+		result[i].LinkOptions = opts
+		if batch.Alias == "" {
+			continue
+		}
+		if err := ValidateAlias(batch.Alias); err != nil {
+			return nil, err
+		}
+		if _, exists := aliases[batch.Alias]; exists {
+			return nil, fmt.Errorf("%w: %q повторяется в запросе", ErrInvalidAlias, batch.Alias)
+		}
+		aliases[batch.Alias] = struct{}{}
+		result[i].Hash = batch.Alias
+	}
 
-	for _, batch := range list {
-		row := models.DBShortenRow{
-			Hash: GetHash(),
-			URL:  batch.URL,
+attempts:
+	for attempt := 0; attempt < u.maxAttempts(); attempt++ {
+		for i := range result {
+			if list[i].Alias != "" {
+				continue
+			}
+			hash, err := u.generator.Generate(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if _, isAlias := aliases[hash]; isAlias {
+				// Сгенерированный код совпал с alias из того же запроса
+				continue attempts
+			}
+			result[i].Hash = hash
 		}
-		result = append(result, row)
 
-		responseRow := models.BatchShortenResponse{
-			ID:   batch.ID,
-			Hash: u.cfg.BaseURL + "/" + row.Hash,
+		err := u.database.AddLinks(ctx, result, middleware.GetUserID(ctx))
+		if errors.Is(err, customErrors.ErrHashCollision) {
+			var collision *customErrors.HashCollisionError
+			if errors.As(err, &collision) {
+				if _, isAlias := aliases[collision.Hash]; isAlias {
+					return nil, fmt.Errorf("%w: %s", ErrAliasTaken, collision.Hash)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
 		}
-		response = append(response, responseRow)
-	}
 
-	err := u.database.AddLinks(ctx, result, middleware.GetUserID(ctx))
-	if err != nil {
-		return nil, err
+		var response models.BatchShortenResponseList
+		for i, batch := range list {
+			response = append(response, models.BatchShortenResponse{
+				ID:   batch.ID,
+				Hash: u.cfg.BaseURL + "/" + result[i].Hash,
+			})
+		}
+		return response, nil
 	}
-
-	return response, nil
+	return nil, ErrCodeSpaceExhausted
 }
 
 // UserList returns all user links with full short URLs.
@@ -129,8 +381,11 @@ func (u *URLUseCase) UserList(ctx context.Context, userID string) (models.UserLi
 
 	for _, link := range links {
 		row := models.UserLinksResponse{
-			Original: link.URL,
-			Short:    u.cfg.BaseURL + "/" + link.Hash,
+			Original:     link.URL,
+			Short:        u.cfg.BaseURL + "/" + link.Hash,
+			RedirectCode: link.RedirectCode,
+			CacheMaxAge:  link.CacheMaxAge,
+			Targeting:    link.Targeting,
 		}
 		result = append(result, row)
 	}
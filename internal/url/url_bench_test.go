@@ -4,34 +4,37 @@ import (
 	"context"
 	"github.com/thxhix/shortener/internal/config"
 	"github.com/thxhix/shortener/internal/database/drivers"
+	"github.com/thxhix/shortener/internal/models"
 	"strconv"
 	"testing"
 )
 
 func BenchmarkShorten(b *testing.B) {
 	cfg := config.Config{BaseURL: "http://localhost:8080"}
-	db, _ := drivers.NewFileDatabase("./tmp_bench.json")
-	uc := NewURLUseCase(db, cfg)
+	db, _ := drivers.NewFileDatabase("./tmp_bench.json", drivers.FileOptions{})
+	defer db.Close()
+	uc := NewURLUseCase(db, cfg, NewRandomGenerator(DefaultCodeLength))
 
 	ctx := context.Background()
 
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		_, _ = uc.Shorten(ctx, "https://example.com/bench"+strconv.Itoa(i))
+		_, _ = uc.Shorten(ctx, models.FullURL{URL: "https://example.com/bench" + strconv.Itoa(i)})
 	}
 }
 
 func BenchmarkGetFullURL(b *testing.B) {
 	cfg := config.Config{BaseURL: "http://localhost:8080"}
-	db, _ := drivers.NewFileDatabase("./tmp_bench.json")
-	uc := NewURLUseCase(db, cfg)
+	db, _ := drivers.NewFileDatabase("./tmp_bench.json", drivers.FileOptions{})
+	defer db.Close()
+	uc := NewURLUseCase(db, cfg, NewRandomGenerator(DefaultCodeLength))
 
 	ctx := context.Background()
 
-	shorten, _ := uc.Shorten(ctx, "https://example.com/bench")
+	shorten, _ := uc.Shorten(ctx, models.FullURL{URL: "https://example.com/bench"})
 
 	b.ResetTimer()
 
-	_, _ = uc.GetFullURL(ctx, shorten)
+	_, _ = uc.GetFullURL(ctx, shorten, models.RequestAttributes{})
 }
@@ -0,0 +1,640 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thxhix/shortener/internal/config"
+	"github.com/thxhix/shortener/internal/database/drivers"
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/middleware"
+	"github.com/thxhix/shortener/internal/models"
+	"github.com/thxhix/shortener/internal/useragent"
+)
+
+// fixedGenerator returns the given codes in order, repeating the last one.
+type fixedGenerator struct {
+	codes []string
+}
+
+func (g *fixedGenerator) Generate(ctx context.Context) (string, error) {
+	code := g.codes[0]
+	if len(g.codes) > 1 {
+		g.codes = g.codes[1:]
+	}
+	return code, nil
+}
+
+func newTestUseCase(t *testing.T, codes ...string) (*URLUseCase, *drivers.MemoryDatabase) {
+	db, err := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
+	require.NoError(t, err)
+
+	cfg := config.Config{BaseURL: "http://localhost:8080", CodeMaxAttempts: 3}
+	return NewURLUseCase(db, cfg, &fixedGenerator{codes: codes}), db
+}
+
+func TestURLUseCase_ShortenCollision(t *testing.T) {
+	uc, db := newTestUseCase(t, "aaa", "aaa", "bbb")
+	ctx := context.Background()
+
+	shorten, err := uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru"})
+	require.NoError(t, err)
+	require.Equal(t, "aaa", shorten)
+
+	shorten, err = uc.Shorten(ctx, models.FullURL{URL: "https://google.com"})
+	require.NoError(t, err)
+	require.Equal(t, "bbb", shorten, "при коллизии код должен генерироваться заново")
+
+	shorten, err = uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru"})
+	require.ErrorIs(t, err, customErrors.ErrDuplicate)
+	require.Equal(t, "aaa", shorten, "для дубликата ссылки должен вернуться существующий код")
+
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://test.ru"})
+	require.ErrorIs(t, err, ErrCodeSpaceExhausted)
+
+	_, err = db.GetFullLink(ctx, "bbb")
+	require.NoError(t, err)
+}
+
+func TestURLUseCase_BatchShortenCollision(t *testing.T) {
+	uc, db := newTestUseCase(t, "aaa", "bbb", "aaa", "ccc", "ddd")
+	ctx := context.Background()
+
+	_, err := uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru"})
+	require.NoError(t, err)
+
+	response, err := uc.BatchShorten(ctx, models.BatchShortenRequestList{
+		{ID: "1", URL: "https://google.com"},
+		{ID: "2", URL: "https://test.ru"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, models.BatchShortenResponseList{
+		{ID: "1", Hash: "http://localhost:8080/ccc"},
+		{ID: "2", Hash: "http://localhost:8080/ddd"},
+	}, response, "при коллизии коды всего batch должны генерироваться заново")
+
+	row, err := db.GetFullLink(ctx, "ddd")
+	require.NoError(t, err)
+	require.Equal(t, "https://test.ru", row.URL)
+
+	_, err = uc.BatchShorten(ctx, models.BatchShortenRequestList{
+		{ID: "1", URL: "https://example.com"},
+		{ID: "2", URL: "https://example.org"},
+	})
+	require.ErrorIs(t, err, ErrCodeSpaceExhausted, "повторяющийся код исчерпывает попытки")
+}
+
+func TestURLUseCase_ShortenDuplicateOptions(t *testing.T) {
+	uc, _ := newTestUseCase(t, "aaa", "bbb", "ccc")
+	ctx := context.Background()
+
+	_, err := uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru"})
+	require.NoError(t, err)
+
+	// Защищённая ссылка не должна подменяться существующей незащищённой
+	for _, request := range []models.FullURL{
+		{URL: "https://ya.ru", Password: "secret"},
+		{URL: "https://ya.ru", OneTime: true},
+		{URL: "https://ya.ru", Alias: "promo", Password: "secret"},
+	} {
+		shorten, err := uc.Shorten(ctx, request)
+		require.ErrorIs(t, err, ErrDuplicateOptions)
+		require.NotErrorIs(t, err, customErrors.ErrDuplicate)
+		require.Empty(t, shorten, "существующая ссылка с другими ограничениями не должна возвращаться")
+	}
+
+	protected, err := uc.Shorten(ctx, models.FullURL{URL: "https://google.com", Password: "secret", OneTime: true})
+	require.NoError(t, err)
+
+	shorten, err := uc.Shorten(ctx, models.FullURL{URL: "https://google.com", Password: "secret", OneTime: true})
+	require.ErrorIs(t, err, customErrors.ErrDuplicate, "те же ограничения и пароль дают существующую ссылку")
+	require.Equal(t, protected, shorten)
+	for _, request := range []models.FullURL{
+		{URL: "https://google.com", Password: "other", OneTime: true},
+		{URL: "https://google.com", OneTime: true},
+		{URL: "https://google.com", Password: "secret"},
+	} {
+		_, err = uc.Shorten(ctx, request)
+		require.ErrorIs(t, err, ErrDuplicateOptions)
+	}
+
+	response, err := uc.BatchShorten(ctx, models.BatchShortenRequestList{
+		{ID: "1", URL: "https://ya.ru", OneTime: true},
+	})
+	require.ErrorIs(t, err, customErrors.ErrDuplicate)
+	require.Empty(t, response, "пакет с дубликатом не должен возвращать существующие ссылки")
+}
+
+func TestURLUseCase_ShortenAlias(t *testing.T) {
+	uc, _ := newTestUseCase(t, "aaa")
+	ctx := context.Background()
+
+	shorten, err := uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru", Alias: "promo"})
+	require.NoError(t, err)
+	require.Equal(t, "promo", shorten)
+
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://google.com", Alias: "promo"})
+	require.ErrorIs(t, err, ErrAliasTaken)
+	require.NotErrorIs(t, err, customErrors.ErrDuplicate, "занятый alias не должен считаться дубликатом ссылки")
+
+	shorten, err = uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru", Alias: "other"})
+	require.ErrorIs(t, err, customErrors.ErrDuplicate)
+	require.Equal(t, "promo", shorten)
+
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://google.com", Alias: "ping"})
+	require.ErrorIs(t, err, ErrInvalidAlias)
+}
+
+func TestURLUseCase_BatchShortenAlias(t *testing.T) {
+	uc, db := newTestUseCase(t, "promo", "aaa", "bbb")
+	ctx := context.Background()
+
+	response, err := uc.BatchShorten(ctx, models.BatchShortenRequestList{
+		{ID: "1", URL: "https://ya.ru", Alias: "promo"},
+		{ID: "2", URL: "https://google.com"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, models.BatchShortenResponseList{
+		{ID: "1", Hash: "http://localhost:8080/promo"},
+		{ID: "2", Hash: "http://localhost:8080/aaa"},
+	}, response, "сгенерированный код не должен совпадать с alias из запроса")
+
+	_, err = uc.BatchShorten(ctx, models.BatchShortenRequestList{
+		{ID: "1", URL: "https://test.ru"},
+		{ID: "2", URL: "https://example.com", Alias: "promo"},
+	})
+	require.ErrorIs(t, err, ErrAliasTaken)
+
+	_, err = db.GetFullLink(ctx, "bbb")
+	require.ErrorIs(t, err, customErrors.ErrNotFound, "batch с занятым alias не должен ничего сохранять")
+
+	_, err = uc.BatchShorten(ctx, models.BatchShortenRequestList{
+		{ID: "1", URL: "https://test.ru", Alias: "same"},
+		{ID: "2", URL: "https://example.com", Alias: "same"},
+	})
+	require.ErrorIs(t, err, ErrInvalidAlias)
+}
+
+func TestURLUseCase_LinkLimits(t *testing.T) {
+	uc, db := newTestUseCase(t, "aaa", "bbb", "ccc")
+	ctx := context.Background()
+
+	_, err := uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru", LinkOptions: models.LinkOptions{MaxClicks: 2}})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		link, err := uc.GetFullURL(ctx, "aaa", models.RequestAttributes{})
+		require.NoError(t, err)
+		require.Equal(t, "https://ya.ru", link.URL)
+	}
+	_, err = uc.GetFullURL(ctx, "aaa", models.RequestAttributes{})
+	require.ErrorIs(t, err, ErrLinkExpired, "после лимита переходов ссылка должна истечь")
+
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://google.com", LinkOptions: models.LinkOptions{ExpiresAt: &expiresAt}})
+	require.NoError(t, err)
+
+	_, err = uc.GetFullURL(ctx, "bbb", models.RequestAttributes{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, err := uc.GetFullURL(ctx, "bbb", models.RequestAttributes{})
+		return errors.Is(err, ErrLinkExpired)
+	}, time.Second, 10*time.Millisecond, "ссылка должна истечь без прохода sweeper")
+
+	require.NoError(t, db.RemoveUserLinks(ctx, "", []string{"bbb"}))
+	_, err = uc.GetFullURL(ctx, "bbb", models.RequestAttributes{})
+	require.ErrorIs(t, err, ErrLinkDeleted, "удаление важнее истечения")
+
+	past := time.Now().Add(-time.Minute)
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://test.ru", LinkOptions: models.LinkOptions{ExpiresAt: &past}})
+	require.ErrorIs(t, err, ErrInvalidLinkOptions)
+
+	_, err = uc.BatchShorten(ctx, models.BatchShortenRequestList{
+		{ID: "1", URL: "https://test.ru", LinkOptions: models.LinkOptions{MaxClicks: -1}},
+	})
+	require.ErrorIs(t, err, ErrInvalidLinkOptions)
+}
+
+func TestURLUseCase_Password(t *testing.T) {
+	uc, db := newTestUseCase(t, "aaa", "bbb")
+	uc.attempts = newAttemptLimiter(2, time.Minute)
+	ctx := context.Background()
+
+	_, err := uc.Shorten(ctx, models.FullURL{
+		URL:         "https://ya.ru",
+		Password:    "secret",
+		LinkOptions: models.LinkOptions{PasswordHash: "forged"},
+	})
+	require.NoError(t, err)
+
+	row, err := db.GetFullLink(ctx, "aaa")
+	require.NoError(t, err)
+	require.NotContains(t, row.PasswordHash, "secret", "пароль не должен храниться в открытом виде")
+	require.NotEqual(t, "forged", row.PasswordHash, "хэш от клиента должен игнорироваться")
+
+	_, err = uc.GetFullURL(ctx, "aaa", models.RequestAttributes{})
+	require.ErrorIs(t, err, ErrPasswordRequired)
+
+	link, err := uc.UnlockURL(ctx, "aaa", "secret", models.RequestAttributes{})
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru", link.URL)
+
+	for i := 0; i < 2; i++ {
+		_, err = uc.UnlockURL(ctx, "aaa", "wrong", models.RequestAttributes{})
+		require.ErrorIs(t, err, ErrWrongPassword)
+	}
+	_, err = uc.UnlockURL(ctx, "aaa", "secret", models.RequestAttributes{})
+	require.ErrorIs(t, err, ErrTooManyAttempts, "после лимита неудачных попыток пароль не должен проверяться")
+
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://google.com", LinkOptions: models.LinkOptions{PasswordHash: "forged"}})
+	require.NoError(t, err)
+	link, err = uc.GetFullURL(ctx, "bbb", models.RequestAttributes{})
+	require.NoError(t, err, "ссылка без пароля не должна требовать его")
+	require.Equal(t, "https://google.com", link.URL)
+
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://test.ru", Password: strings.Repeat("x", MaxPasswordLength+1)})
+	require.ErrorIs(t, err, ErrInvalidLinkOptions)
+}
+
+func TestURLUseCase_PasswordConcurrent(t *testing.T) {
+	uc, _ := newTestUseCase(t, "aaa")
+	uc.attempts = newAttemptLimiter(2, time.Minute)
+	ctx := context.Background()
+
+	_, err := uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru", Password: "secret"})
+	require.NoError(t, err)
+
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := uc.UnlockURL(ctx, "aaa", "wrong", models.RequestAttributes{})
+			errs <- err
+		}()
+	}
+
+	checked := 0
+	for i := 0; i < cap(errs); i++ {
+		err := <-errs
+		if errors.Is(err, ErrWrongPassword) {
+			checked++
+			continue
+		}
+		require.ErrorIs(t, err, ErrTooManyAttempts)
+	}
+	require.Equal(t, 2, checked, "параллельные попытки не должны проверять больше паролей, чем позволяет лимит")
+}
+
+func TestURLUseCase_OneTime(t *testing.T) {
+	uc, _ := newTestUseCase(t, "aaa", "bbb")
+	ctx := context.Background()
+
+	_, err := uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru", OneTime: true})
+	require.NoError(t, err)
+
+	// Бот не получает адрес ссылки и не расходует её переход
+	_, err = uc.GetFullURL(ctx, "aaa", models.RequestAttributes{Bot: true})
+	require.ErrorIs(t, err, ErrLimitedForBots)
+
+	link, err := uc.GetFullURL(ctx, "aaa", models.RequestAttributes{})
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru", link.URL)
+
+	_, err = uc.GetFullURL(ctx, "aaa", models.RequestAttributes{})
+	require.ErrorIs(t, err, ErrLinkExpired, "одноразовая ссылка должна истечь после первого перехода")
+
+	_, err = uc.BatchShorten(ctx, models.BatchShortenRequestList{
+		{ID: "1", URL: "https://google.com", OneTime: true, LinkOptions: models.LinkOptions{MaxClicks: 2}},
+	})
+	require.ErrorIs(t, err, ErrInvalidLinkOptions)
+}
+
+// fakeClock is a Clock set by the test.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestURLUseCase_ActivationWindow(t *testing.T) {
+	db, err := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
+	require.NoError(t, err)
+	start := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	uc := NewURLUseCase(db, config.Config{}, &fixedGenerator{codes: []string{"aaa"}}, WithClock(clock))
+	ctx := context.Background()
+
+	from := start.Add(time.Hour)
+	until := start.Add(2 * time.Hour)
+	_, err = uc.Shorten(ctx, models.FullURL{
+		URL:         "https://ya.ru",
+		LinkOptions: models.LinkOptions{ActiveFrom: &from, ActiveUntil: &until},
+	})
+	require.NoError(t, err)
+
+	_, err = uc.GetFullURL(ctx, "aaa", models.RequestAttributes{})
+	require.ErrorIs(t, err, ErrLinkNotActive, "до начала окна ссылка не должна работать")
+
+	clock.now = from
+	link, err := uc.GetFullURL(ctx, "aaa", models.RequestAttributes{})
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru", link.URL)
+
+	clock.now = until
+	_, err = uc.GetFullURL(ctx, "aaa", models.RequestAttributes{})
+	require.ErrorIs(t, err, ErrLinkExpired, "после окончания окна ссылка должна истечь")
+
+	_, err = uc.Shorten(ctx, models.FullURL{
+		URL:         "https://google.com",
+		LinkOptions: models.LinkOptions{ActiveFrom: &until, ActiveUntil: &until},
+	})
+	require.ErrorIs(t, err, ErrInvalidLinkOptions, "окно не может заканчиваться раньше начала")
+}
+
+func TestURLUseCase_Versions(t *testing.T) {
+	uc, _ := newTestUseCase(t, "aaa")
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "owner")
+
+	_, err := uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru/typo"})
+	require.NoError(t, err)
+
+	_, err = uc.UpdateURL(ctx, "owner", "aaa", "not a url")
+	require.ErrorIs(t, err, ErrInvalidURL)
+	_, err = uc.UpdateURL(ctx, "stranger", "aaa", "https://ya.ru/fixed")
+	require.ErrorIs(t, err, customErrors.ErrNotOwner)
+	_, err = uc.URLVersions(ctx, "stranger", "aaa")
+	require.ErrorIs(t, err, customErrors.ErrNotOwner, "чужую историю смотреть нельзя")
+
+	version, err := uc.UpdateURL(ctx, "owner", "aaa", "https://ya.ru/fixed")
+	require.NoError(t, err)
+	require.Equal(t, models.LinkVersion{Version: 2, URL: "https://ya.ru/fixed", Current: true}, version)
+
+	link, err := uc.GetFullURL(ctx, "aaa", models.RequestAttributes{})
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru/fixed", link.URL, "редирект должен вести на новый адрес")
+
+	version, err = uc.RollbackURL(ctx, "owner", "aaa", 1)
+	require.NoError(t, err)
+	require.Equal(t, models.LinkVersion{Version: 3, URL: "https://ya.ru/typo", Current: true}, version,
+		"откат сохраняется как новая версия")
+
+	versions, err := uc.URLVersions(ctx, "owner", "aaa")
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	for i, original := range []string{"https://ya.ru/typo", "https://ya.ru/fixed", "https://ya.ru/typo"} {
+		require.Equal(t, i+1, versions[i].Version)
+		require.Equal(t, original, versions[i].URL)
+	}
+	require.True(t, versions[2].Current)
+
+	version, err = uc.RollbackURL(ctx, "owner", "aaa", 3)
+	require.NoError(t, err)
+	require.Equal(t, 3, version.Version, "откат на текущую версию ничего не меняет")
+
+	_, err = uc.RollbackURL(ctx, "owner", "aaa", 7)
+	require.ErrorIs(t, err, ErrVersionNotFound)
+	_, err = uc.RollbackURL(ctx, "owner", "zzz", 1)
+	require.ErrorIs(t, err, customErrors.ErrNotFound)
+}
+
+func TestURLUseCase_Targeting(t *testing.T) {
+	uc, _ := newTestUseCase(t, "aaa")
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "owner")
+	iPhone := models.RequestAttributes{Device: "mobile", OS: "iOS"}
+
+	_, err := uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru", LinkOptions: models.LinkOptions{
+		Targeting: models.TargetingRuleList{{URL: "https://apps.apple.com"}},
+	}})
+	require.ErrorIs(t, err, ErrInvalidLinkOptions, "правила проверяются при создании ссылки")
+
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru", LinkOptions: models.LinkOptions{
+		Targeting: models.TargetingRuleList{{OS: "iOS", URL: "https://apps.apple.com/app/id1"}},
+	}})
+	require.NoError(t, err)
+	link, err := uc.GetFullURL(ctx, "aaa", iPhone)
+	require.NoError(t, err)
+	require.Equal(t, "https://apps.apple.com/app/id1", link.URL)
+	link, err = uc.GetFullURL(ctx, "aaa", models.RequestAttributes{Device: "desktop", OS: "Windows"})
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru", link.URL, "без подходящего правила ведём на основной адрес")
+
+	_, err = uc.UpdateTargeting(ctx, "stranger", "aaa", nil)
+	require.ErrorIs(t, err, customErrors.ErrNotOwner)
+	_, err = uc.LinkTargeting(ctx, "stranger", "aaa")
+	require.ErrorIs(t, err, customErrors.ErrNotOwner, "чужие правила смотреть нельзя")
+	_, err = uc.UpdateTargeting(ctx, "owner", "aaa", models.TargetingRuleList{{Device: "tv", URL: "https://ya.ru/tv"}})
+	require.ErrorIs(t, err, ErrInvalidLinkOptions)
+
+	android := models.TargetingRuleList{{OS: "Android", URL: "https://play.google.com/store/apps/details?id=app"}}
+	rules, err := uc.UpdateTargeting(ctx, "owner", "aaa", android)
+	require.NoError(t, err)
+	require.Equal(t, android, rules)
+	link, err = uc.GetFullURL(ctx, "aaa", iPhone)
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru", link.URL, "правила заменяются целиком")
+
+	rules, err = uc.UpdateTargeting(ctx, "owner", "aaa", nil)
+	require.NoError(t, err)
+	require.Equal(t, models.TargetingRuleList{}, rules, "пустой список снимает таргетинг")
+	rules, err = uc.LinkTargeting(ctx, "owner", "aaa")
+	require.NoError(t, err)
+	require.Empty(t, rules)
+}
+
+func TestURLUseCase_LinkStats(t *testing.T) {
+	db, err := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
+	require.NoError(t, err)
+	now := time.Date(2024, time.May, 15, 12, 30, 0, 0, time.UTC)
+	uc := NewURLUseCase(db, config.Config{}, &fixedGenerator{codes: []string{"aaa"}}, WithClock(&fakeClock{now: now}))
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "owner")
+
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru"})
+	require.NoError(t, err)
+
+	chrome := "Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	err = db.AddClickEvents(ctx, models.ClickEventList{
+		{Hash: "aaa", Time: now.Add(-26 * time.Hour), IP: "10.0.0.1", UserAgent: chrome, Referrer: "https://google.com/search"},
+		{Hash: "aaa", Time: now.Add(-2 * time.Hour), IP: "10.0.0.1", UserAgent: chrome, Referrer: "https://www.google.com/"},
+		{Hash: "aaa", Time: now.Add(-time.Hour), IP: "10.0.0.2", UserAgent: "curl/8.0"},
+		{Hash: "aaa", Time: now.Add(-time.Hour), IP: "10.0.0.5", UserAgent: "Mozilla/5.0 (compatible; Googlebot/2.1)"},
+		{Hash: "aaa", Time: now.Add(-30 * 24 * time.Hour), IP: "10.0.0.3"},
+		{Hash: "bbb", Time: now.Add(-time.Hour), IP: "10.0.0.4"},
+	})
+	require.NoError(t, err)
+
+	stats, err := uc.LinkStats(ctx, "owner", "aaa", models.StatsQuery{})
+	require.NoError(t, err)
+	require.Equal(t, now.Add(-DefaultStatsRange), stats.From)
+	require.Equal(t, now, stats.To)
+	require.Equal(t, BucketDay, stats.Bucket)
+	require.Equal(t, 3, stats.TotalClicks, "учитываются только переходы за период")
+	require.Equal(t, 2, stats.UniqueVisitors)
+	require.Len(t, stats.Series, 8, "неполные крайние дни тоже попадают в ряд")
+	require.Equal(t, 1, stats.Series[6].Clicks)
+	require.Equal(t, 2, stats.Series[7].Clicks)
+	require.Equal(t, models.StatsCountList{{Name: "google.com", Clicks: 2}, {Name: directReferrer, Clicks: 1}}, stats.TopReferrers)
+	require.Equal(t, models.StatsCountList{{Name: "Chrome", Clicks: 2}, {Name: "curl", Clicks: 1}}, stats.UserAgents)
+	require.Equal(t, models.StatsCountList{{Name: "other", Clicks: 3}}, stats.Devices, "переходы ботов не учитываются")
+
+	from := now.Add(-3 * time.Hour)
+	stats, err = uc.LinkStats(ctx, "owner", "aaa", models.StatsQuery{From: &from, Bucket: BucketHour})
+	require.NoError(t, err)
+	require.Len(t, stats.Series, 4)
+	require.Equal(t, []int{0, 1, 1, 0}, []int{stats.Series[0].Clicks, stats.Series[1].Clicks, stats.Series[2].Clicks, stats.Series[3].Clicks})
+
+	_, err = uc.LinkStats(ctx, "stranger", "aaa", models.StatsQuery{})
+	require.ErrorIs(t, err, customErrors.ErrNotOwner, "чужую статистику смотреть нельзя")
+	_, err = uc.LinkStats(ctx, "owner", "aaa", models.StatsQuery{Bucket: "minute"})
+	require.ErrorIs(t, err, ErrInvalidStatsQuery)
+	_, err = uc.LinkStats(ctx, "owner", "aaa", models.StatsQuery{From: &now})
+	require.ErrorIs(t, err, ErrInvalidStatsQuery, "пустой период")
+	long := now.Add(-365 * 24 * time.Hour)
+	_, err = uc.LinkStats(ctx, "owner", "aaa", models.StatsQuery{From: &long, Bucket: BucketHour})
+	require.ErrorIs(t, err, ErrInvalidStatsQuery, "слишком много интервалов")
+}
+
+func TestURLUseCase_LinkStatsUserAgentParser(t *testing.T) {
+	db, err := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
+	require.NoError(t, err)
+	agents, err := useragent.NewParser(useragent.Rules{
+		Bots:     []useragent.Rule{{Contains: "InternalCrawler", Name: "InternalCrawler"}},
+		Browsers: []useragent.Rule{{Contains: "AcmeBrowser", Name: "Acme"}},
+	})
+	require.NoError(t, err)
+	now := time.Date(2024, time.May, 15, 12, 30, 0, 0, time.UTC)
+	uc := NewURLUseCase(db, config.Config{}, &fixedGenerator{codes: []string{"aaa"}}, WithClock(&fakeClock{now: now}), WithUserAgentParser(agents))
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "owner")
+
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru"})
+	require.NoError(t, err)
+
+	// События без классификации разбираются настроенными, а не встроенными правилами
+	err = db.AddClickEvents(ctx, models.ClickEventList{
+		{Hash: "aaa", Time: now.Add(-time.Hour), IP: "10.0.0.1", UserAgent: "AcmeBrowser/1.0"},
+		{Hash: "aaa", Time: now.Add(-time.Hour), IP: "10.0.0.2", UserAgent: "InternalCrawler/2.0"},
+	})
+	require.NoError(t, err)
+
+	stats, err := uc.LinkStats(ctx, "owner", "aaa", models.StatsQuery{})
+	require.NoError(t, err)
+	require.Equal(t, models.StatsCountList{{Name: "Acme", Clicks: 1}}, stats.UserAgents, "бот по настроенным правилам не учитывается")
+	require.Equal(t, models.StatsCountList{{Name: "other", Clicks: 1}}, stats.Devices)
+}
+
+func TestURLUseCase_LinkStatsRollups(t *testing.T) {
+	db, err := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
+	require.NoError(t, err)
+	now := time.Date(2024, time.May, 15, 12, 30, 0, 0, time.UTC)
+	uc := NewURLUseCase(db, config.Config{}, &fixedGenerator{codes: []string{"aaa"}}, WithClock(&fakeClock{now: now}))
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "owner")
+
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru"})
+	require.NoError(t, err)
+
+	err = db.AddClickEvents(ctx, models.ClickEventList{
+		{Hash: "aaa", Time: now.Add(-50 * time.Hour), IP: "10.0.0.1"},
+		{Hash: "aaa", Time: now.Add(-26 * time.Hour), IP: "10.0.0.1"},
+		{Hash: "aaa", Time: now.Add(-2 * time.Hour), IP: "10.0.0.2"},
+		{Hash: "aaa", Time: now.Add(-time.Hour), IP: "10.0.0.3"},
+	})
+	require.NoError(t, err)
+
+	watermark := now.Add(-90 * time.Minute).Truncate(time.Hour)
+	_, err = db.RollupClicks(ctx, watermark)
+	require.NoError(t, err)
+	_, err = db.PruneClicks(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+
+	stats, err := uc.LinkStats(ctx, "owner", "aaa", models.StatsQuery{})
+	require.NoError(t, err)
+	require.Equal(t, 4, stats.TotalClicks, "удалённые события учитываются по сводкам")
+	require.Equal(t, []int{1, 1, 2}, []int{stats.Series[5].Clicks, stats.Series[6].Clicks, stats.Series[7].Clicks})
+	require.Equal(t, 2, stats.UniqueVisitors, "уникальные посетители считаются по сохранённым событиям")
+	require.NotNil(t, stats.RolledUpUntil)
+	require.Equal(t, watermark, *stats.RolledUpUntil)
+
+	from := now.Add(-3 * time.Hour)
+	stats, err = uc.LinkStats(ctx, "owner", "aaa", models.StatsQuery{From: &from, Bucket: BucketHour})
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1, 1, 0}, []int{stats.Series[0].Clicks, stats.Series[1].Clicks, stats.Series[2].Clicks, stats.Series[3].Clicks})
+	require.Equal(t, 2, stats.TotalClicks)
+}
+
+func TestURLUseCase_LinkStatsRollupsUnalignedFrom(t *testing.T) {
+	db, err := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
+	require.NoError(t, err)
+	now := time.Date(2024, time.May, 15, 12, 30, 0, 0, time.UTC)
+	uc := NewURLUseCase(db, config.Config{}, &fixedGenerator{codes: []string{"aaa"}}, WithClock(&fakeClock{now: now}))
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "owner")
+
+	_, err = uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru"})
+	require.NoError(t, err)
+
+	err = db.AddClickEvents(ctx, models.ClickEventList{
+		{Hash: "aaa", Time: time.Date(2024, time.May, 14, 1, 0, 0, 0, time.UTC), IP: "10.0.0.1"},
+		{Hash: "aaa", Time: time.Date(2024, time.May, 14, 5, 0, 0, 0, time.UTC), IP: "10.0.0.2"},
+		{Hash: "aaa", Time: time.Date(2024, time.May, 15, 2, 0, 0, 0, time.UTC), IP: "10.0.0.3"},
+	})
+	require.NoError(t, err)
+	_, err = db.RollupClicks(ctx, time.Date(2024, time.May, 15, 11, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	// Переход до начала периода входит в сводку за день, но учитываться не должен
+	from := time.Date(2024, time.May, 14, 3, 0, 0, 0, time.UTC)
+	stats, err := uc.LinkStats(ctx, "owner", "aaa", models.StatsQuery{From: &from})
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.TotalClicks, "переходы до начала периода не учитываются")
+	require.Equal(t, []int{1, 1}, []int{stats.Series[0].Clicks, stats.Series[1].Clicks})
+}
+
+func TestURLUseCase_LinkStatsRollupsMatchRaw(t *testing.T) {
+	now := time.Date(2024, time.May, 15, 12, 30, 0, 0, time.UTC)
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "owner")
+
+	var events models.ClickEventList
+	for at := now.Add(-4 * 24 * time.Hour); at.Before(now); at = at.Add(37 * time.Minute) {
+		events = append(events, models.ClickEvent{Hash: "aaa", Time: at, IP: "10.0.0.1"})
+	}
+
+	newUseCase := func(watermark *time.Time) *URLUseCase {
+		db, err := drivers.NewMemoryDatabase(drivers.MemoryOptions{})
+		require.NoError(t, err)
+		uc := NewURLUseCase(db, config.Config{}, &fixedGenerator{codes: []string{"aaa"}}, WithClock(&fakeClock{now: now}))
+		_, err = uc.Shorten(ctx, models.FullURL{URL: "https://ya.ru"})
+		require.NoError(t, err)
+		require.NoError(t, db.AddClickEvents(ctx, events))
+		if watermark != nil {
+			_, err = db.RollupClicks(ctx, *watermark)
+			require.NoError(t, err)
+		}
+		return uc
+	}
+	watermark := time.Date(2024, time.May, 15, 11, 0, 0, 0, time.UTC)
+	raw := newUseCase(nil)
+	rolled := newUseCase(&watermark)
+
+	from := time.Date(2024, time.May, 12, 7, 20, 0, 0, time.UTC)
+	to := time.Date(2024, time.May, 14, 18, 45, 0, 0, time.UTC)
+	queries := []models.StatsQuery{
+		{From: &from, To: &to, Bucket: BucketHour},
+		{From: &from, To: &to, Bucket: BucketDay},
+		{From: &from, To: &to, Bucket: BucketWeek},
+		{From: &from, Bucket: BucketHour},
+		{From: &from, Bucket: BucketDay},
+	}
+	for _, query := range queries {
+		want, err := raw.LinkStats(ctx, "owner", "aaa", query)
+		require.NoError(t, err)
+		got, err := rolled.LinkStats(ctx, "owner", "aaa", query)
+		require.NoError(t, err)
+
+		require.NotNil(t, got.RolledUpUntil, "сводки должны использоваться")
+		require.Equal(t, want.TotalClicks, got.TotalClicks, "итог по сводкам должен совпадать с подсчётом по событиям, bucket %s", query.Bucket)
+		require.Equal(t, want.Series, got.Series, "ряды должны совпадать, bucket %s", query.Bucket)
+	}
+}
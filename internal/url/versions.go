@@ -0,0 +1,99 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	customErrors "github.com/thxhix/shortener/internal/errors"
+	"github.com/thxhix/shortener/internal/models"
+)
+
+// ErrInvalidURL is returned when a link is edited to redirect to an invalid URL.
+var ErrInvalidURL = errors.New("некорректная ссылка")
+
+// ErrVersionNotFound is returned on a rollback to a version the link never had.
+var ErrVersionNotFound = errors.New("версия ссылки не найдена")
+
+// UpdateURL changes the original URL of the user's link and returns its new
+// current version. The previous destination is kept in the history of the link.
+// Returns ErrInvalidURL if the URL cannot be parsed, ErrNotFound if the link does
+// not exist or is deleted, ErrNotOwner if it belongs to another user and
+// ErrDuplicate if another link already shortens the URL. Updating the link to its
+// current destination changes nothing.
+func (u *URLUseCase) UpdateURL(ctx context.Context, userID string, hash string, original string) (models.LinkVersion, error) {
+	if _, err := url.ParseRequestURI(original); err != nil {
+		return models.LinkVersion{}, ErrInvalidURL
+	}
+
+	row, err := u.database.UpdateLink(ctx, userID, hash, original)
+	if err != nil {
+		return models.LinkVersion{}, err
+	}
+	return currentVersion(row), nil
+}
+
+// URLVersions returns all destinations of the user's link, oldest first,
+// with the current one last. Errors are the same as in UpdateURL.
+func (u *URLUseCase) URLVersions(ctx context.Context, userID string, hash string) (models.LinkVersionList, error) {
+	row, err := u.ownLink(ctx, userID, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := u.database.GetLinkHistory(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return append(history, currentVersion(row)), nil
+}
+
+// RollbackURL makes the link redirect to the destination of the given version
+// again. The rollback is stored as a new version, so the history is never lost.
+// Returns ErrVersionNotFound if the link has no such version, other errors
+// are the same as in UpdateURL. A rollback to the current version changes nothing.
+func (u *URLUseCase) RollbackURL(ctx context.Context, userID string, hash string, version int) (models.LinkVersion, error) {
+	versions, err := u.URLVersions(ctx, userID, hash)
+	if err != nil {
+		return models.LinkVersion{}, err
+	}
+
+	for _, v := range versions {
+		if v.Version != version {
+			continue
+		}
+		if v.Current {
+			return v, nil
+		}
+		row, err := u.database.UpdateLink(ctx, userID, hash, v.URL)
+		if err != nil {
+			return models.LinkVersion{}, err
+		}
+		return currentVersion(row), nil
+	}
+	return models.LinkVersion{}, ErrVersionNotFound
+}
+
+// ownLink returns the link by the given short hash if it belongs to the user.
+func (u *URLUseCase) ownLink(ctx context.Context, userID string, hash string) (models.DBShortenRow, error) {
+	row, err := u.database.GetFullLink(ctx, hash)
+	if err != nil {
+		return models.DBShortenRow{}, err
+	}
+	if row.IsDeleted {
+		return models.DBShortenRow{}, customErrors.ErrNotFound
+	}
+	if row.UserID == "" || row.UserID != userID {
+		return models.DBShortenRow{}, customErrors.ErrNotOwner
+	}
+	return row, nil
+}
+
+// currentVersion describes the destination the row redirects to now.
+func currentVersion(row models.DBShortenRow) models.LinkVersion {
+	return models.LinkVersion{
+		Version: row.CurrentVersion(),
+		URL:     row.URL,
+		Current: true,
+	}
+}
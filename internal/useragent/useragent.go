@@ -0,0 +1,178 @@
+// Package useragent classifies clients by their User-Agent header: it tells
+// bots apart from people and detects the device class, the OS family and
+// the browser family.
+//
+// Classification is driven by ordered substring rules. The rules bundled
+// with the package are in rules.json, a file with the same format may be
+// loaded instead to update them without a rebuild.
+package useragent
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Device classes of user agents.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceOther   = "other"
+)
+
+// Unknown is the OS or browser family of user agents no rule matches.
+const Unknown = "Other"
+
+//go:embed rules.json
+var bundledRules []byte
+
+// Rule assigns the name to user agents that contain the substring,
+// compared case-insensitively.
+type Rule struct {
+	Contains string `json:"contains"`
+	Name     string `json:"name"`
+}
+
+// Rules holds the rules of every attribute. Within a list the first matching
+// rule wins, so specific rules must come before generic ones, e.g. Edge
+// before Chrome, since Edge also mentions Chrome.
+type Rules struct {
+	// Bots name the bots, a match makes the user agent a bot.
+	Bots []Rule `json:"bots"`
+
+	// Devices name the device classes: desktop, mobile or tablet.
+	Devices []Rule `json:"devices"`
+
+	// OS name the families of operating systems.
+	OS []Rule `json:"os"`
+
+	// Browsers name the families of browsers and other clients.
+	Browsers []Rule `json:"browsers"`
+}
+
+// Agent is the classification of a user agent.
+type Agent struct {
+	// Device is one of the Device* classes.
+	Device string
+
+	// OS is the family of the operating system, Unknown if not detected.
+	OS string
+
+	// Browser is the family of the browser, or the name of the bot.
+	// Unknown if not detected.
+	Browser string
+
+	// Bot is set for crawlers, link-preview fetchers and other bots.
+	Bot bool
+}
+
+// Parser classifies user agents. It is safe for concurrent use.
+type Parser struct {
+	rules Rules
+}
+
+// NewParser creates a Parser with the rules. Returns an error if a rule
+// has an empty substring or name, or a device rule names an unknown class.
+func NewParser(rules Rules) (*Parser, error) {
+	for i, rule := range rules.Devices {
+		switch rule.Name {
+		case DeviceDesktop, DeviceMobile, DeviceTablet:
+		default:
+			return nil, fmt.Errorf("правило devices #%d: неизвестный класс устройства %q", i+1, rule.Name)
+		}
+	}
+
+	var err error
+	parser := &Parser{}
+	if parser.rules.Bots, err = normalize("bots", rules.Bots); err != nil {
+		return nil, err
+	}
+	if parser.rules.Devices, err = normalize("devices", rules.Devices); err != nil {
+		return nil, err
+	}
+	if parser.rules.OS, err = normalize("os", rules.OS); err != nil {
+		return nil, err
+	}
+	if parser.rules.Browsers, err = normalize("browsers", rules.Browsers); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// normalize returns copies of the rules with lowercased substrings.
+func normalize(list string, rules []Rule) ([]Rule, error) {
+	result := make([]Rule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Contains == "" || rule.Name == "" {
+			return nil, fmt.Errorf("правило %s #%d: пустая подстрока или имя", list, i+1)
+		}
+		result = append(result, Rule{Contains: strings.ToLower(rule.Contains), Name: rule.Name})
+	}
+	return result, nil
+}
+
+// ParseRules creates a Parser with the rules encoded as JSON
+// in the format of the bundled rules.json.
+func ParseRules(data []byte) (*Parser, error) {
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("некорректный файл правил user-agent: %w", err)
+	}
+	return NewParser(rules)
+}
+
+// Load creates a Parser with the rules from the file at path,
+// or with the bundled rules if path is empty.
+func Load(path string) (*Parser, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(data)
+}
+
+// Default returns the Parser with the bundled rules.
+var Default = sync.OnceValue(func() *Parser {
+	parser, err := ParseRules(bundledRules)
+	if err != nil {
+		panic(err)
+	}
+	return parser
+})
+
+// Parse classifies the user agent. Bots get the DeviceBot class
+// and their name as the browser.
+func (p *Parser) Parse(userAgent string) Agent {
+	lower := strings.ToLower(userAgent)
+	agent := Agent{
+		Device:  match(p.rules.Devices, lower, DeviceOther),
+		OS:      match(p.rules.OS, lower, Unknown),
+		Browser: match(p.rules.Browsers, lower, Unknown),
+	}
+
+	if bot := match(p.rules.Bots, lower, ""); bot != "" {
+		agent.Bot = true
+		agent.Device = DeviceBot
+		agent.Browser = bot
+	}
+	return agent
+}
+
+// match returns the name of the first rule contained in the lowercased
+// user agent, fallback if there is none.
+func match(rules []Rule, userAgent string, fallback string) string {
+	for _, rule := range rules {
+		if strings.Contains(userAgent, rule.Contains) {
+			return rule.Name
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,162 @@
+package useragent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_Parse(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      Agent
+	}{
+		{
+			name:      "Chrome на Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			want:      Agent{Device: DeviceDesktop, OS: "Windows", Browser: "Chrome"},
+		},
+		{
+			name:      "Edge на Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0",
+			want:      Agent{Device: DeviceDesktop, OS: "Windows", Browser: "Edge"},
+		},
+		{
+			name:      "Safari на macOS",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+			want:      Agent{Device: DeviceDesktop, OS: "macOS", Browser: "Safari"},
+		},
+		{
+			name:      "Firefox на Linux",
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+			want:      Agent{Device: DeviceDesktop, OS: "Linux", Browser: "Firefox"},
+		},
+		{
+			name:      "Chrome на ChromeOS",
+			userAgent: "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			want:      Agent{Device: DeviceDesktop, OS: "ChromeOS", Browser: "Chrome"},
+		},
+		{
+			name:      "Safari на iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want:      Agent{Device: DeviceMobile, OS: "iOS", Browser: "Safari"},
+		},
+		{
+			name:      "Chrome на iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/124.0.6367.88 Mobile/15E148 Safari/604.1",
+			want:      Agent{Device: DeviceMobile, OS: "iOS", Browser: "Chrome"},
+		},
+		{
+			name:      "Safari на iPad",
+			userAgent: "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			want:      Agent{Device: DeviceTablet, OS: "iOS", Browser: "Safari"},
+		},
+		{
+			name:      "Chrome на Android-телефоне",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+			want:      Agent{Device: DeviceMobile, OS: "Android", Browser: "Chrome"},
+		},
+		{
+			name:      "Samsung Internet на Android-планшете",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/24.0 Chrome/117.0.0.0 Safari/537.36",
+			want:      Agent{Device: DeviceTablet, OS: "Android", Browser: "Samsung Internet"},
+		},
+		{
+			name:      "Яндекс Браузер",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 YaBrowser/24.4.0.0 Safari/537.36",
+			want:      Agent{Device: DeviceDesktop, OS: "Windows", Browser: "Yandex Browser"},
+		},
+		{
+			name:      "Googlebot",
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:      Agent{Device: DeviceBot, OS: Unknown, Browser: "Googlebot", Bot: true},
+		},
+		{
+			name:      "Googlebot для смартфонов",
+			userAgent: "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:      Agent{Device: DeviceBot, OS: "Android", Browser: "Googlebot", Bot: true},
+		},
+		{
+			name:      "превью в Telegram",
+			userAgent: "TelegramBot (like TwitterBot)",
+			want:      Agent{Device: DeviceBot, OS: Unknown, Browser: "TelegramBot", Bot: true},
+		},
+		{
+			name:      "превью в Slack",
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			want:      Agent{Device: DeviceBot, OS: Unknown, Browser: "Slackbot", Bot: true},
+		},
+		{
+			name:      "превью в Facebook",
+			userAgent: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			want:      Agent{Device: DeviceBot, OS: Unknown, Browser: "Facebook", Bot: true},
+		},
+		{
+			name:      "превью в WhatsApp",
+			userAgent: "WhatsApp/2.23.20.0 A",
+			want:      Agent{Device: DeviceBot, OS: Unknown, Browser: "WhatsApp", Bot: true},
+		},
+		{
+			name:      "неизвестный краулер",
+			userAgent: "Mozilla/5.0 (compatible; SomeCrawler/1.0)",
+			want:      Agent{Device: DeviceBot, OS: Unknown, Browser: "Bot", Bot: true},
+		},
+		{
+			name:      "curl",
+			userAgent: "curl/8.4.0",
+			want:      Agent{Device: DeviceOther, OS: Unknown, Browser: "curl"},
+		},
+		{
+			name:      "пустой user-agent",
+			userAgent: "",
+			want:      Agent{Device: DeviceOther, OS: Unknown, Browser: Unknown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Default().Parse(tt.userAgent))
+		})
+	}
+}
+
+func TestNewParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules Rules
+	}{
+		{"пустая подстрока", Rules{Bots: []Rule{{Name: "Bot"}}}},
+		{"пустое имя", Rules{Browsers: []Rule{{Contains: "chrome/"}}}},
+		{"неизвестный класс устройства", Rules{Devices: []Rule{{Contains: "tv", Name: "tv"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.rules)
+			require.Error(t, err)
+		})
+	}
+
+	parser, err := NewParser(Rules{Browsers: []Rule{{Contains: "MyApp/", Name: "MyApp"}}})
+	require.NoError(t, err)
+	require.Equal(t, "MyApp", parser.Parse("myapp/1.0").Browser, "подстроки сравниваются без учёта регистра")
+}
+
+func TestLoad(t *testing.T) {
+	parser, err := Load("")
+	require.NoError(t, err)
+	require.Same(t, Default(), parser, "без пути используются встроенные правила")
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"bots": [{"contains": "checker", "name": "Checker"}]}`), 0666))
+	parser, err = Load(path)
+	require.NoError(t, err)
+	require.Equal(t, Agent{Device: DeviceBot, OS: Unknown, Browser: "Checker", Bot: true}, parser.Parse("uptime-checker/2.0"))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"bots": [`), 0666))
+	_, err = Load(path)
+	require.Error(t, err)
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS id_ranges (
+    name VARCHAR(64) PRIMARY KEY,
+    next_id BIGINT NOT NULL
+);
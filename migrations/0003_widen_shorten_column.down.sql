@@ -0,0 +1,11 @@
+-- Rolling back only works while every code still fits in 10 characters,
+-- longer aliases and codes must be removed first.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM shortener WHERE length(shorten) > 10) THEN
+        RAISE EXCEPTION 'нельзя сузить shortener.shorten до VARCHAR(10): есть коды длиннее 10 символов';
+    END IF;
+END
+$$;
+
+ALTER TABLE shortener ALTER COLUMN shorten TYPE VARCHAR(10);
@@ -0,0 +1 @@
+ALTER TABLE shortener ALTER COLUMN shorten TYPE VARCHAR(64);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_shortener_expires_at;
+
+ALTER TABLE shortener DROP COLUMN IF EXISTS is_expired;
+ALTER TABLE shortener DROP COLUMN IF EXISTS clicks;
+ALTER TABLE shortener DROP COLUMN IF EXISTS max_clicks;
+ALTER TABLE shortener DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_shortener_active_until;
+
+ALTER TABLE shortener DROP COLUMN IF EXISTS active_until;
+ALTER TABLE shortener DROP COLUMN IF EXISTS active_from;
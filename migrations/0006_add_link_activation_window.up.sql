@@ -0,0 +1,4 @@
+ALTER TABLE shortener ADD COLUMN IF NOT EXISTS active_from TIMESTAMPTZ;
+ALTER TABLE shortener ADD COLUMN IF NOT EXISTS active_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_shortener_active_until ON shortener(active_until) WHERE NOT is_expired;
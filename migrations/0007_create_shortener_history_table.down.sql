@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS shortener_history;
+
+ALTER TABLE shortener DROP COLUMN IF EXISTS version;
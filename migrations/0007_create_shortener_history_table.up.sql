@@ -0,0 +1,10 @@
+ALTER TABLE shortener ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS shortener_history (
+    id SERIAL PRIMARY KEY,
+    shorten VARCHAR(64) NOT NULL,
+    version INTEGER NOT NULL,
+    original VARCHAR(512) NOT NULL,
+    replaced_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (shorten, version)
+);
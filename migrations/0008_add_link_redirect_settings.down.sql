@@ -0,0 +1,2 @@
+ALTER TABLE shortener DROP COLUMN IF EXISTS cache_max_age;
+ALTER TABLE shortener DROP COLUMN IF EXISTS redirect_code;
@@ -0,0 +1,2 @@
+ALTER TABLE shortener ADD COLUMN IF NOT EXISTS redirect_code SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE shortener ADD COLUMN IF NOT EXISTS cache_max_age INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS click_rollup_watermark;
+
+DROP TABLE IF EXISTS clicks_daily;
+
+DROP TABLE IF EXISTS clicks_hourly;
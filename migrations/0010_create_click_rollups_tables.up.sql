@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS clicks_hourly (
+    shorten VARCHAR(64) NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    clicks INTEGER NOT NULL,
+    PRIMARY KEY (shorten, bucket_start)
+);
+
+CREATE TABLE IF NOT EXISTS clicks_daily (
+    shorten VARCHAR(64) NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    clicks INTEGER NOT NULL,
+    PRIMARY KEY (shorten, bucket_start)
+);
+
+CREATE TABLE IF NOT EXISTS click_rollup_watermark (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    watermark TIMESTAMPTZ
+);
+
+INSERT INTO click_rollup_watermark (id, watermark) VALUES (TRUE, NULL) ON CONFLICT DO NOTHING;
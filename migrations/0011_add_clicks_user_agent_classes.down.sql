@@ -0,0 +1,3 @@
+ALTER TABLE clicks DROP COLUMN IF EXISTS browser;
+ALTER TABLE clicks DROP COLUMN IF EXISTS os;
+ALTER TABLE clicks DROP COLUMN IF EXISTS device;
@@ -0,0 +1,3 @@
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS device VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS os TEXT NOT NULL DEFAULT '';
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS browser TEXT NOT NULL DEFAULT '';
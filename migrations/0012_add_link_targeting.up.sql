@@ -0,0 +1 @@
+ALTER TABLE shortener ADD COLUMN IF NOT EXISTS targeting JSONB NOT NULL DEFAULT '[]';